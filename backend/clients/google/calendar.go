@@ -12,6 +12,9 @@ import (
 	"google.golang.org/api/option"
 )
 
+// CalendarScopes are the scopes the calendar client needs to watch the user's events.
+var CalendarScopes = []string{calendar.CalendarScope}
+
 type CalendarClient struct {
 	service *calendar.Service
 }
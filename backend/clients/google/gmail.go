@@ -13,6 +13,9 @@ const (
 	MAX_RESULTS = 50
 )
 
+// GmailScopes are the scopes the Gmail client needs to send emails and look them up again.
+var GmailScopes = []string{gmail.GmailSendScope, gmail.GmailReadonlyScope}
+
 type GmailClient struct {
 	service *gmail.Service
 }
@@ -39,7 +42,7 @@ func InitGmailClient(
 }
 
 func (c *GmailClient) GetHistoryID(ctx context.Context) (*uint64, error) {
-	profile, err := c.service.Users.GetProfile("me").Do()
+	profile, err := c.service.Users.GetProfile("me").Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("unable to get the user's history: %w", err)
 	}
@@ -67,7 +70,7 @@ func (c *GmailClient) GetHistoryWithID(
 func (c *GmailClient) GetUserEmail(
 	ctx context.Context,
 ) (string, error) {
-	profile, err := c.service.Users.GetProfile("me").Do()
+	profile, err := c.service.Users.GetProfile("me").Context(ctx).Do()
 	if err != nil {
 		return "", fmt.Errorf("unable to get the user's profile: %w", err)
 	}
@@ -78,15 +81,38 @@ func (c *GmailClient) GetUserEmail(
 func (c *GmailClient) SendRawEmail(
 	ctx context.Context,
 	encodedMIME string,
-) error {
+) (*gmail.Message, error) {
 	msg := &gmail.Message{
 		Raw: encodedMIME,
 	}
 
-	_, err := c.service.Users.Messages.Send("me", msg).Do()
+	sent, err := c.service.Users.Messages.Send("me", msg).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send Gmail message: %w", err)
+	}
+
+	return sent, nil
+}
+
+// FindMessageByMessageID returns the message carrying the given Message-ID header, or nil when
+// the mailbox has none.
+func (c *GmailClient) FindMessageByMessageID(
+	ctx context.Context,
+	messageID string,
+) (*gmail.Message, error) {
+	res, err := c.service.Users.Messages.List("me").
+		Q(fmt.Sprintf("rfc822msgid:%s", messageID)).
+		IncludeSpamTrash(true).
+		MaxResults(1).
+		Context(ctx).
+		Do()
 	if err != nil {
-		return fmt.Errorf("failed to send Gmail message: %w", err)
+		return nil, fmt.Errorf("failed to search Gmail messages: %w", err)
+	}
+
+	if len(res.Messages) == 0 {
+		return nil, nil
 	}
 
-	return nil
+	return res.Messages[0], nil
 }
@@ -19,7 +19,7 @@ const (
 	deadLetterExchange = "dlx_exchange"
 	deadLetterQueue    = "process_node_run_dlq"
 
-	// Retry configuration
+	// Retry configuration used when the handler error does not carry its own retry decision
 	maxRetries        = 3
 	initialDelayMs    = 2000   // 2 seconds in milliseconds
 	maxDelayMs        = 120000 // 2 minutes in milliseconds
@@ -28,6 +28,9 @@ const (
 
 type RabbitMQClient interface {
 	Publish(ctx context.Context, msg []byte) error
+	// PublishDelayed publishes through the delayed exchange. Delays are capped at the plugin's
+	// limit of math.MaxInt32 milliseconds (about 24 days).
+	PublishDelayed(ctx context.Context, msg []byte, delay time.Duration) error
 	Subscribe(ctx context.Context, handler func([]byte) error) error
 	StartReturnListener()
 	Close() error
@@ -169,6 +172,33 @@ func (c *rabbitMQClient) Publish(ctx context.Context, msg []byte) error {
 	return nil
 }
 
+func (c *rabbitMQClient) PublishDelayed(
+	ctx context.Context,
+	msg []byte,
+	delay time.Duration,
+) error {
+	delayMs := min(max(delay.Milliseconds(), 0), math.MaxInt32)
+
+	if err := c.channel.PublishWithContext(ctx,
+		retryExchange,
+		"retry", // routing key
+		false,   // mandatory
+		false,   // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Headers: amqp.Table{
+				"x-delay": int32(delayMs),
+			},
+			Body: msg,
+		}); err != nil {
+		c.logger.WithError(err).Error("delayed publish failed")
+		return fmt.Errorf("failed to publish delayed message: %w", err)
+	}
+
+	return nil
+}
+
 func (c *rabbitMQClient) Subscribe(ctx context.Context, handler func([]byte) error) error {
 	if err := c.channel.Qos(
 		100,   // prefetch count
@@ -219,7 +249,7 @@ func (c *rabbitMQClient) Subscribe(ctx context.Context, handler func([]byte) err
 					// Process message
 					if err := handler(delivery.Body); err != nil {
 						c.logger.WithError(err).Error("Failed to process message")
-						c.handleFailedMessage(delivery)
+						c.handleFailedMessage(delivery, err)
 
 						return
 					}
@@ -235,7 +265,10 @@ func (c *rabbitMQClient) Subscribe(ctx context.Context, handler func([]byte) err
 	return nil
 }
 
-func (c *rabbitMQClient) handleFailedMessage(msg amqp.Delivery) {
+// handleFailedMessage republishes the message through the delayed exchange or dead letters it.
+// A *RetryableError or *PermanentError returned by the handler decides the outcome, otherwise the
+// default backoff applies.
+func (c *rabbitMQClient) handleFailedMessage(msg amqp.Delivery, handlerErr error) {
 	retryCount := 0
 
 	if msg.Headers != nil {
@@ -246,13 +279,22 @@ func (c *rabbitMQClient) handleFailedMessage(msg amqp.Delivery) {
 		}
 	}
 
+	var retryableErr *RetryableError
+
+	var permanentErr *PermanentError
+
+	isRetryable := errors.As(handlerErr, &retryableErr)
+	isPermanent := errors.As(handlerErr, &permanentErr)
+
 	c.logger.WithFields(logrus.Fields{
-		"retry_count": retryCount,
-		"max_retries": maxRetries,
+		"retry_count":  retryCount,
+		"max_retries":  maxRetries,
+		"is_retryable": isRetryable,
+		"is_permanent": isPermanent,
 	}).Info("handling failed message")
 
-	if retryCount >= maxRetries {
-		c.logger.WithField("retry_count", retryCount).Warn("max retries exceeded, sending to DLQ")
+	if isPermanent || (!isRetryable && retryCount >= maxRetries) {
+		c.logger.WithField("retry_count", retryCount).Warn("not retrying message, sending to DLQ")
 
 		if err := msg.Nack(false, false); err != nil {
 			c.logger.WithError(err).Error("failed to nack message to DLQ")
@@ -261,9 +303,16 @@ func (c *rabbitMQClient) handleFailedMessage(msg amqp.Delivery) {
 		return
 	}
 
-	delay := float64(initialDelayMs) * math.Pow(float64(backoffMultiplier), float64(retryCount))
+	var delay float64
+	if isRetryable {
+		delay = float64(retryableErr.Delay.Milliseconds())
+	} else {
+		delay = float64(initialDelayMs) * math.Pow(float64(backoffMultiplier), float64(retryCount))
+	}
 
-	if delay > float64(maxDelayMs) {
+	if delay > math.MaxInt32 {
+		delay = math.MaxInt32
+	} else if !isRetryable && delay > float64(maxDelayMs) {
 		delay = float64(maxDelayMs)
 	}
 
@@ -0,0 +1,34 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetryableError asks the consumer to redeliver the message after Delay instead of using the
+// default backoff.
+type RetryableError struct {
+	Err   error
+	Delay time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return fmt.Sprintf("retryable after %s: %v", e.Delay, e.Err)
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError sends the message straight to the dead letter queue without retrying.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("permanent failure: %v", e.Err)
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
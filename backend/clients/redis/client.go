@@ -16,7 +16,14 @@ const (
 	workflowProgressChannelPrefix = "workflow-progress"
 )
 
+// Progress event types, used as the SSE event name.
+const (
+	NodeUpdateEvent        = "node_update"
+	WorkflowRunUpdateEvent = "workflow_run_update"
+)
+
 type NodeStatusUpdate struct {
+	Type      string         `json:"type"`
 	RunID     int32          `json:"runId"`
 	NodeID    int32          `json:"nodeId"`
 	Status    string         `json:"status"`
@@ -41,8 +48,30 @@ type RedisClient interface {
 		status string,
 		details map[string]any,
 	) error
+	PublishWorkflowRunStatusUpdate(
+		ctx context.Context,
+		runID int32,
+		status string,
+		details map[string]any,
+	) error
 	SubscribeWorkflowProgress(ctx context.Context) (<-chan *redis.Message, *redis.PubSub, error)
 
+	// Rate limits
+	// TakeRateLimitToken takes a token from a token bucket refilled at ratePerSecond and holding
+	// at most burst tokens. It returns how long to wait for the next token when the bucket is
+	// empty, or zero when a token was taken.
+	TakeRateLimitToken(
+		ctx context.Context,
+		bucket string,
+		ratePerSecond float64,
+		burst int,
+	) (time.Duration, error)
+	// ConsumeQuota counts one use against a quota that resets at resetAt. It reports false,
+	// without counting, once limit uses were counted.
+	ConsumeQuota(ctx context.Context, quota string, limit int64, resetAt time.Time) (bool, error)
+	// RefundQuota takes back one use counted by ConsumeQuota.
+	RefundQuota(ctx context.Context, quota string) error
+
 	// Calendar Events
 	TryEventClaim(
 		ctx context.Context,
@@ -221,6 +250,7 @@ func (c *redisClient) PublishNodeStatusUpdate(
 ) error {
 	channel := c.generateProgressChannel(runID)
 	payload := NodeStatusUpdate{
+		Type:      NodeUpdateEvent,
 		RunID:     runID,
 		NodeID:    nodeID,
 		Status:    status,
@@ -260,6 +290,39 @@ func (c *redisClient) PublishNodeStatusUpdate(
 	return nil
 }
 
+func (c *redisClient) PublishWorkflowRunStatusUpdate(
+	ctx context.Context,
+	runID int32,
+	status string,
+	details map[string]any,
+) error {
+	channel := c.generateProgressChannel(runID)
+	payload := NodeStatusUpdate{
+		Type:      WorkflowRunUpdateEvent,
+		RunID:     runID,
+		Status:    status,
+		Timestamp: time.Now().UTC(),
+		Details:   details,
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow run status update payload: %w", err)
+	}
+
+	if err := c.client.Publish(ctx, channel, payloadBytes).Err(); err != nil {
+		c.logger.WithError(err).WithFields(logrus.Fields{
+			"run_id":  runID,
+			"status":  status,
+			"channel": channel,
+		}).Error("failed to publish workflow run status update to redis")
+
+		return fmt.Errorf("failed to publish workflow run status update to redis: %w", err)
+	}
+
+	return nil
+}
+
 func (c *redisClient) SubscribeWorkflowProgress(
 	ctx context.Context,
 ) (<-chan *redis.Message, *redis.PubSub, error) {
@@ -315,3 +378,97 @@ func (c *redisClient) TryEventClaim(
 
 	return claimed, nil
 }
+
+var takeRateLimitTokenScript = redis.NewScript(`
+	local rate = tonumber(ARGV[1])
+	local burst = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+	local tokens = tonumber(bucket[1]) or burst
+	local ts = tonumber(bucket[2]) or now
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	local wait = 0
+	if tokens >= 1 then
+		tokens = tokens - 1
+	else
+		wait = math.ceil((1 - tokens) / rate)
+	end
+	redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+	redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate))
+	return wait
+`)
+
+func (c *redisClient) TakeRateLimitToken(
+	ctx context.Context,
+	bucket string,
+	ratePerSecond float64,
+	burst int,
+) (time.Duration, error) {
+	key := fmt.Sprintf("rate_limit:%s", bucket)
+	ratePerMilli := ratePerSecond / 1000
+
+	wait, err := takeRateLimitTokenScript.Run(
+		ctx,
+		c.client,
+		[]string{key},
+		ratePerMilli,
+		burst,
+		time.Now().UnixMilli(),
+	).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+var consumeQuotaScript = redis.NewScript(`
+	local used = tonumber(redis.call("GET", KEYS[1]) or "0")
+	if used >= tonumber(ARGV[1]) then
+		return 0
+	end
+	if redis.call("INCR", KEYS[1]) == 1 then
+		redis.call("PEXPIREAT", KEYS[1], ARGV[2])
+	end
+	return 1
+`)
+
+func (c *redisClient) ConsumeQuota(
+	ctx context.Context,
+	quota string,
+	limit int64,
+	resetAt time.Time,
+) (bool, error) {
+	key := fmt.Sprintf("quota:%s", quota)
+
+	consumed, err := consumeQuotaScript.Run(
+		ctx,
+		c.client,
+		[]string{key},
+		limit,
+		resetAt.UnixMilli(),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume quota: %w", err)
+	}
+
+	return consumed == 1, nil
+}
+
+var refundQuotaScript = redis.NewScript(`
+	local used = tonumber(redis.call("GET", KEYS[1]) or "0")
+	if used > 0 then
+		redis.call("DECR", KEYS[1])
+	end
+	return 1
+`)
+
+func (c *redisClient) RefundQuota(ctx context.Context, quota string) error {
+	key := fmt.Sprintf("quota:%s", quota)
+
+	if err := refundQuotaScript.Run(ctx, c.client, []string{key}).Err(); err != nil {
+		return fmt.Errorf("failed to refund quota: %w", err)
+	}
+
+	return nil
+}
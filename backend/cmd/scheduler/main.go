@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	_ "expvar"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"runtime/debug"
@@ -49,6 +51,15 @@ func main() {
 
 	s = NewScheduler(cfg)
 
+	if port := cfg.GetEnvVars().SchedulerMetricsPort; port != "" {
+		go func() {
+			// expvar registers /debug/vars on the default mux
+			if err := http.ListenAndServe(":"+port, nil); err != nil {
+				logger.WithError(err).Error("metrics server stopped")
+			}
+		}()
+	}
+
 	go func() {
 		err = s.PollAndRunScheduledWorkflows(ctx)
 		if err != nil {
@@ -13,8 +13,12 @@ import (
 type Scheduler struct {
 	schedulerService      models.SchedulerService
 	calendarService       models.WorkflowCalendarService
+	runReaperService      models.RunReaperService
+	reconcilerService     models.NodeRunReconcilerService
 	schedulerPollInterval time.Duration
 	calendarPollInterval  time.Duration
+	runReaperInterval     time.Duration
+	reconcileInterval     time.Duration
 	logger                logrus.FieldLogger
 }
 
@@ -22,8 +26,12 @@ func NewScheduler(cfg models.AppConfig) *Scheduler {
 	return &Scheduler{
 		schedulerService:      cfg.GetSchedulerService(),
 		calendarService:       cfg.GetWorkflowCalendarService(),
+		runReaperService:      cfg.GetRunReaperService(),
+		reconcilerService:     cfg.GetNodeRunReconcilerService(),
 		schedulerPollInterval: cfg.GetEnvVars().SchedulerPollInterval,
 		calendarPollInterval:  cfg.GetEnvVars().CalendarPollInterval,
+		runReaperInterval:     cfg.GetEnvVars().RunReaperInterval,
+		reconcileInterval:     cfg.GetEnvVars().NodeRunReconcileInterval,
 		logger:                cfg.GetLogger(),
 	}
 }
@@ -35,9 +43,13 @@ func (s *Scheduler) StopScheduler() {
 func (s *Scheduler) PollAndRunScheduledWorkflows(ctx context.Context) error {
 	schedulerTicker := time.NewTicker(s.schedulerPollInterval)
 	calendarTicker := time.NewTicker(s.calendarPollInterval)
+	runReaperTicker := time.NewTicker(s.runReaperInterval)
+	reconcileTicker := time.NewTicker(s.reconcileInterval)
 
 	defer schedulerTicker.Stop()
 	defer calendarTicker.Stop()
+	defer runReaperTicker.Stop()
+	defer reconcileTicker.Stop()
 
 	s.logger.Info("start polling for scheduled workflows")
 
@@ -94,6 +106,22 @@ func (s *Scheduler) PollAndRunScheduledWorkflows(ctx context.Context) error {
 			}
 
 			s.logger.Info("finished polling for calendar events")
+
+		case <-runReaperTicker.C:
+			reaped, err := s.runReaperService.ReapStuckWorkflowRuns(ctx)
+			if err != nil {
+				s.logger.WithError(err).Error("failed to reap stuck workflow runs")
+			} else if reaped > 0 {
+				s.logger.WithField("count", reaped).Info("timed out stuck workflow runs")
+			}
+
+		case <-reconcileTicker.C:
+			repaired, err := s.reconcilerService.ReconcilePendingNodeRuns(ctx)
+			if err != nil {
+				s.logger.WithError(err).Error("failed to reconcile pending node runs")
+			} else if repaired > 0 {
+				s.logger.WithField("count", repaired).Info("republished idle node run tasks")
+			}
 		}
 	}
 }
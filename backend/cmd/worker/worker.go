@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/tinyautomator/tinyautomator-core/backend/clients/rabbitmq"
@@ -11,16 +12,22 @@ import (
 )
 
 type Worker struct {
-	executor       models.ExecutorService
-	rabbitMQClient rabbitmq.RabbitMQClient
-	logger         logrus.FieldLogger
+	executor            models.ExecutorService
+	outboxRelay         models.OutboxRelayService
+	rabbitMQClient      rabbitmq.RabbitMQClient
+	outboxRelayInterval time.Duration
+	outboxPurgeInterval time.Duration
+	logger              logrus.FieldLogger
 }
 
 func NewWorker(cfg models.AppConfig) *Worker {
 	return &Worker{
-		executor:       services.NewExecutorService(cfg),
-		rabbitMQClient: cfg.GetRabbitMQClient(),
-		logger:         cfg.GetLogger(),
+		executor:            services.NewExecutorService(cfg),
+		outboxRelay:         cfg.GetOutboxRelayService(),
+		rabbitMQClient:      cfg.GetRabbitMQClient(),
+		outboxRelayInterval: cfg.GetEnvVars().OutboxRelayInterval,
+		outboxPurgeInterval: cfg.GetEnvVars().OutboxPurgeInterval,
+		logger:              cfg.GetLogger(),
 	}
 }
 
@@ -35,7 +42,41 @@ func (w *Worker) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start worker: %w", err)
 	}
 
+	go w.RelayOutboxTasks(ctx)
+
 	w.logger.Info("worker started successfully")
 
 	return nil
 }
+
+// RelayOutboxTasks publishes the tasks written to the outbox until ctx is cancelled. Every
+// worker runs the relay, each outbox task is only claimed by one of them at a time.
+func (w *Worker) RelayOutboxTasks(ctx context.Context) {
+	relayTicker := time.NewTicker(w.outboxRelayInterval)
+	purgeTicker := time.NewTicker(w.outboxPurgeInterval)
+
+	defer relayTicker.Stop()
+	defer purgeTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("ctx cancelled - stopping outbox relay")
+			return
+		case <-relayTicker.C:
+			relayed, err := w.outboxRelay.RelayOutboxTasks(ctx)
+			if err != nil {
+				w.logger.WithError(err).Error("failed to relay outbox tasks")
+			} else if relayed > 0 {
+				w.logger.WithField("count", relayed).Debug("relayed outbox tasks")
+			}
+		case <-purgeTicker.C:
+			purged, err := w.outboxRelay.PurgeSentOutboxTasks(ctx)
+			if err != nil {
+				w.logger.WithError(err).Error("failed to purge sent outbox tasks")
+			} else if purged > 0 {
+				w.logger.WithField("count", purged).Info("purged sent outbox tasks")
+			}
+		}
+	}
+}
@@ -37,6 +37,9 @@ type appConfig struct {
 	oauthIntegrationSvc  models.OauthIntegrationService
 	accountService       models.AccountService
 	workflowCalendarSvc  models.WorkflowCalendarService
+	runReaperSvc         models.RunReaperService
+	nodeRunReconcilerSvc models.NodeRunReconcilerService
+	outboxRelaySvc       models.OutboxRelayService
 }
 
 var cfg *appConfig
@@ -71,6 +74,9 @@ func NewAppConfig(ctx context.Context) (models.AppConfig, error) {
 	cfg.oauthIntegrationSvc = services.NewOauthIntegrationService(cfg)
 	cfg.accountService = services.NewAccountService(cfg)
 	cfg.workflowCalendarSvc = services.NewWorkflowCalendarService(cfg)
+	cfg.runReaperSvc = services.NewRunReaperService(cfg)
+	cfg.nodeRunReconcilerSvc = services.NewNodeRunReconcilerService(cfg)
+	cfg.outboxRelaySvc = services.NewOutboxRelayService(cfg)
 
 	return cfg, nil
 }
@@ -143,6 +149,18 @@ func (c *appConfig) GetWorkflowCalendarService() models.WorkflowCalendarService
 	return c.workflowCalendarSvc
 }
 
+func (c *appConfig) GetRunReaperService() models.RunReaperService {
+	return c.runReaperSvc
+}
+
+func (c *appConfig) GetNodeRunReconcilerService() models.NodeRunReconcilerService {
+	return c.nodeRunReconcilerSvc
+}
+
+func (c *appConfig) GetOutboxRelayService() models.OutboxRelayService {
+	return c.outboxRelaySvc
+}
+
 func (c *appConfig) GetWorkflowService() models.WorkflowService {
 	return c.workflowSvc
 }
@@ -8,6 +8,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	formatter "github.com/antonfisher/nested-logrus-formatter"
 	"github.com/clerk/clerk-sdk-go/v2"
@@ -37,6 +38,12 @@ func validateEnvVars(e *models.EnvironmentVariables) error {
 		return fmt.Errorf("failed to decode token encryption key: %w", err)
 	}
 
+	// runs waiting on a node or a retry for as long as they may must not be timed out as stalled
+	longestWait := max(models.MaxNodeTimeout, models.MaxRetryDelayMs*time.Millisecond)
+	if e.RunStallTimeout <= longestWait {
+		return fmt.Errorf("run stall timeout must be longer than %s", longestWait)
+	}
+
 	return nil
 }
 
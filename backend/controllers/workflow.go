@@ -1,6 +1,9 @@
 package controllers
 
 import (
+	"errors"
+	"io"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -17,12 +20,21 @@ type WorkflowController interface {
 	GetWorkflowRender(ctx *gin.Context)
 	RunWorkFlow(ctx *gin.Context)
 	ArchiveWorkflow(ctx *gin.Context)
+	ExecuteWorkflowNode(ctx *gin.Context)
+	GetWorkflowConcurrency(ctx *gin.Context)
+	UpdateWorkflowConcurrency(ctx *gin.Context)
+	GetWorkflowTimeout(ctx *gin.Context)
+	UpdateWorkflowTimeout(ctx *gin.Context)
+	GetNodeTypes(ctx *gin.Context)
+	GetWorkflowInputSchema(ctx *gin.Context)
+	UpdateWorkflowInputSchema(ctx *gin.Context)
 }
 
 type workflowController struct {
 	logger          logrus.FieldLogger
 	repo            models.WorkflowRepository
 	orchestrator    models.OrchestratorService
+	executor        models.ExecutorService
 	redis           redis.RedisClient
 	workflowService models.WorkflowService
 }
@@ -42,12 +54,31 @@ type UpdateWorkflowRequest struct {
 	Edges       []*models.WorkflowEdgeDTO `json:"edges"       binding:"required"`
 }
 
+type UpdateWorkflowConcurrencyRequest struct {
+	MaxConcurrentRuns int32  `json:"max_concurrent_runs" binding:"required"`
+	OverlapPolicy     string `json:"overlap_policy"      binding:"required"`
+}
+
+type UpdateWorkflowTimeoutRequest struct {
+	RunTimeoutSeconds int32 `json:"run_timeout_seconds" binding:"required"`
+}
+
+type UpdateWorkflowInputSchemaRequest struct {
+	Inputs []*models.WorkflowInput `json:"inputs" binding:"required"`
+}
+
+type ExecuteWorkflowNodeRequest struct {
+	// ParentOutputs mocks the outputs of upstream nodes, keyed by node ID.
+	ParentOutputs map[int32]map[string]any `json:"parent_outputs"`
+}
+
 func NewWorkflowController(cfg models.AppConfig) *workflowController {
 	return &workflowController{
 		logger:          cfg.GetLogger(),
 		repo:            cfg.GetWorkflowRepository(),
 		redis:           cfg.GetRedisClient(),
 		orchestrator:    services.NewOrchestratorService(cfg),
+		executor:        cfg.GetExecutorService(),
 		workflowService: services.NewWorkflowService(cfg),
 	}
 }
@@ -137,6 +168,16 @@ func (c *workflowController) CreateWorkflow(ctx *gin.Context) {
 		req.Nodes,
 		req.Edges,
 	)
+	var nodeErr *services.NodeValidationError
+	if errors.As(err, &nodeErr) {
+		ctx.JSON(
+			http.StatusUnprocessableEntity,
+			gin.H{"error": "invalid workflow nodes", "node_errors": nodeErr.NodeErrors},
+		)
+
+		return
+	}
+
 	if err != nil {
 		c.logger.Errorf("workflow creation error: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create workflow"})
@@ -200,6 +241,16 @@ func (c *workflowController) UpdateWorkflow(ctx *gin.Context) {
 		req.Nodes,
 		req.Edges,
 	); err != nil {
+		var nodeErr *services.NodeValidationError
+		if errors.As(err, &nodeErr) {
+			ctx.JSON(
+				http.StatusUnprocessableEntity,
+				gin.H{"error": "invalid workflow nodes", "node_errors": nodeErr.NodeErrors},
+			)
+
+			return
+		}
+
 		c.logger.WithError(err).Error("failed to update workflow")
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to update workflow"})
 
@@ -290,3 +341,420 @@ func (c *workflowController) ArchiveWorkflow(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "workflow archived"})
 }
+
+// ExecuteWorkflowNode runs a single action node right away, without creating a workflow run,
+// and returns its output or error.
+func (c *workflowController) ExecuteWorkflowNode(ctx *gin.Context) {
+	workflowID, err := strconv.Atoi(ctx.Param("workflowID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	nodeID, err := strconv.Atoi(ctx.Param("nodeID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid node id"})
+		return
+	}
+
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+	if err := c.workflowService.VerifyWorkflowAccess(ctx.Request.Context(), int32(workflowID), userID); err != nil {
+		if err == services.ErrUserDoesNotHaveAccessToWorkflow {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to execute workflow node"})
+
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify workflow access"})
+
+		return
+	}
+
+	// the body is optional, a node without upstream references needs no mock outputs
+	var req ExecuteWorkflowNodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(
+			http.StatusUnprocessableEntity,
+			gin.H{"error": "invalid request body", "details": err.Error()},
+		)
+
+		return
+	}
+
+	result, err := c.executor.ExecuteSingleNode(
+		ctx.Request.Context(),
+		userID,
+		int32(workflowID),
+		int32(nodeID),
+		req.ParentOutputs,
+	)
+	var limitErr *services.UsageLimitError
+	if errors.As(err, &limitErr) {
+		retryAfter := int(math.Ceil(limitErr.RetryAfter.Seconds()))
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       limitErr.Reason,
+			"retry_after": retryAfter,
+		})
+
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrWorkflowNodeNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "workflow node not found"})
+		case errors.Is(err, services.ErrNodeNotExecutable):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.logger.WithError(err).Error("failed to execute workflow node")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to execute workflow node"})
+		}
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (c *workflowController) GetWorkflowConcurrency(ctx *gin.Context) {
+	idStr := ctx.Param("workflowID")
+
+	workflowID, err := strconv.Atoi(idStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+	if err := c.workflowService.VerifyWorkflowAccess(ctx.Request.Context(), int32(workflowID), userID); err != nil {
+		if err == services.ErrUserDoesNotHaveAccessToWorkflow {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to view workflow"})
+
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify workflow access"})
+
+		return
+	}
+
+	concurrency, err := c.workflowService.GetWorkflowConcurrency(
+		ctx.Request.Context(),
+		int32(workflowID),
+	)
+	if err != nil {
+		c.logger.WithError(err).Error("failed to get workflow concurrency")
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{"error": "failed to get workflow concurrency"},
+		)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, concurrency)
+}
+
+// UpdateWorkflowConcurrency sets how many runs of the workflow may be in progress at once and
+// what happens to runs started beyond that.
+func (c *workflowController) UpdateWorkflowConcurrency(ctx *gin.Context) {
+	idStr := ctx.Param("workflowID")
+
+	workflowID, err := strconv.Atoi(idStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+	if err := c.workflowService.VerifyWorkflowAccess(ctx.Request.Context(), int32(workflowID), userID); err != nil {
+		if err == services.ErrUserDoesNotHaveAccessToWorkflow {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to update workflow"})
+
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify workflow access"})
+
+		return
+	}
+
+	var req UpdateWorkflowConcurrencyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(
+			http.StatusUnprocessableEntity,
+			gin.H{"error": "invalid request body", "details": err.Error()},
+		)
+
+		return
+	}
+
+	concurrency, err := c.workflowService.UpdateWorkflowConcurrency(
+		ctx.Request.Context(),
+		&models.WorkflowConcurrency{
+			WorkflowID:        int32(workflowID),
+			MaxConcurrentRuns: req.MaxConcurrentRuns,
+			OverlapPolicy:     req.OverlapPolicy,
+		},
+	)
+	if errors.Is(err, services.ErrInvalidWorkflowConcurrency) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.logger.WithError(err).Error("failed to update workflow concurrency")
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{"error": "failed to update workflow concurrency"},
+		)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, concurrency)
+}
+
+func (c *workflowController) GetWorkflowTimeout(ctx *gin.Context) {
+	idStr := ctx.Param("workflowID")
+
+	workflowID, err := strconv.Atoi(idStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+	if err := c.workflowService.VerifyWorkflowAccess(ctx.Request.Context(), int32(workflowID), userID); err != nil {
+		if err == services.ErrUserDoesNotHaveAccessToWorkflow {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to view workflow"})
+
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify workflow access"})
+
+		return
+	}
+
+	timeout, err := c.workflowService.GetWorkflowTimeout(ctx.Request.Context(), int32(workflowID))
+	if err != nil {
+		c.logger.WithError(err).Error("failed to get workflow timeout")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workflow timeout"})
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, timeout)
+}
+
+// UpdateWorkflowTimeout sets how long a run of the workflow may take before it times out.
+func (c *workflowController) UpdateWorkflowTimeout(ctx *gin.Context) {
+	idStr := ctx.Param("workflowID")
+
+	workflowID, err := strconv.Atoi(idStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+	if err := c.workflowService.VerifyWorkflowAccess(ctx.Request.Context(), int32(workflowID), userID); err != nil {
+		if err == services.ErrUserDoesNotHaveAccessToWorkflow {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to update workflow"})
+
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify workflow access"})
+
+		return
+	}
+
+	var req UpdateWorkflowTimeoutRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(
+			http.StatusUnprocessableEntity,
+			gin.H{"error": "invalid request body", "details": err.Error()},
+		)
+
+		return
+	}
+
+	timeout, err := c.workflowService.UpdateWorkflowTimeout(
+		ctx.Request.Context(),
+		&models.WorkflowTimeout{
+			WorkflowID:        int32(workflowID),
+			RunTimeoutSeconds: req.RunTimeoutSeconds,
+		},
+	)
+	if errors.Is(err, services.ErrInvalidWorkflowTimeout) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.logger.WithError(err).Error("failed to update workflow timeout")
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{"error": "failed to update workflow timeout"},
+		)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, timeout)
+}
+
+// GetWorkflowInputSchema returns the inputs runs of the workflow take.
+func (c *workflowController) GetWorkflowInputSchema(ctx *gin.Context) {
+	idStr := ctx.Param("workflowID")
+
+	workflowID, err := strconv.Atoi(idStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+	if err := c.workflowService.VerifyWorkflowAccess(ctx.Request.Context(), int32(workflowID), userID); err != nil {
+		if err == services.ErrUserDoesNotHaveAccessToWorkflow {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to view workflow"})
+
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify workflow access"})
+
+		return
+	}
+
+	schema, err := c.workflowService.GetWorkflowInputSchema(ctx.Request.Context(), int32(workflowID))
+	if err != nil {
+		c.logger.WithError(err).Error("failed to get workflow input schema")
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{"error": "failed to get workflow input schema"},
+		)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schema)
+}
+
+// UpdateWorkflowInputSchema declares the inputs runs of the workflow take.
+func (c *workflowController) UpdateWorkflowInputSchema(ctx *gin.Context) {
+	idStr := ctx.Param("workflowID")
+
+	workflowID, err := strconv.Atoi(idStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+	if err := c.workflowService.VerifyWorkflowAccess(ctx.Request.Context(), int32(workflowID), userID); err != nil {
+		if err == services.ErrUserDoesNotHaveAccessToWorkflow {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to update workflow"})
+
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify workflow access"})
+
+		return
+	}
+
+	var req UpdateWorkflowInputSchemaRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(
+			http.StatusUnprocessableEntity,
+			gin.H{"error": "invalid request body", "details": err.Error()},
+		)
+
+		return
+	}
+
+	schema, err := c.workflowService.UpdateWorkflowInputSchema(
+		ctx.Request.Context(),
+		&models.WorkflowInputSchema{
+			WorkflowID: int32(workflowID),
+			Inputs:     req.Inputs,
+		},
+	)
+	if errors.Is(err, services.ErrInvalidWorkflowInputSchema) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.logger.WithError(err).Error("failed to update workflow input schema")
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{"error": "failed to update workflow input schema"},
+		)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schema)
+}
+
+// GetNodeTypes returns the catalog of node types the user can build workflows from.
+func (c *workflowController) GetNodeTypes(ctx *gin.Context) {
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+
+	nodeTypes, err := c.workflowService.GetNodeTypes(ctx.Request.Context(), userID)
+	if err != nil {
+		c.logger.WithError(err).Error("failed to get node types")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get node types"})
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nodeTypes)
+}
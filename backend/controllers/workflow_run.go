@@ -3,7 +3,9 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -20,6 +22,8 @@ type WorkflowRunController interface {
 	GetWorkflowRuns(ctx *gin.Context)
 	GetWorkflowNodeRuns(ctx *gin.Context)
 	RunWorkflow(ctx *gin.Context)
+	CancelWorkflowRun(ctx *gin.Context)
+	ResumeWorkflowRun(ctx *gin.Context)
 	StreamWorkflowRunProgress(ctx *gin.Context)
 }
 
@@ -45,6 +49,12 @@ func NewWorkflowRunController(cfg models.AppConfig, ctx context.Context) *workfl
 	return c
 }
 
+// parseDryRunQuery reads the dry_run query parameter. Run listings only include real runs
+// unless dry runs are asked for.
+func parseDryRunQuery(ctx *gin.Context) (bool, error) {
+	return strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+}
+
 func (c *workflowRunController) GetWorkflowRun(ctx *gin.Context) {
 	idStr := ctx.Param("runID")
 
@@ -93,7 +103,13 @@ func (c *workflowRunController) GetUserWorkflowRuns(ctx *gin.Context) {
 
 	userID := user.(*models.User).ID
 
-	workflowRuns, err := c.workflowRunRepo.GetUserWorkflowRuns(ctx, userID)
+	dryRun, err := parseDryRunQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+		return
+	}
+
+	workflowRuns, err := c.workflowRunRepo.GetUserWorkflowRuns(ctx, userID, dryRun)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workflow runs"})
 		return
@@ -130,7 +146,13 @@ func (c *workflowRunController) GetWorkflowRuns(ctx *gin.Context) {
 		return
 	}
 
-	workflowRuns, err := c.workflowRunRepo.GetWorkflowRuns(ctx, int32(workflowID))
+	dryRun, err := parseDryRunQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+		return
+	}
+
+	workflowRuns, err := c.workflowRunRepo.GetWorkflowRuns(ctx, int32(workflowID), dryRun)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get workflow runs"})
 		return
@@ -180,7 +202,7 @@ func (c *workflowRunController) GetWorkflowNodeRuns(ctx *gin.Context) {
 }
 
 func (c *workflowRunController) RunWorkflow(ctx *gin.Context) {
-	idStr := ctx.Param("workflowID")
+	idStr := ctx.Param("id")
 
 	workflowID, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -209,8 +231,44 @@ func (c *workflowRunController) RunWorkflow(ctx *gin.Context) {
 		return
 	}
 
-	// TODO: ratelimit
-	runID, err := c.orchestrator.OrchestrateWorkflow(ctx, userID, int32(workflowID))
+	// the body is optional, an empty one starts a regular run with the default inputs
+	var opts models.WorkflowRunOptions
+	if err := ctx.ShouldBindJSON(&opts); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	opts.Manual = true
+
+	runID, err := c.orchestrator.OrchestrateWorkflow(ctx, userID, int32(workflowID), opts)
+
+	var limitErr *services.UsageLimitError
+	if errors.As(err, &limitErr) {
+		retryAfter := int(math.Ceil(limitErr.RetryAfter.Seconds()))
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       limitErr.Reason,
+			"retry_after": retryAfter,
+		})
+
+		return
+	}
+
+	if errors.Is(err, services.ErrWorkflowRunSkipped) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	var inputErr *services.InputValidationError
+	if errors.As(err, &inputErr) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":        "invalid inputs",
+			"input_errors": inputErr.InputErrors,
+		})
+
+		return
+	}
+
 	if err != nil || runID == -1 {
 		// TODO: don't return the error to the client
 		c.logger.WithError(err).Error("failed to execute workflow")
@@ -219,8 +277,113 @@ func (c *workflowRunController) RunWorkflow(ctx *gin.Context) {
 		return
 	}
 
+	ctx.JSON(http.StatusOK, gin.H{
+		"run_id":  runID,
+		"dry_run": opts.DryRun,
+	})
+}
+
+func (c *workflowRunController) CancelWorkflowRun(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+
+	runID, err := strconv.Atoi(idStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid run id"})
+		return
+	}
+
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	run, err := c.workflowRunRepo.GetWorkflowRunCore(ctx, int32(runID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "workflow run not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+	if err := c.workflowService.VerifyWorkflowAccess(ctx, run.WorkflowID, userID); err != nil {
+		if err == services.ErrUserDoesNotHaveAccessToWorkflow {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to cancel workflow run"})
+
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify workflow access"})
+
+		return
+	}
+
+	if err := c.workflowRunService.CancelWorkflowRun(ctx, int32(runID)); err != nil {
+		if errors.Is(err, services.ErrWorkflowRunNotRunning) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "workflow run is not running"})
+			return
+		}
+
+		c.logger.WithError(err).Error("failed to cancel workflow run")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel workflow run"})
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"run_id": runID,
+		"status": "cancelled",
+	})
+}
+
+func (c *workflowRunController) ResumeWorkflowRun(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+
+	runID, err := strconv.Atoi(idStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid run id"})
+		return
+	}
+
+	user, ok := ctx.Get("user")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	run, err := c.workflowRunRepo.GetWorkflowRunCore(ctx, int32(runID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "workflow run not found"})
+		return
+	}
+
+	userID := user.(*models.User).ID
+	if err := c.workflowService.VerifyWorkflowAccess(ctx, run.WorkflowID, userID); err != nil {
+		if err == services.ErrUserDoesNotHaveAccessToWorkflow {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to resume workflow run"})
+
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify workflow access"})
+
+		return
+	}
+
+	if err := c.workflowRunService.ResumeWorkflowRun(ctx, userID, int32(runID)); err != nil {
+		if errors.Is(err, services.ErrWorkflowRunNotFailed) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "only failed workflow runs can be resumed"})
+			return
+		}
+
+		c.logger.WithError(err).Error("failed to resume workflow run")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resume workflow run"})
+
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"run_id": runID,
+		"status": "running",
 	})
 }
 
@@ -366,17 +529,22 @@ func (c *workflowRunController) StreamWorkflowRunProgress(ctx *gin.Context) {
 				return true
 			}
 
-			ctx.SSEvent("node_update", updateEvent)
+			eventType := updateEvent.Type
+			if eventType == "" {
+				eventType = redis.NodeUpdateEvent
+			}
+
+			ctx.SSEvent(eventType, updateEvent)
 
 			if err := ctx.Request.Context().Err(); err != nil {
 				c.logger.WithError(err).
 					WithField("runId", idStr).
-					Error("client disconnected after sending node_update sse event")
+					Errorf("client disconnected after sending %s sse event", eventType)
 
 				return true
 			}
 
-			c.logger.WithField("runId", idStr).Debug("sent node_update sse event")
+			c.logger.WithField("runId", idStr).Debugf("sent %s sse event", eventType)
 
 			return true
 
@@ -43,10 +43,18 @@ type WorkflowCalendar struct {
 	UpdatedAt      int64  `json:"updated_at"`
 }
 
+type WorkflowConcurrency struct {
+	WorkflowID        int32  `json:"workflow_id"`
+	MaxConcurrentRuns int32  `json:"max_concurrent_runs"`
+	OverlapPolicy     string `json:"overlap_policy"`
+	UpdatedAt         int64  `json:"updated_at"`
+}
+
 type WorkflowEdge struct {
-	WorkflowID   int32 `json:"workflow_id"`
-	SourceNodeID int32 `json:"source_node_id"`
-	TargetNodeID int32 `json:"target_node_id"`
+	WorkflowID   int32       `json:"workflow_id"`
+	SourceNodeID int32       `json:"source_node_id"`
+	TargetNodeID int32       `json:"target_node_id"`
+	Label        null.String `json:"label"`
 }
 
 type WorkflowEmail struct {
@@ -61,6 +69,12 @@ type WorkflowEmail struct {
 	UpdatedAt      int64  `json:"updated_at"`
 }
 
+type WorkflowInputSchema struct {
+	WorkflowID int32  `json:"workflow_id"`
+	Inputs     []byte `json:"inputs"`
+	UpdatedAt  int64  `json:"updated_at"`
+}
+
 type WorkflowNode struct {
 	ID         int32  `json:"id"`
 	WorkflowID int32  `json:"workflow_id"`
@@ -79,6 +93,7 @@ type WorkflowNodeRun struct {
 	FinishedAt     null.Int    `json:"finished_at"`
 	Metadata       []byte      `json:"metadata"`
 	ErrorMessage   null.String `json:"error_message"`
+	IterationIndex int32       `json:"iteration_index"`
 }
 
 type WorkflowNodeUi struct {
@@ -88,11 +103,22 @@ type WorkflowNodeUi struct {
 }
 
 type WorkflowRun struct {
-	ID         int32    `json:"id"`
-	WorkflowID int32    `json:"workflow_id"`
-	Status     string   `json:"status"`
-	FinishedAt null.Int `json:"finished_at"`
-	CreatedAt  int64    `json:"created_at"`
+	ID                int32       `json:"id"`
+	WorkflowID        int32       `json:"workflow_id"`
+	Status            string      `json:"status"`
+	FinishedAt        null.Int    `json:"finished_at"`
+	CreatedAt         int64       `json:"created_at"`
+	DryRun            bool        `json:"dry_run"`
+	ParentRunID       null.Int32  `json:"parent_run_id"`
+	ParentNodeRunID   null.Int32  `json:"parent_node_run_id"`
+	Depth             int32       `json:"depth"`
+	Inputs            []byte      `json:"inputs"`
+	SkipReason        null.String `json:"skip_reason"`
+	DeadlineAt        null.Int    `json:"deadline_at"`
+	ErrorMessage      null.String `json:"error_message"`
+	TriggerSource     string      `json:"trigger_source"`
+	TriggerPayload    []byte      `json:"trigger_payload"`
+	WorkflowVersionID int32       `json:"workflow_version_id"`
 }
 
 type WorkflowSchedule struct {
@@ -105,3 +131,36 @@ type WorkflowSchedule struct {
 	CreatedAt      int64    `json:"created_at"`
 	UpdatedAt      int64    `json:"updated_at"`
 }
+
+type WorkflowSideEffect struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	WorkflowRunID  int32  `json:"workflow_run_id"`
+	Output         []byte `json:"output"`
+	CreatedAt      int64  `json:"created_at"`
+}
+
+type WorkflowTaskOutbox struct {
+	ID        int64       `json:"id"`
+	Payload   []byte      `json:"payload"`
+	DelayMs   int64       `json:"delay_ms"`
+	CreatedAt int64       `json:"created_at"`
+	SentAt    null.Int    `json:"sent_at"`
+	Attempts  int32       `json:"attempts"`
+	LastError null.String `json:"last_error"`
+	RetryAt   null.Int    `json:"retry_at"`
+	DeadAt    null.Int    `json:"dead_at"`
+}
+
+type WorkflowVersion struct {
+	ID         int32  `json:"id"`
+	WorkflowID int32  `json:"workflow_id"`
+	Version    int32  `json:"version"`
+	Graph      []byte `json:"graph"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+type WorkflowTimeout struct {
+	WorkflowID        int32 `json:"workflow_id"`
+	RunTimeoutSeconds int32 `json:"run_timeout_seconds"`
+	UpdatedAt         int64 `json:"updated_at"`
+}
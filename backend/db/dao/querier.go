@@ -6,6 +6,8 @@ package dao
 
 import (
 	"context"
+
+	null "github.com/guregu/null/v6"
 )
 
 type Querier interface {
@@ -16,12 +18,49 @@ type Querier interface {
 	//      updated_at = $3
 	//  WHERE id = $1
 	ArchiveWorkflow(ctx context.Context, arg *ArchiveWorkflowParams) error
+	//CancelPendingWorkflowNodeRuns
+	//
+	//  UPDATE workflow_node_run
+	//  SET status = 'cancelled',
+	//      finished_at = $2
+	//  WHERE workflow_run_id = $1
+	//    AND status IN ('pending', 'waiting')
+	CancelPendingWorkflowNodeRuns(ctx context.Context, arg *CancelPendingWorkflowNodeRunsParams) error
+	//CancelWorkflowRun
+	//
+	//  UPDATE workflow_run
+	//  SET status = 'cancelled',
+	//      finished_at = $2
+	//  WHERE id = $1
+	//    AND status IN ('running', 'queued')
+	CancelWorkflowRun(ctx context.Context, arg *CancelWorkflowRunParams) (int64, error)
+	//ClaimOutboxTasks
+	//
+	//  SELECT id, payload, delay_ms, created_at, sent_at, attempts, last_error, retry_at, dead_at
+	//  FROM workflow_task_outbox
+	//  WHERE sent_at IS NULL
+	//    AND dead_at IS NULL
+	//    AND (retry_at IS NULL OR retry_at <= $2)
+	//  ORDER BY id ASC
+	//  LIMIT $1
+	//  FOR UPDATE SKIP LOCKED
+	ClaimOutboxTasks(ctx context.Context, arg *ClaimOutboxTasksParams) ([]*WorkflowTaskOutbox, error)
+	//CompleteLoopWorkflowNodeRun
+	//
+	//  UPDATE workflow_node_run
+	//  SET status = $2,
+	//      finished_at = $3,
+	//      metadata = $4
+	//  WHERE id = $1
+	//    AND status = 'running'
+	CompleteLoopWorkflowNodeRun(ctx context.Context, arg *CompleteLoopWorkflowNodeRunParams) (int64, error)
 	//CompleteWorkflowRun
 	//
 	//  UPDATE workflow_run
 	//  SET status = $2,
 	//      finished_at = $3
 	//  WHERE id = $1
+	//    AND status = 'running'
 	CompleteWorkflowRun(ctx context.Context, arg *CompleteWorkflowRunParams) error
 	//CreateOauthIntegration
 	//
@@ -39,6 +78,27 @@ type Querier interface {
 	//  ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	//  RETURNING id, user_id, provider, provider_user_id, access_token, refresh_token, expires_at, scopes, created_at, updated_at, additional_parameters
 	CreateOauthIntegration(ctx context.Context, arg *CreateOauthIntegrationParams) (*OauthIntegration, error)
+	//CreateSkippedWorkflowRun
+	//
+	//  INSERT INTO workflow_run (
+	//    workflow_id,
+	//    status,
+	//    created_at,
+	//    finished_at,
+	//    dry_run,
+	//    parent_run_id,
+	//    parent_node_run_id,
+	//    depth,
+	//    inputs,
+	//    skip_reason,
+	//    trigger_source,
+	//    trigger_payload,
+	//    workflow_version_id
+	//  ) VALUES (
+	//    $1, 'skipped', $2, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+	//  )
+	//  RETURNING id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
+	CreateSkippedWorkflowRun(ctx context.Context, arg *CreateSkippedWorkflowRunParams) (*WorkflowRun, error)
 	//CreateWorkflow
 	//
 	//  INSERT INTO workflow (
@@ -73,12 +133,13 @@ type Querier interface {
 	//  INSERT INTO workflow_edge (
 	//    workflow_id,
 	//    source_node_id,
-	//    target_node_id
+	//    target_node_id,
+	//    label
 	//  )
 	//  VALUES (
-	//    $1, $2, $3
+	//    $1, $2, $3, $4
 	//  )
-	//  RETURNING workflow_id, source_node_id, target_node_id
+	//  RETURNING workflow_id, source_node_id, target_node_id, label
 	CreateWorkflowEdge(ctx context.Context, arg *CreateWorkflowEdgeParams) (*WorkflowEdge, error)
 	//CreateWorkflowEmail
 	//
@@ -113,11 +174,12 @@ type Querier interface {
 	//    workflow_run_id,
 	//    workflow_node_id,
 	//    status,
-	//    metadata
+	//    metadata,
+	//    iteration_index
 	//  )
-	//  VALUES ($1, $2, $3, $4)
-	//  ON CONFLICT (workflow_run_id, workflow_node_id) DO NOTHING
-	//  RETURNING id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message
+	//  VALUES ($1, $2, $3, $4, $5)
+	//  ON CONFLICT (workflow_run_id, workflow_node_id, iteration_index) DO NOTHING
+	//  RETURNING id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
 	CreateWorkflowNodeRun(ctx context.Context, arg *CreateWorkflowNodeRunParams) (*WorkflowNodeRun, error)
 	//CreateWorkflowNodeUi
 	//
@@ -134,11 +196,21 @@ type Querier interface {
 	//CreateWorkflowRun
 	//
 	//  INSERT INTO workflow_run (
-	//    workflow_id, status, created_at
+	//    workflow_id,
+	//    status,
+	//    created_at,
+	//    dry_run,
+	//    parent_run_id,
+	//    parent_node_run_id,
+	//    depth,
+	//    inputs,
+	//    trigger_source,
+	//    trigger_payload,
+	//    workflow_version_id
 	//  ) VALUES (
-	//    $1, 'running', $2
+	//    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 	//  )
-	//  RETURNING id, workflow_id, status, finished_at, created_at
+	//  RETURNING id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
 	CreateWorkflowRun(ctx context.Context, arg *CreateWorkflowRunParams) (*WorkflowRun, error)
 	//CreateWorkflowSchedule
 	//
@@ -154,11 +226,29 @@ type Querier interface {
 	//  VALUES ($1, $2, $3, $4, $5, $6, $7)
 	//  RETURNING id, workflow_id, schedule_type, next_run_at, last_run_at, execution_state, created_at, updated_at
 	CreateWorkflowSchedule(ctx context.Context, arg *CreateWorkflowScheduleParams) (*WorkflowSchedule, error)
+	//CreateWorkflowVersion
+	//
+	//  INSERT INTO workflow_version (
+	//    workflow_id,
+	//    version,
+	//    graph,
+	//    created_at
+	//  )
+	//  SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3
+	//  FROM workflow_version
+	//  WHERE workflow_id = $1
+	//  RETURNING id, workflow_id, version, graph, created_at
+	CreateWorkflowVersion(ctx context.Context, arg *CreateWorkflowVersionParams) (*WorkflowVersion, error)
 	//DeleteOauthIntegrationByUserID
 	//
 	//  DELETE FROM oauth_integration
 	//  WHERE user_id = $1
 	DeleteOauthIntegrationByUserID(ctx context.Context, userID string) error
+	//DeleteSentOutboxTasks
+	//
+	//  DELETE FROM workflow_task_outbox
+	//  WHERE sent_at < $1
+	DeleteSentOutboxTasks(ctx context.Context, sentAt null.Int) (int64, error)
 	//DeleteWorkflowEdge
 	//
 	//  DELETE FROM workflow_edge
@@ -180,6 +270,15 @@ type Querier interface {
 	//
 	//  DELETE FROM workflow_schedule WHERE workflow_id = $1
 	DeleteWorkflowScheduleByWorkflowID(ctx context.Context, workflowID int32) error
+	//FailRunningWorkflowNodeRuns
+	//
+	//  UPDATE workflow_node_run
+	//  SET status = 'failed',
+	//      finished_at = $2,
+	//      error_message = $3
+	//  WHERE workflow_run_id = $1
+	//    AND status = 'running'
+	FailRunningWorkflowNodeRuns(ctx context.Context, arg *FailRunningWorkflowNodeRunsParams) error
 	//GetActiveWorkflowCalendarsLocked
 	//
 	//  WITH locked AS (
@@ -220,12 +319,17 @@ type Querier interface {
 	GetActiveWorkflowEmailsLocked(ctx context.Context, limit int32) ([]*GetActiveWorkflowEmailsLockedRow, error)
 	//GetChildWorkflowNodeRuns
 	//
-	//  SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message
+	//  SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message, wnr.iteration_index, we.label
 	//  FROM workflow_node_run wnr
-	//  INNER JOIN workflow_edge we ON wnr.workflow_node_id = we.target_node_id
-	//  WHERE workflow_run_id = $1
-	//  AND source_node_id = $2
-	GetChildWorkflowNodeRuns(ctx context.Context, arg *GetChildWorkflowNodeRunsParams) ([]*WorkflowNodeRun, error)
+	//  INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+	//  INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+	//  CROSS JOIN LATERAL jsonb_to_recordset(wv.graph -> 'edges')
+	//    AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+	//  WHERE wnr.workflow_run_id = $1
+	//  AND wnr.workflow_node_id = we.target_node_id
+	//  AND we.source_node_id = $2
+	//  AND wnr.iteration_index = $3
+	GetChildWorkflowNodeRuns(ctx context.Context, arg *GetChildWorkflowNodeRunsParams) ([]*GetChildWorkflowNodeRunsRow, error)
 	//GetDueSchedulesLocked
 	//
 	//  WITH locked AS (
@@ -246,6 +350,23 @@ type Querier interface {
 	//  WHERE workflow_schedule.id = locked.id
 	//  RETURNING workflow_schedule.id, workflow_schedule.workflow_id, workflow_schedule.schedule_type, workflow_schedule.next_run_at, workflow_schedule.last_run_at, workflow_schedule.execution_state, workflow_schedule.created_at, workflow_schedule.updated_at, locked.user_id
 	GetDueSchedulesLocked(ctx context.Context, limit int32) ([]*GetDueSchedulesLockedRow, error)
+	//GetLatestWorkflowVersion
+	//
+	//  SELECT id, workflow_id, version, graph, created_at
+	//  FROM workflow_version
+	//  WHERE workflow_id = $1
+	//  ORDER BY version DESC
+	//  LIMIT 1
+	GetLatestWorkflowVersion(ctx context.Context, workflowID int32) (*WorkflowVersion, error)
+	//GetLoopIterationNodeRuns
+	//
+	//  SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
+	//  FROM workflow_node_run
+	//  WHERE workflow_run_id = $1
+	//    AND workflow_node_id = ANY($2::int[])
+	//    AND iteration_index >= 0
+	//  ORDER BY iteration_index ASC
+	GetLoopIterationNodeRuns(ctx context.Context, arg *GetLoopIterationNodeRunsParams) ([]*WorkflowNodeRun, error)
 	//GetOauthIntegrationByID
 	//
 	//  SELECT id, user_id, provider, provider_user_id, access_token, refresh_token, expires_at, scopes, created_at, updated_at, additional_parameters FROM oauth_integration
@@ -266,12 +387,17 @@ type Querier interface {
 	GetOauthIntegrationsByUserID(ctx context.Context, userID string) ([]*OauthIntegration, error)
 	//GetParentWorkflowNodeRuns
 	//
-	//  SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message
+	//  SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message, wnr.iteration_index, we.label
 	//  FROM workflow_node_run wnr
-	//  INNER JOIN workflow_edge we ON wnr.workflow_node_id = we.source_node_id
-	//  WHERE workflow_run_id = $1
-	//  AND target_node_id = $2
-	GetParentWorkflowNodeRuns(ctx context.Context, arg *GetParentWorkflowNodeRunsParams) ([]*WorkflowNodeRun, error)
+	//  INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+	//  INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+	//  CROSS JOIN LATERAL jsonb_to_recordset(wv.graph -> 'edges')
+	//    AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+	//  WHERE wnr.workflow_run_id = $1
+	//  AND wnr.workflow_node_id = we.source_node_id
+	//  AND we.target_node_id = $2
+	//  AND (wnr.iteration_index = $3 OR wnr.iteration_index = -1)
+	GetParentWorkflowNodeRuns(ctx context.Context, arg *GetParentWorkflowNodeRunsParams) ([]*GetParentWorkflowNodeRunsRow, error)
 	//GetUserWorkflowRuns
 	//
 	//  SELECT
@@ -280,13 +406,16 @@ type Querier interface {
 	//    wr.id as workflow_run_id,
 	//    wr.status as workflow_run_status,
 	//    wr.created_at as workflow_run_created_at,
-	//    wr.finished_at as workflow_run_finished_at
+	//    wr.finished_at as workflow_run_finished_at,
+	//    wr.dry_run as workflow_run_dry_run,
+	//    wr.inputs as workflow_run_inputs
 	//  FROM workflow_run wr
 	//  INNER JOIN workflow w ON wr.workflow_id = w.id
 	//  WHERE w.user_id = $1
+	//    AND wr.dry_run = $2
 	//  ORDER BY wr.created_at DESC
 	//  LIMIT 25
-	GetUserWorkflowRuns(ctx context.Context, userID string) ([]*GetUserWorkflowRunsRow, error)
+	GetUserWorkflowRuns(ctx context.Context, arg *GetUserWorkflowRunsParams) ([]*GetUserWorkflowRunsRow, error)
 	//GetUserWorkflows
 	//
 	//  SELECT id, user_id, name, description, status, created_at, updated_at
@@ -299,6 +428,12 @@ type Querier interface {
 	//  FROM workflow
 	//  WHERE id = $1
 	GetWorkflow(ctx context.Context, id int32) (*Workflow, error)
+	//GetWorkflowConcurrency
+	//
+	//  SELECT workflow_id, max_concurrent_runs, overlap_policy, updated_at
+	//  FROM workflow_concurrency
+	//  WHERE workflow_id = $1
+	GetWorkflowConcurrency(ctx context.Context, workflowID int32) (*WorkflowConcurrency, error)
 	//GetWorkflowGraph
 	//
 	//  SELECT
@@ -312,33 +447,60 @@ type Querier interface {
 	//    node_type,
 	//    config,
 	//    source_node_id,
-	//    target_node_id
+	//    target_node_id,
+	//    label
 	//  FROM workflow w
 	//  INNER JOIN workflow_node wn ON w.id = wn.workflow_id
 	//  LEFT JOIN workflow_edge we ON w.id = we.workflow_id
 	//    AND we.source_node_id = wn.id
 	//  WHERE w.id = $1
 	GetWorkflowGraph(ctx context.Context, id int32) ([]*GetWorkflowGraphRow, error)
+	//GetWorkflowInputSchema
+	//
+	//  SELECT workflow_id, inputs, updated_at
+	//  FROM workflow_input_schema
+	//  WHERE workflow_id = $1
+	GetWorkflowInputSchema(ctx context.Context, workflowID int32) (*WorkflowInputSchema, error)
 	//GetWorkflowNode
 	//
 	//  SELECT id, workflow_id, category, node_type, config
 	//  FROM workflow_node
 	//  WHERE id = $1
 	GetWorkflowNode(ctx context.Context, id int32) (*WorkflowNode, error)
+	//GetWorkflowNodeRunByID
+	//
+	//  SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
+	//  FROM workflow_node_run
+	//  WHERE id = $1
+	GetWorkflowNodeRunByID(ctx context.Context, id int32) (*WorkflowNodeRun, error)
 	//GetWorkflowNodeRunByWorkflowRunIDAndNodeID
 	//
-	//  SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message
+	//  SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
 	//  FROM workflow_node_run
 	//  WHERE workflow_run_id = $1
 	//    AND workflow_node_id = $2
+	//    AND iteration_index = $3
 	GetWorkflowNodeRunByWorkflowRunIDAndNodeID(ctx context.Context, arg *GetWorkflowNodeRunByWorkflowRunIDAndNodeIDParams) (*WorkflowNodeRun, error)
 	//GetWorkflowNodeRunsByRunID
 	//
-	//  SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message
+	//  SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
 	//  FROM workflow_node_run
 	//  WHERE workflow_run_id = $1
 	//  ORDER BY started_at ASC
 	GetWorkflowNodeRunsByRunID(ctx context.Context, workflowRunID int32) ([]*WorkflowNodeRun, error)
+	//GetWorkflowRunByID
+	//
+	//  SELECT id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
+	//  FROM workflow_run
+	//  WHERE id = $1
+	GetWorkflowRunByID(ctx context.Context, id int32) (*WorkflowRun, error)
+	//GetWorkflowRunVersion
+	//
+	//  SELECT wv.id, wv.workflow_id, wv.version, wv.graph, wv.created_at
+	//  FROM workflow_version wv
+	//  INNER JOIN workflow_run wr ON wr.workflow_version_id = wv.id
+	//  WHERE wr.id = $1
+	GetWorkflowRunVersion(ctx context.Context, id int32) (*WorkflowVersion, error)
 	//GetWorkflowRunWithNodeRuns
 	//
 	//  SELECT
@@ -347,25 +509,182 @@ type Querier interface {
 	//    wr.status AS workflow_run_status,
 	//    wr.finished_at AS workflow_run_finished_at,
 	//    wr.created_at AS workflow_run_created_at,
+	//    wr.dry_run AS workflow_run_dry_run,
+	//    wr.parent_run_id AS workflow_run_parent_run_id,
+	//    wr.depth AS workflow_run_depth,
+	//    wr.inputs AS workflow_run_inputs,
+	//    wr.deadline_at AS workflow_run_deadline_at,
+	//    wr.error_message AS workflow_run_error_message,
+	//    wr.trigger_source AS workflow_run_trigger_source,
+	//    wr.trigger_payload AS workflow_run_trigger_payload,
+	//    wr.workflow_version_id AS workflow_run_workflow_version_id,
 	//    wnr.id AS node_run_id,
 	//    wnr.workflow_node_id,
 	//    wnr.status AS node_run_status,
 	//    wnr.started_at AS node_run_started_at,
 	//    wnr.finished_at AS node_run_finished_at,
 	//    wnr.metadata,
-	//    wnr.error_message
+	//    wnr.error_message,
+	//    wnr.iteration_index
 	//  FROM workflow_run wr
 	//  INNER JOIN workflow_node_run wnr ON wr.id = wnr.workflow_run_id
 	//  WHERE wr.id = $1
 	GetWorkflowRunWithNodeRuns(ctx context.Context, id int32) ([]*GetWorkflowRunWithNodeRunsRow, error)
+	//GetWorkflowSideEffect
+	//
+	//  SELECT idempotency_key, workflow_run_id, output, created_at
+	//  FROM workflow_side_effect
+	//  WHERE idempotency_key = $1
+	GetWorkflowSideEffect(ctx context.Context, idempotencyKey string) (*WorkflowSideEffect, error)
+	//GetWorkflowTimeout
+	//
+	//  SELECT workflow_id, run_timeout_seconds, updated_at
+	//  FROM workflow_timeout
+	//  WHERE workflow_id = $1
+	GetWorkflowTimeout(ctx context.Context, workflowID int32) (*WorkflowTimeout, error)
+	//GetWorkflowVersion
+	//
+	//  SELECT id, workflow_id, version, graph, created_at
+	//  FROM workflow_version
+	//  WHERE id = $1
+	GetWorkflowVersion(ctx context.Context, id int32) (*WorkflowVersion, error)
+	//InsertOutboxTask
+	//
+	//  INSERT INTO workflow_task_outbox (payload, delay_ms, created_at)
+	//  VALUES ($1, $2, $3)
+	InsertOutboxTask(ctx context.Context, arg *InsertOutboxTaskParams) error
+	//InsertWorkflowSideEffect
+	//
+	//  INSERT INTO workflow_side_effect (
+	//    idempotency_key,
+	//    workflow_run_id,
+	//    output,
+	//    created_at
+	//  )
+	//  VALUES ($1, $2, $3, $4)
+	//  ON CONFLICT (idempotency_key) DO NOTHING
+	InsertWorkflowSideEffect(ctx context.Context, arg *InsertWorkflowSideEffectParams) error
+	//ListActiveWorkflowRuns
+	//
+	//  SELECT id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
+	//  FROM workflow_run
+	//  WHERE workflow_id = $1
+	//    AND status IN ('running', 'queued')
+	//    AND dry_run = FALSE
+	//  ORDER BY created_at ASC, id ASC
+	ListActiveWorkflowRuns(ctx context.Context, workflowID int32) ([]*WorkflowRun, error)
+	//ListIdlePendingWorkflowNodeRuns
+	//
+	//  SELECT
+	//    wnr.id,
+	//    wnr.workflow_run_id,
+	//    wnr.workflow_node_id,
+	//    wnr.iteration_index,
+	//    wr.workflow_id,
+	//    w.user_id
+	//  FROM workflow_node_run wnr
+	//  INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+	//  INNER JOIN workflow w ON wr.workflow_id = w.id
+	//  INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+	//  CROSS JOIN LATERAL (
+	//    SELECT
+	//      COALESCE(BOOL_AND(
+	//        p.status IN ('success', 'failed', 'skipped', 'cancelled')
+	//        OR (p.status = 'running' AND we.label = 'each')
+	//      ), TRUE) AS settled,
+	//      GREATEST(wr.created_at, MAX(p.started_at), MAX(p.finished_at)) AS ready_at
+	//    FROM jsonb_to_recordset(wv.graph -> 'edges')
+	//      AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+	//    INNER JOIN workflow_node_run p
+	//      ON p.workflow_run_id = wnr.workflow_run_id
+	//      AND p.workflow_node_id = we.source_node_id
+	//      AND p.iteration_index IN (wnr.iteration_index, -1)
+	//    WHERE we.target_node_id = wnr.workflow_node_id
+	//  ) parents
+	//  WHERE wnr.status = 'pending'
+	//    AND wr.status = 'running'
+	//    AND parents.settled
+	//    AND parents.ready_at <= $1
+	//    AND NOT EXISTS (
+	//      SELECT 1
+	//      FROM workflow_task_outbox o
+	//      WHERE o.sent_at IS NULL
+	//        AND (o.payload ->> 'node_run_id')::INTEGER = wnr.id
+	//    )
+	//  ORDER BY wnr.id ASC
+	//  LIMIT $2
+	ListIdlePendingWorkflowNodeRuns(ctx context.Context, arg *ListIdlePendingWorkflowNodeRunsParams) ([]*ListIdlePendingWorkflowNodeRunsRow, error)
+	//ListStuckWorkflowRuns
+	//
+	//  SELECT
+	//    wr.id,
+	//    wr.workflow_id,
+	//    w.user_id,
+	//    wr.deadline_at,
+	//    progress.last_progress_at::BIGINT AS last_progress_at
+	//  FROM workflow_run wr
+	//  INNER JOIN workflow w ON wr.workflow_id = w.id
+	//  CROSS JOIN LATERAL (
+	//    SELECT
+	//      GREATEST(wr.created_at, MAX(wnr.started_at), MAX(wnr.finished_at)) AS last_progress_at,
+	//      COALESCE(BOOL_OR(wnr.status = 'waiting'), FALSE) AS waiting
+	//    FROM workflow_node_run wnr
+	//    WHERE wnr.workflow_run_id = wr.id
+	//  ) progress
+	//  WHERE wr.status = 'running'
+	//    AND (
+	//      wr.deadline_at <= $1
+	//      OR (NOT progress.waiting AND progress.last_progress_at <= $2)
+	//    )
+	//  ORDER BY wr.id ASC
+	//  LIMIT $3
+	ListStuckWorkflowRuns(ctx context.Context, arg *ListStuckWorkflowRunsParams) ([]*ListStuckWorkflowRunsRow, error)
 	//ListWorkflowRuns
 	//
-	//  SELECT id, workflow_id, status, finished_at, created_at
+	//  SELECT id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
 	//  FROM workflow_run
 	//  WHERE workflow_id = $1
+	//    AND dry_run = $2
 	//  ORDER BY created_at DESC
 	//  LIMIT 25
-	ListWorkflowRuns(ctx context.Context, workflowID int32) ([]*WorkflowRun, error)
+	ListWorkflowRuns(ctx context.Context, arg *ListWorkflowRunsParams) ([]*WorkflowRun, error)
+	//LockWorkflowRuns
+	//
+	//  SELECT id
+	//  FROM workflow
+	//  WHERE id = $1
+	//  FOR UPDATE
+	LockWorkflowRuns(ctx context.Context, id int32) error
+	//LockWorkflowVersions
+	//
+	//  SELECT id
+	//  FROM workflow
+	//  WHERE id = $1
+	//  FOR UPDATE
+	LockWorkflowVersions(ctx context.Context, id int32) error
+	//MarkOutboxTaskDead
+	//
+	//  UPDATE workflow_task_outbox
+	//  SET attempts = attempts + 1,
+	//      last_error = $2,
+	//      dead_at = $3
+	//  WHERE id = $1
+	MarkOutboxTaskDead(ctx context.Context, arg *MarkOutboxTaskDeadParams) error
+	//MarkOutboxTaskFailed
+	//
+	//  UPDATE workflow_task_outbox
+	//  SET attempts = attempts + 1,
+	//      last_error = $2,
+	//      retry_at = $3
+	//  WHERE id = $1
+	MarkOutboxTaskFailed(ctx context.Context, arg *MarkOutboxTaskFailedParams) error
+	//MarkOutboxTaskSent
+	//
+	//  UPDATE workflow_task_outbox
+	//  SET sent_at = $2,
+	//      attempts = attempts + 1
+	//  WHERE id = $1
+	MarkOutboxTaskSent(ctx context.Context, arg *MarkOutboxTaskSentParams) error
 	//MarkWorkflowNodeAsRunning
 	//
 	//  UPDATE workflow_node_run
@@ -374,6 +693,13 @@ type Querier interface {
 	//      retry_count = $3
 	//  WHERE id = $1
 	MarkWorkflowNodeAsRunning(ctx context.Context, arg *MarkWorkflowNodeAsRunningParams) error
+	//MarkWorkflowNodeAsWaiting
+	//
+	//  UPDATE workflow_node_run
+	//  SET status = 'waiting',
+	//      metadata = $2
+	//  WHERE id = $1
+	MarkWorkflowNodeAsWaiting(ctx context.Context, arg *MarkWorkflowNodeAsWaitingParams) error
 	//RenderWorkflowGraph
 	//
 	//  SELECT
@@ -389,7 +715,8 @@ type Querier interface {
 	//    node_type,
 	//    config,
 	//    source_node_id,
-	//    target_node_id
+	//    target_node_id,
+	//    label
 	//  FROM workflow w
 	//  INNER JOIN workflow_node wn ON w.id = wn.workflow_id
 	//  INNER JOIN workflow_node_ui wnu ON wn.id = wnu.id
@@ -397,6 +724,67 @@ type Querier interface {
 	//    AND we.source_node_id = wn.id
 	//  WHERE w.id = $1
 	RenderWorkflowGraph(ctx context.Context, id int32) ([]*RenderWorkflowGraphRow, error)
+	//ResetWorkflowNodeRuns
+	//
+	//  UPDATE workflow_node_run
+	//  SET status = 'pending',
+	//      retry_count = 0,
+	//      started_at = NULL,
+	//      finished_at = NULL,
+	//      metadata = NULL,
+	//      error_message = NULL
+	//  WHERE workflow_run_id = $1
+	//    AND id = ANY($2::int[])
+	//    AND status IN ('failed', 'skipped')
+	ResetWorkflowNodeRuns(ctx context.Context, arg *ResetWorkflowNodeRunsParams) error
+	//ResumeWorkflowRun
+	//
+	//  UPDATE workflow_run
+	//  SET status = 'running',
+	//      finished_at = NULL
+	//  WHERE id = $1
+	//    AND status = 'failed'
+	ResumeWorkflowRun(ctx context.Context, id int32) (int64, error)
+	//SetWorkflowNodeRunChildRun
+	//
+	//  UPDATE workflow_node_run
+	//  SET metadata = COALESCE(metadata, '{}'::JSONB) || jsonb_build_object('child_run_id', $2::INTEGER)
+	//  WHERE id = $1
+	SetWorkflowNodeRunChildRun(ctx context.Context, arg *SetWorkflowNodeRunChildRunParams) error
+	//SetWorkflowNodeRunMetadata
+	//
+	//  UPDATE workflow_node_run
+	//  SET metadata = $2
+	//  WHERE id = $1
+	SetWorkflowNodeRunMetadata(ctx context.Context, arg *SetWorkflowNodeRunMetadataParams) error
+	//SetWorkflowNodeRunRetryCount
+	//
+	//  UPDATE workflow_node_run
+	//  SET retry_count = $2
+	//  WHERE id = $1
+	SetWorkflowNodeRunRetryCount(ctx context.Context, arg *SetWorkflowNodeRunRetryCountParams) error
+	//SetWorkflowRunDeadline
+	//
+	//  UPDATE workflow_run
+	//  SET deadline_at = $2
+	//  WHERE id = $1
+	SetWorkflowRunDeadline(ctx context.Context, arg *SetWorkflowRunDeadlineParams) error
+	//StartQueuedWorkflowRun
+	//
+	//  UPDATE workflow_run
+	//  SET status = 'running'
+	//  WHERE id = $1
+	//    AND status = 'queued'
+	StartQueuedWorkflowRun(ctx context.Context, id int32) (int64, error)
+	//TimeOutWorkflowRun
+	//
+	//  UPDATE workflow_run
+	//  SET status = 'timed_out',
+	//      finished_at = $2,
+	//      error_message = $3
+	//  WHERE id = $1
+	//    AND status = 'running'
+	TimeOutWorkflowRun(ctx context.Context, arg *TimeOutWorkflowRunParams) (int64, error)
 	//UpdateOauthIntegration
 	//
 	//  UPDATE oauth_integration
@@ -470,6 +858,47 @@ type Querier interface {
 	//      updated_at = $5
 	//  WHERE workflow_id = $6
 	UpdateWorkflowSchedule(ctx context.Context, arg *UpdateWorkflowScheduleParams) error
+	//UpsertWorkflowConcurrency
+	//
+	//  INSERT INTO workflow_concurrency (
+	//    workflow_id,
+	//    max_concurrent_runs,
+	//    overlap_policy,
+	//    updated_at
+	//  )
+	//  VALUES ($1, $2, $3, $4)
+	//  ON CONFLICT (workflow_id) DO UPDATE
+	//  SET max_concurrent_runs = EXCLUDED.max_concurrent_runs,
+	//      overlap_policy = EXCLUDED.overlap_policy,
+	//      updated_at = EXCLUDED.updated_at
+	//  RETURNING workflow_id, max_concurrent_runs, overlap_policy, updated_at
+	UpsertWorkflowConcurrency(ctx context.Context, arg *UpsertWorkflowConcurrencyParams) (*WorkflowConcurrency, error)
+	//UpsertWorkflowInputSchema
+	//
+	//  INSERT INTO workflow_input_schema (
+	//    workflow_id,
+	//    inputs,
+	//    updated_at
+	//  )
+	//  VALUES ($1, $2, $3)
+	//  ON CONFLICT (workflow_id) DO UPDATE
+	//  SET inputs = EXCLUDED.inputs,
+	//      updated_at = EXCLUDED.updated_at
+	//  RETURNING workflow_id, inputs, updated_at
+	UpsertWorkflowInputSchema(ctx context.Context, arg *UpsertWorkflowInputSchemaParams) (*WorkflowInputSchema, error)
+	//UpsertWorkflowTimeout
+	//
+	//  INSERT INTO workflow_timeout (
+	//    workflow_id,
+	//    run_timeout_seconds,
+	//    updated_at
+	//  )
+	//  VALUES ($1, $2, $3)
+	//  ON CONFLICT (workflow_id) DO UPDATE
+	//  SET run_timeout_seconds = EXCLUDED.run_timeout_seconds,
+	//      updated_at = EXCLUDED.updated_at
+	//  RETURNING workflow_id, run_timeout_seconds, updated_at
+	UpsertWorkflowTimeout(ctx context.Context, arg *UpsertWorkflowTimeoutParams) (*WorkflowTimeout, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -8,6 +8,7 @@ package dao
 import (
 	"context"
 
+	null "github.com/guregu/null/v6"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -99,18 +100,20 @@ const createWorkflowEdge = `-- name: CreateWorkflowEdge :one
 INSERT INTO workflow_edge (
   workflow_id,
   source_node_id,
-  target_node_id
+  target_node_id,
+  label
 )
 VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4
 )
-RETURNING workflow_id, source_node_id, target_node_id
+RETURNING workflow_id, source_node_id, target_node_id, label
 `
 
 type CreateWorkflowEdgeParams struct {
-	WorkflowID   int32 `json:"workflow_id"`
-	SourceNodeID int32 `json:"source_node_id"`
-	TargetNodeID int32 `json:"target_node_id"`
+	WorkflowID   int32       `json:"workflow_id"`
+	SourceNodeID int32       `json:"source_node_id"`
+	TargetNodeID int32       `json:"target_node_id"`
+	Label        null.String `json:"label"`
 }
 
 // CreateWorkflowEdge
@@ -118,16 +121,27 @@ type CreateWorkflowEdgeParams struct {
 //	INSERT INTO workflow_edge (
 //	  workflow_id,
 //	  source_node_id,
-//	  target_node_id
+//	  target_node_id,
+//	  label
 //	)
 //	VALUES (
-//	  $1, $2, $3
+//	  $1, $2, $3, $4
 //	)
-//	RETURNING workflow_id, source_node_id, target_node_id
+//	RETURNING workflow_id, source_node_id, target_node_id, label
 func (q *Queries) CreateWorkflowEdge(ctx context.Context, arg *CreateWorkflowEdgeParams) (*WorkflowEdge, error) {
-	row := q.db.QueryRow(ctx, createWorkflowEdge, arg.WorkflowID, arg.SourceNodeID, arg.TargetNodeID)
+	row := q.db.QueryRow(ctx, createWorkflowEdge,
+		arg.WorkflowID,
+		arg.SourceNodeID,
+		arg.TargetNodeID,
+		arg.Label,
+	)
 	var i WorkflowEdge
-	err := row.Scan(&i.WorkflowID, &i.SourceNodeID, &i.TargetNodeID)
+	err := row.Scan(
+		&i.WorkflowID,
+		&i.SourceNodeID,
+		&i.TargetNodeID,
+		&i.Label,
+	)
 	return &i, err
 }
 
@@ -332,7 +346,8 @@ SELECT
   node_type,
   config,
   source_node_id,
-  target_node_id
+  target_node_id,
+  label
 FROM workflow w
 INNER JOIN workflow_node wn ON w.id = wn.workflow_id
 LEFT JOIN workflow_edge we ON w.id = we.workflow_id
@@ -352,6 +367,7 @@ type GetWorkflowGraphRow struct {
 	Config              []byte      `json:"config"`
 	SourceNodeID        pgtype.Int4 `json:"source_node_id"`
 	TargetNodeID        pgtype.Int4 `json:"target_node_id"`
+	Label               null.String `json:"label"`
 }
 
 // GetWorkflowGraph
@@ -367,7 +383,8 @@ type GetWorkflowGraphRow struct {
 //	  node_type,
 //	  config,
 //	  source_node_id,
-//	  target_node_id
+//	  target_node_id,
+//	  label
 //	FROM workflow w
 //	INNER JOIN workflow_node wn ON w.id = wn.workflow_id
 //	LEFT JOIN workflow_edge we ON w.id = we.workflow_id
@@ -394,6 +411,7 @@ func (q *Queries) GetWorkflowGraph(ctx context.Context, id int32) ([]*GetWorkflo
 			&i.Config,
 			&i.SourceNodeID,
 			&i.TargetNodeID,
+			&i.Label,
 		); err != nil {
 			return nil, err
 		}
@@ -443,7 +461,8 @@ SELECT
   node_type,
   config,
   source_node_id,
-  target_node_id
+  target_node_id,
+  label
 FROM workflow w
 INNER JOIN workflow_node wn ON w.id = wn.workflow_id
 INNER JOIN workflow_node_ui wnu ON wn.id = wnu.id
@@ -466,6 +485,7 @@ type RenderWorkflowGraphRow struct {
 	Config              []byte      `json:"config"`
 	SourceNodeID        pgtype.Int4 `json:"source_node_id"`
 	TargetNodeID        pgtype.Int4 `json:"target_node_id"`
+	Label               null.String `json:"label"`
 }
 
 // RenderWorkflowGraph
@@ -483,7 +503,8 @@ type RenderWorkflowGraphRow struct {
 //	  node_type,
 //	  config,
 //	  source_node_id,
-//	  target_node_id
+//	  target_node_id,
+//	  label
 //	FROM workflow w
 //	INNER JOIN workflow_node wn ON w.id = wn.workflow_id
 //	INNER JOIN workflow_node_ui wnu ON wn.id = wnu.id
@@ -513,6 +534,7 @@ func (q *Queries) RenderWorkflowGraph(ctx context.Context, id int32) ([]*RenderW
 			&i.Config,
 			&i.SourceNodeID,
 			&i.TargetNodeID,
+			&i.Label,
 		); err != nil {
 			return nil, err
 		}
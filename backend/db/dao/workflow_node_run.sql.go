@@ -11,16 +11,79 @@ import (
 	null "github.com/guregu/null/v6"
 )
 
+const cancelPendingWorkflowNodeRuns = `-- name: CancelPendingWorkflowNodeRuns :exec
+UPDATE workflow_node_run
+SET status = 'cancelled',
+    finished_at = $2
+WHERE workflow_run_id = $1
+  AND status IN ('pending', 'waiting')
+`
+
+type CancelPendingWorkflowNodeRunsParams struct {
+	WorkflowRunID int32    `json:"workflow_run_id"`
+	FinishedAt    null.Int `json:"finished_at"`
+}
+
+// CancelPendingWorkflowNodeRuns
+//
+//	UPDATE workflow_node_run
+//	SET status = 'cancelled',
+//	    finished_at = $2
+//	WHERE workflow_run_id = $1
+//	  AND status IN ('pending', 'waiting')
+func (q *Queries) CancelPendingWorkflowNodeRuns(ctx context.Context, arg *CancelPendingWorkflowNodeRunsParams) error {
+	_, err := q.db.Exec(ctx, cancelPendingWorkflowNodeRuns, arg.WorkflowRunID, arg.FinishedAt)
+	return err
+}
+
+const completeLoopWorkflowNodeRun = `-- name: CompleteLoopWorkflowNodeRun :execrows
+UPDATE workflow_node_run
+SET status = $2,
+    finished_at = $3,
+    metadata = $4
+WHERE id = $1
+  AND status = 'running'
+`
+
+type CompleteLoopWorkflowNodeRunParams struct {
+	ID         int32    `json:"id"`
+	Status     string   `json:"status"`
+	FinishedAt null.Int `json:"finished_at"`
+	Metadata   []byte   `json:"metadata"`
+}
+
+// CompleteLoopWorkflowNodeRun
+//
+//	UPDATE workflow_node_run
+//	SET status = $2,
+//	    finished_at = $3,
+//	    metadata = $4
+//	WHERE id = $1
+//	  AND status = 'running'
+func (q *Queries) CompleteLoopWorkflowNodeRun(ctx context.Context, arg *CompleteLoopWorkflowNodeRunParams) (int64, error) {
+	result, err := q.db.Exec(ctx, completeLoopWorkflowNodeRun,
+		arg.ID,
+		arg.Status,
+		arg.FinishedAt,
+		arg.Metadata,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const createWorkflowNodeRun = `-- name: CreateWorkflowNodeRun :one
 INSERT INTO workflow_node_run (
   workflow_run_id,
   workflow_node_id,
   status,
-  metadata
+  metadata,
+  iteration_index
 )
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (workflow_run_id, workflow_node_id) DO NOTHING
-RETURNING id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (workflow_run_id, workflow_node_id, iteration_index) DO NOTHING
+RETURNING id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
 `
 
 type CreateWorkflowNodeRunParams struct {
@@ -28,6 +91,7 @@ type CreateWorkflowNodeRunParams struct {
 	WorkflowNodeID int32  `json:"workflow_node_id"`
 	Status         string `json:"status"`
 	Metadata       []byte `json:"metadata"`
+	IterationIndex int32  `json:"iteration_index"`
 }
 
 // CreateWorkflowNodeRun
@@ -36,17 +100,19 @@ type CreateWorkflowNodeRunParams struct {
 //	  workflow_run_id,
 //	  workflow_node_id,
 //	  status,
-//	  metadata
+//	  metadata,
+//	  iteration_index
 //	)
-//	VALUES ($1, $2, $3, $4)
-//	ON CONFLICT (workflow_run_id, workflow_node_id) DO NOTHING
-//	RETURNING id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message
+//	VALUES ($1, $2, $3, $4, $5)
+//	ON CONFLICT (workflow_run_id, workflow_node_id, iteration_index) DO NOTHING
+//	RETURNING id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
 func (q *Queries) CreateWorkflowNodeRun(ctx context.Context, arg *CreateWorkflowNodeRunParams) (*WorkflowNodeRun, error) {
 	row := q.db.QueryRow(ctx, createWorkflowNodeRun,
 		arg.WorkflowRunID,
 		arg.WorkflowNodeID,
 		arg.Status,
 		arg.Metadata,
+		arg.IterationIndex,
 	)
 	var i WorkflowNodeRun
 	err := row.Scan(
@@ -59,32 +125,140 @@ func (q *Queries) CreateWorkflowNodeRun(ctx context.Context, arg *CreateWorkflow
 		&i.FinishedAt,
 		&i.Metadata,
 		&i.ErrorMessage,
+		&i.IterationIndex,
 	)
 	return &i, err
 }
 
+const failRunningWorkflowNodeRuns = `-- name: FailRunningWorkflowNodeRuns :exec
+UPDATE workflow_node_run
+SET status = 'failed',
+    finished_at = $2,
+    error_message = $3
+WHERE workflow_run_id = $1
+  AND status = 'running'
+`
+
+type FailRunningWorkflowNodeRunsParams struct {
+	WorkflowRunID int32       `json:"workflow_run_id"`
+	FinishedAt    null.Int    `json:"finished_at"`
+	ErrorMessage  null.String `json:"error_message"`
+}
+
+// FailRunningWorkflowNodeRuns
+//
+//	UPDATE workflow_node_run
+//	SET status = 'failed',
+//	    finished_at = $2,
+//	    error_message = $3
+//	WHERE workflow_run_id = $1
+//	  AND status = 'running'
+func (q *Queries) FailRunningWorkflowNodeRuns(ctx context.Context, arg *FailRunningWorkflowNodeRunsParams) error {
+	_, err := q.db.Exec(ctx, failRunningWorkflowNodeRuns, arg.WorkflowRunID, arg.FinishedAt, arg.ErrorMessage)
+	return err
+}
+
 const getChildWorkflowNodeRuns = `-- name: GetChildWorkflowNodeRuns :many
-SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message
+SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message, wnr.iteration_index, we.label
 FROM workflow_node_run wnr
-INNER JOIN workflow_edge we ON wnr.workflow_node_id = we.target_node_id
-WHERE workflow_run_id = $1
-AND source_node_id = $2
+INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+CROSS JOIN LATERAL jsonb_to_recordset(wv.graph -> 'edges')
+  AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+WHERE wnr.workflow_run_id = $1
+AND wnr.workflow_node_id = we.target_node_id
+AND we.source_node_id = $2
+AND wnr.iteration_index = $3
 `
 
 type GetChildWorkflowNodeRunsParams struct {
-	WorkflowRunID int32 `json:"workflow_run_id"`
-	SourceNodeID  int32 `json:"source_node_id"`
+	WorkflowRunID  int32 `json:"workflow_run_id"`
+	SourceNodeID   int32 `json:"source_node_id"`
+	IterationIndex int32 `json:"iteration_index"`
+}
+
+type GetChildWorkflowNodeRunsRow struct {
+	ID             int32       `json:"id"`
+	WorkflowRunID  int32       `json:"workflow_run_id"`
+	WorkflowNodeID int32       `json:"workflow_node_id"`
+	Status         string      `json:"status"`
+	RetryCount     int32       `json:"retry_count"`
+	StartedAt      null.Int    `json:"started_at"`
+	FinishedAt     null.Int    `json:"finished_at"`
+	Metadata       []byte      `json:"metadata"`
+	ErrorMessage   null.String `json:"error_message"`
+	IterationIndex int32       `json:"iteration_index"`
+	Label          null.String `json:"label"`
 }
 
 // GetChildWorkflowNodeRuns
 //
-//	SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message
+//	SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message, wnr.iteration_index, we.label
 //	FROM workflow_node_run wnr
-//	INNER JOIN workflow_edge we ON wnr.workflow_node_id = we.target_node_id
+//	INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+//	INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+//	CROSS JOIN LATERAL jsonb_to_recordset(wv.graph -> 'edges')
+//	  AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+//	WHERE wnr.workflow_run_id = $1
+//	AND wnr.workflow_node_id = we.target_node_id
+//	AND we.source_node_id = $2
+//	AND wnr.iteration_index = $3
+func (q *Queries) GetChildWorkflowNodeRuns(ctx context.Context, arg *GetChildWorkflowNodeRunsParams) ([]*GetChildWorkflowNodeRunsRow, error) {
+	rows, err := q.db.Query(ctx, getChildWorkflowNodeRuns, arg.WorkflowRunID, arg.SourceNodeID, arg.IterationIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*GetChildWorkflowNodeRunsRow
+	for rows.Next() {
+		var i GetChildWorkflowNodeRunsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowRunID,
+			&i.WorkflowNodeID,
+			&i.Status,
+			&i.RetryCount,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.Metadata,
+			&i.ErrorMessage,
+			&i.IterationIndex,
+			&i.Label,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLoopIterationNodeRuns = `-- name: GetLoopIterationNodeRuns :many
+SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
+FROM workflow_node_run
+WHERE workflow_run_id = $1
+  AND workflow_node_id = ANY($2::int[])
+  AND iteration_index >= 0
+ORDER BY iteration_index ASC
+`
+
+type GetLoopIterationNodeRunsParams struct {
+	WorkflowRunID int32   `json:"workflow_run_id"`
+	NodeIds       []int32 `json:"node_ids"`
+}
+
+// GetLoopIterationNodeRuns
+//
+//	SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
+//	FROM workflow_node_run
 //	WHERE workflow_run_id = $1
-//	AND source_node_id = $2
-func (q *Queries) GetChildWorkflowNodeRuns(ctx context.Context, arg *GetChildWorkflowNodeRunsParams) ([]*WorkflowNodeRun, error) {
-	rows, err := q.db.Query(ctx, getChildWorkflowNodeRuns, arg.WorkflowRunID, arg.SourceNodeID)
+//	  AND workflow_node_id = ANY($2::int[])
+//	  AND iteration_index >= 0
+//	ORDER BY iteration_index ASC
+func (q *Queries) GetLoopIterationNodeRuns(ctx context.Context, arg *GetLoopIterationNodeRunsParams) ([]*WorkflowNodeRun, error) {
+	rows, err := q.db.Query(ctx, getLoopIterationNodeRuns, arg.WorkflowRunID, arg.NodeIds)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +276,7 @@ func (q *Queries) GetChildWorkflowNodeRuns(ctx context.Context, arg *GetChildWor
 			&i.FinishedAt,
 			&i.Metadata,
 			&i.ErrorMessage,
+			&i.IterationIndex,
 		); err != nil {
 			return nil, err
 		}
@@ -114,34 +289,59 @@ func (q *Queries) GetChildWorkflowNodeRuns(ctx context.Context, arg *GetChildWor
 }
 
 const getParentWorkflowNodeRuns = `-- name: GetParentWorkflowNodeRuns :many
-SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message
+SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message, wnr.iteration_index, we.label
 FROM workflow_node_run wnr
-INNER JOIN workflow_edge we ON wnr.workflow_node_id = we.source_node_id
-WHERE workflow_run_id = $1
-AND target_node_id = $2
+INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+CROSS JOIN LATERAL jsonb_to_recordset(wv.graph -> 'edges')
+  AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+WHERE wnr.workflow_run_id = $1
+AND wnr.workflow_node_id = we.source_node_id
+AND we.target_node_id = $2
+AND (wnr.iteration_index = $3 OR wnr.iteration_index = -1)
 `
 
 type GetParentWorkflowNodeRunsParams struct {
-	WorkflowRunID int32 `json:"workflow_run_id"`
-	TargetNodeID  int32 `json:"target_node_id"`
+	WorkflowRunID  int32 `json:"workflow_run_id"`
+	TargetNodeID   int32 `json:"target_node_id"`
+	IterationIndex int32 `json:"iteration_index"`
+}
+
+type GetParentWorkflowNodeRunsRow struct {
+	ID             int32       `json:"id"`
+	WorkflowRunID  int32       `json:"workflow_run_id"`
+	WorkflowNodeID int32       `json:"workflow_node_id"`
+	Status         string      `json:"status"`
+	RetryCount     int32       `json:"retry_count"`
+	StartedAt      null.Int    `json:"started_at"`
+	FinishedAt     null.Int    `json:"finished_at"`
+	Metadata       []byte      `json:"metadata"`
+	ErrorMessage   null.String `json:"error_message"`
+	IterationIndex int32       `json:"iteration_index"`
+	Label          null.String `json:"label"`
 }
 
 // GetParentWorkflowNodeRuns
 //
-//	SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message
+//	SELECT wnr.id, wnr.workflow_run_id, wnr.workflow_node_id, wnr.status, wnr.retry_count, wnr.started_at, wnr.finished_at, wnr.metadata, wnr.error_message, wnr.iteration_index, we.label
 //	FROM workflow_node_run wnr
-//	INNER JOIN workflow_edge we ON wnr.workflow_node_id = we.source_node_id
-//	WHERE workflow_run_id = $1
-//	AND target_node_id = $2
-func (q *Queries) GetParentWorkflowNodeRuns(ctx context.Context, arg *GetParentWorkflowNodeRunsParams) ([]*WorkflowNodeRun, error) {
-	rows, err := q.db.Query(ctx, getParentWorkflowNodeRuns, arg.WorkflowRunID, arg.TargetNodeID)
+//	INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+//	INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+//	CROSS JOIN LATERAL jsonb_to_recordset(wv.graph -> 'edges')
+//	  AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+//	WHERE wnr.workflow_run_id = $1
+//	AND wnr.workflow_node_id = we.source_node_id
+//	AND we.target_node_id = $2
+//	AND (wnr.iteration_index = $3 OR wnr.iteration_index = -1)
+func (q *Queries) GetParentWorkflowNodeRuns(ctx context.Context, arg *GetParentWorkflowNodeRunsParams) ([]*GetParentWorkflowNodeRunsRow, error) {
+	rows, err := q.db.Query(ctx, getParentWorkflowNodeRuns, arg.WorkflowRunID, arg.TargetNodeID, arg.IterationIndex)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []*WorkflowNodeRun
+	var items []*GetParentWorkflowNodeRunsRow
 	for rows.Next() {
-		var i WorkflowNodeRun
+		var i GetParentWorkflowNodeRunsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.WorkflowRunID,
@@ -152,6 +352,8 @@ func (q *Queries) GetParentWorkflowNodeRuns(ctx context.Context, arg *GetParentW
 			&i.FinishedAt,
 			&i.Metadata,
 			&i.ErrorMessage,
+			&i.IterationIndex,
+			&i.Label,
 		); err != nil {
 			return nil, err
 		}
@@ -163,26 +365,58 @@ func (q *Queries) GetParentWorkflowNodeRuns(ctx context.Context, arg *GetParentW
 	return items, nil
 }
 
+const getWorkflowNodeRunByID = `-- name: GetWorkflowNodeRunByID :one
+SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
+FROM workflow_node_run
+WHERE id = $1
+`
+
+// GetWorkflowNodeRunByID
+//
+//	SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
+//	FROM workflow_node_run
+//	WHERE id = $1
+func (q *Queries) GetWorkflowNodeRunByID(ctx context.Context, id int32) (*WorkflowNodeRun, error) {
+	row := q.db.QueryRow(ctx, getWorkflowNodeRunByID, id)
+	var i WorkflowNodeRun
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowRunID,
+		&i.WorkflowNodeID,
+		&i.Status,
+		&i.RetryCount,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.Metadata,
+		&i.ErrorMessage,
+		&i.IterationIndex,
+	)
+	return &i, err
+}
+
 const getWorkflowNodeRunByWorkflowRunIDAndNodeID = `-- name: GetWorkflowNodeRunByWorkflowRunIDAndNodeID :one
-SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message
+SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
 FROM workflow_node_run
 WHERE workflow_run_id = $1
   AND workflow_node_id = $2
+  AND iteration_index = $3
 `
 
 type GetWorkflowNodeRunByWorkflowRunIDAndNodeIDParams struct {
 	WorkflowRunID  int32 `json:"workflow_run_id"`
 	WorkflowNodeID int32 `json:"workflow_node_id"`
+	IterationIndex int32 `json:"iteration_index"`
 }
 
 // GetWorkflowNodeRunByWorkflowRunIDAndNodeID
 //
-//	SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message
+//	SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
 //	FROM workflow_node_run
 //	WHERE workflow_run_id = $1
 //	  AND workflow_node_id = $2
+//	  AND iteration_index = $3
 func (q *Queries) GetWorkflowNodeRunByWorkflowRunIDAndNodeID(ctx context.Context, arg *GetWorkflowNodeRunByWorkflowRunIDAndNodeIDParams) (*WorkflowNodeRun, error) {
-	row := q.db.QueryRow(ctx, getWorkflowNodeRunByWorkflowRunIDAndNodeID, arg.WorkflowRunID, arg.WorkflowNodeID)
+	row := q.db.QueryRow(ctx, getWorkflowNodeRunByWorkflowRunIDAndNodeID, arg.WorkflowRunID, arg.WorkflowNodeID, arg.IterationIndex)
 	var i WorkflowNodeRun
 	err := row.Scan(
 		&i.ID,
@@ -194,12 +428,13 @@ func (q *Queries) GetWorkflowNodeRunByWorkflowRunIDAndNodeID(ctx context.Context
 		&i.FinishedAt,
 		&i.Metadata,
 		&i.ErrorMessage,
+		&i.IterationIndex,
 	)
 	return &i, err
 }
 
 const getWorkflowNodeRunsByRunID = `-- name: GetWorkflowNodeRunsByRunID :many
-SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message
+SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
 FROM workflow_node_run
 WHERE workflow_run_id = $1
 ORDER BY started_at ASC
@@ -207,7 +442,7 @@ ORDER BY started_at ASC
 
 // GetWorkflowNodeRunsByRunID
 //
-//	SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message
+//	SELECT id, workflow_run_id, workflow_node_id, status, retry_count, started_at, finished_at, metadata, error_message, iteration_index
 //	FROM workflow_node_run
 //	WHERE workflow_run_id = $1
 //	ORDER BY started_at ASC
@@ -230,6 +465,129 @@ func (q *Queries) GetWorkflowNodeRunsByRunID(ctx context.Context, workflowRunID
 			&i.FinishedAt,
 			&i.Metadata,
 			&i.ErrorMessage,
+			&i.IterationIndex,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIdlePendingWorkflowNodeRuns = `-- name: ListIdlePendingWorkflowNodeRuns :many
+SELECT
+  wnr.id,
+  wnr.workflow_run_id,
+  wnr.workflow_node_id,
+  wnr.iteration_index,
+  wr.workflow_id,
+  w.user_id
+FROM workflow_node_run wnr
+INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+INNER JOIN workflow w ON wr.workflow_id = w.id
+INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+CROSS JOIN LATERAL (
+  SELECT
+    COALESCE(BOOL_AND(
+      p.status IN ('success', 'failed', 'skipped', 'cancelled')
+      OR (p.status = 'running' AND we.label = 'each')
+    ), TRUE) AS settled,
+    GREATEST(wr.created_at, MAX(p.started_at), MAX(p.finished_at)) AS ready_at
+  FROM jsonb_to_recordset(wv.graph -> 'edges')
+    AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+  INNER JOIN workflow_node_run p
+    ON p.workflow_run_id = wnr.workflow_run_id
+    AND p.workflow_node_id = we.source_node_id
+    AND p.iteration_index IN (wnr.iteration_index, -1)
+  WHERE we.target_node_id = wnr.workflow_node_id
+) parents
+WHERE wnr.status = 'pending'
+  AND wr.status = 'running'
+  AND parents.settled
+  AND parents.ready_at <= $1
+  AND NOT EXISTS (
+    SELECT 1
+    FROM workflow_task_outbox o
+    WHERE o.sent_at IS NULL
+      AND (o.payload ->> 'node_run_id')::INTEGER = wnr.id
+  )
+ORDER BY wnr.id ASC
+LIMIT $2
+`
+
+type ListIdlePendingWorkflowNodeRunsParams struct {
+	IdleBefore  int64 `json:"idle_before"`
+	MaxNodeRuns int32 `json:"max_node_runs"`
+}
+
+type ListIdlePendingWorkflowNodeRunsRow struct {
+	ID             int32  `json:"id"`
+	WorkflowRunID  int32  `json:"workflow_run_id"`
+	WorkflowNodeID int32  `json:"workflow_node_id"`
+	IterationIndex int32  `json:"iteration_index"`
+	WorkflowID     int32  `json:"workflow_id"`
+	UserID         string `json:"user_id"`
+}
+
+// ListIdlePendingWorkflowNodeRuns
+//
+//	SELECT
+//	  wnr.id,
+//	  wnr.workflow_run_id,
+//	  wnr.workflow_node_id,
+//	  wnr.iteration_index,
+//	  wr.workflow_id,
+//	  w.user_id
+//	FROM workflow_node_run wnr
+//	INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+//	INNER JOIN workflow w ON wr.workflow_id = w.id
+//	INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+//	CROSS JOIN LATERAL (
+//	  SELECT
+//	    COALESCE(BOOL_AND(
+//	      p.status IN ('success', 'failed', 'skipped', 'cancelled')
+//	      OR (p.status = 'running' AND we.label = 'each')
+//	    ), TRUE) AS settled,
+//	    GREATEST(wr.created_at, MAX(p.started_at), MAX(p.finished_at)) AS ready_at
+//	  FROM jsonb_to_recordset(wv.graph -> 'edges')
+//	    AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+//	  INNER JOIN workflow_node_run p
+//	    ON p.workflow_run_id = wnr.workflow_run_id
+//	    AND p.workflow_node_id = we.source_node_id
+//	    AND p.iteration_index IN (wnr.iteration_index, -1)
+//	  WHERE we.target_node_id = wnr.workflow_node_id
+//	) parents
+//	WHERE wnr.status = 'pending'
+//	  AND wr.status = 'running'
+//	  AND parents.settled
+//	  AND parents.ready_at <= $1
+//	  AND NOT EXISTS (
+//	    SELECT 1
+//	    FROM workflow_task_outbox o
+//	    WHERE o.sent_at IS NULL
+//	      AND (o.payload ->> 'node_run_id')::INTEGER = wnr.id
+//	  )
+//	ORDER BY wnr.id ASC
+//	LIMIT $2
+func (q *Queries) ListIdlePendingWorkflowNodeRuns(ctx context.Context, arg *ListIdlePendingWorkflowNodeRunsParams) ([]*ListIdlePendingWorkflowNodeRunsRow, error) {
+	rows, err := q.db.Query(ctx, listIdlePendingWorkflowNodeRuns, arg.IdleBefore, arg.MaxNodeRuns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListIdlePendingWorkflowNodeRunsRow
+	for rows.Next() {
+		var i ListIdlePendingWorkflowNodeRunsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowRunID,
+			&i.WorkflowNodeID,
+			&i.IterationIndex,
+			&i.WorkflowID,
+			&i.UserID,
 		); err != nil {
 			return nil, err
 		}
@@ -267,6 +625,127 @@ func (q *Queries) MarkWorkflowNodeAsRunning(ctx context.Context, arg *MarkWorkfl
 	return err
 }
 
+const markWorkflowNodeAsWaiting = `-- name: MarkWorkflowNodeAsWaiting :exec
+UPDATE workflow_node_run
+SET status = 'waiting',
+    metadata = $2
+WHERE id = $1
+`
+
+type MarkWorkflowNodeAsWaitingParams struct {
+	ID       int32  `json:"id"`
+	Metadata []byte `json:"metadata"`
+}
+
+// MarkWorkflowNodeAsWaiting
+//
+//	UPDATE workflow_node_run
+//	SET status = 'waiting',
+//	    metadata = $2
+//	WHERE id = $1
+func (q *Queries) MarkWorkflowNodeAsWaiting(ctx context.Context, arg *MarkWorkflowNodeAsWaitingParams) error {
+	_, err := q.db.Exec(ctx, markWorkflowNodeAsWaiting, arg.ID, arg.Metadata)
+	return err
+}
+
+const resetWorkflowNodeRuns = `-- name: ResetWorkflowNodeRuns :exec
+UPDATE workflow_node_run
+SET status = 'pending',
+    retry_count = 0,
+    started_at = NULL,
+    finished_at = NULL,
+    metadata = NULL,
+    error_message = NULL
+WHERE workflow_run_id = $1
+  AND id = ANY($2::int[])
+  AND status IN ('failed', 'skipped')
+`
+
+type ResetWorkflowNodeRunsParams struct {
+	WorkflowRunID int32   `json:"workflow_run_id"`
+	Ids           []int32 `json:"ids"`
+}
+
+// ResetWorkflowNodeRuns
+//
+//	UPDATE workflow_node_run
+//	SET status = 'pending',
+//	    retry_count = 0,
+//	    started_at = NULL,
+//	    finished_at = NULL,
+//	    metadata = NULL,
+//	    error_message = NULL
+//	WHERE workflow_run_id = $1
+//	  AND id = ANY($2::int[])
+//	  AND status IN ('failed', 'skipped')
+func (q *Queries) ResetWorkflowNodeRuns(ctx context.Context, arg *ResetWorkflowNodeRunsParams) error {
+	_, err := q.db.Exec(ctx, resetWorkflowNodeRuns, arg.WorkflowRunID, arg.Ids)
+	return err
+}
+
+const setWorkflowNodeRunChildRun = `-- name: SetWorkflowNodeRunChildRun :exec
+UPDATE workflow_node_run
+SET metadata = COALESCE(metadata, '{}'::JSONB) || jsonb_build_object('child_run_id', $2::INTEGER)
+WHERE id = $1
+`
+
+type SetWorkflowNodeRunChildRunParams struct {
+	ID         int32 `json:"id"`
+	ChildRunID int32 `json:"child_run_id"`
+}
+
+// SetWorkflowNodeRunChildRun
+//
+//	UPDATE workflow_node_run
+//	SET metadata = COALESCE(metadata, '{}'::JSONB) || jsonb_build_object('child_run_id', $2::INTEGER)
+//	WHERE id = $1
+func (q *Queries) SetWorkflowNodeRunChildRun(ctx context.Context, arg *SetWorkflowNodeRunChildRunParams) error {
+	_, err := q.db.Exec(ctx, setWorkflowNodeRunChildRun, arg.ID, arg.ChildRunID)
+	return err
+}
+
+const setWorkflowNodeRunMetadata = `-- name: SetWorkflowNodeRunMetadata :exec
+UPDATE workflow_node_run
+SET metadata = $2
+WHERE id = $1
+`
+
+type SetWorkflowNodeRunMetadataParams struct {
+	ID       int32  `json:"id"`
+	Metadata []byte `json:"metadata"`
+}
+
+// SetWorkflowNodeRunMetadata
+//
+//	UPDATE workflow_node_run
+//	SET metadata = $2
+//	WHERE id = $1
+func (q *Queries) SetWorkflowNodeRunMetadata(ctx context.Context, arg *SetWorkflowNodeRunMetadataParams) error {
+	_, err := q.db.Exec(ctx, setWorkflowNodeRunMetadata, arg.ID, arg.Metadata)
+	return err
+}
+
+const setWorkflowNodeRunRetryCount = `-- name: SetWorkflowNodeRunRetryCount :exec
+UPDATE workflow_node_run
+SET retry_count = $2
+WHERE id = $1
+`
+
+type SetWorkflowNodeRunRetryCountParams struct {
+	ID         int32 `json:"id"`
+	RetryCount int32 `json:"retry_count"`
+}
+
+// SetWorkflowNodeRunRetryCount
+//
+//	UPDATE workflow_node_run
+//	SET retry_count = $2
+//	WHERE id = $1
+func (q *Queries) SetWorkflowNodeRunRetryCount(ctx context.Context, arg *SetWorkflowNodeRunRetryCountParams) error {
+	_, err := q.db.Exec(ctx, setWorkflowNodeRunRetryCount, arg.ID, arg.RetryCount)
+	return err
+}
+
 const updateWorkflowNodeRun = `-- name: UpdateWorkflowNodeRun :exec
 UPDATE workflow_node_run
 SET status = $2,
@@ -11,11 +11,40 @@ import (
 	null "github.com/guregu/null/v6"
 )
 
+const cancelWorkflowRun = `-- name: CancelWorkflowRun :execrows
+UPDATE workflow_run
+SET status = 'cancelled',
+    finished_at = $2
+WHERE id = $1
+  AND status IN ('running', 'queued')
+`
+
+type CancelWorkflowRunParams struct {
+	ID         int32    `json:"id"`
+	FinishedAt null.Int `json:"finished_at"`
+}
+
+// CancelWorkflowRun
+//
+//	UPDATE workflow_run
+//	SET status = 'cancelled',
+//	    finished_at = $2
+//	WHERE id = $1
+//	  AND status IN ('running', 'queued')
+func (q *Queries) CancelWorkflowRun(ctx context.Context, arg *CancelWorkflowRunParams) (int64, error) {
+	result, err := q.db.Exec(ctx, cancelWorkflowRun, arg.ID, arg.FinishedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const completeWorkflowRun = `-- name: CompleteWorkflowRun :exec
 UPDATE workflow_run
 SET status = $2,
     finished_at = $3
 WHERE id = $1
+  AND status = 'running'
 `
 
 type CompleteWorkflowRunParams struct {
@@ -30,35 +59,168 @@ type CompleteWorkflowRunParams struct {
 //	SET status = $2,
 //	    finished_at = $3
 //	WHERE id = $1
+//	  AND status = 'running'
 func (q *Queries) CompleteWorkflowRun(ctx context.Context, arg *CompleteWorkflowRunParams) error {
 	_, err := q.db.Exec(ctx, completeWorkflowRun, arg.ID, arg.Status, arg.FinishedAt)
 	return err
 }
 
+const createSkippedWorkflowRun = `-- name: CreateSkippedWorkflowRun :one
+INSERT INTO workflow_run (
+  workflow_id,
+  status,
+  created_at,
+  finished_at,
+  dry_run,
+  parent_run_id,
+  parent_node_run_id,
+  depth,
+  inputs,
+  skip_reason,
+  trigger_source,
+  trigger_payload,
+  workflow_version_id
+) VALUES (
+  $1, 'skipped', $2, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+RETURNING id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
+`
+
+type CreateSkippedWorkflowRunParams struct {
+	WorkflowID        int32       `json:"workflow_id"`
+	CreatedAt         int64       `json:"created_at"`
+	DryRun            bool        `json:"dry_run"`
+	ParentRunID       null.Int32  `json:"parent_run_id"`
+	ParentNodeRunID   null.Int32  `json:"parent_node_run_id"`
+	Depth             int32       `json:"depth"`
+	Inputs            []byte      `json:"inputs"`
+	SkipReason        null.String `json:"skip_reason"`
+	TriggerSource     string      `json:"trigger_source"`
+	TriggerPayload    []byte      `json:"trigger_payload"`
+	WorkflowVersionID int32       `json:"workflow_version_id"`
+}
+
+// CreateSkippedWorkflowRun
+//
+//	INSERT INTO workflow_run (
+//	  workflow_id,
+//	  status,
+//	  created_at,
+//	  finished_at,
+//	  dry_run,
+//	  parent_run_id,
+//	  parent_node_run_id,
+//	  depth,
+//	  inputs,
+//	  skip_reason,
+//	  trigger_source,
+//	  trigger_payload,
+//	  workflow_version_id
+//	) VALUES (
+//	  $1, 'skipped', $2, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+//	)
+//	RETURNING id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
+func (q *Queries) CreateSkippedWorkflowRun(ctx context.Context, arg *CreateSkippedWorkflowRunParams) (*WorkflowRun, error) {
+	row := q.db.QueryRow(ctx, createSkippedWorkflowRun,
+		arg.WorkflowID,
+		arg.CreatedAt,
+		arg.DryRun,
+		arg.ParentRunID,
+		arg.ParentNodeRunID,
+		arg.Depth,
+		arg.Inputs,
+		arg.SkipReason,
+		arg.TriggerSource,
+		arg.TriggerPayload,
+		arg.WorkflowVersionID,
+	)
+	var i WorkflowRun
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.Status,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.DryRun,
+		&i.ParentRunID,
+		&i.ParentNodeRunID,
+		&i.Depth,
+		&i.Inputs,
+		&i.SkipReason,
+		&i.DeadlineAt,
+		&i.ErrorMessage,
+		&i.TriggerSource,
+		&i.TriggerPayload,
+		&i.WorkflowVersionID,
+	)
+	return &i, err
+}
+
 const createWorkflowRun = `-- name: CreateWorkflowRun :one
 INSERT INTO workflow_run (
-  workflow_id, status, created_at
+  workflow_id,
+  status,
+  created_at,
+  dry_run,
+  parent_run_id,
+  parent_node_run_id,
+  depth,
+  inputs,
+  trigger_source,
+  trigger_payload,
+  workflow_version_id
 ) VALUES (
-  $1, 'running', $2
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 )
-RETURNING id, workflow_id, status, finished_at, created_at
+RETURNING id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
 `
 
 type CreateWorkflowRunParams struct {
-	WorkflowID int32 `json:"workflow_id"`
-	CreatedAt  int64 `json:"created_at"`
+	WorkflowID        int32      `json:"workflow_id"`
+	Status            string     `json:"status"`
+	CreatedAt         int64      `json:"created_at"`
+	DryRun            bool       `json:"dry_run"`
+	ParentRunID       null.Int32 `json:"parent_run_id"`
+	ParentNodeRunID   null.Int32 `json:"parent_node_run_id"`
+	Depth             int32      `json:"depth"`
+	Inputs            []byte     `json:"inputs"`
+	TriggerSource     string     `json:"trigger_source"`
+	TriggerPayload    []byte     `json:"trigger_payload"`
+	WorkflowVersionID int32      `json:"workflow_version_id"`
 }
 
 // CreateWorkflowRun
 //
 //	INSERT INTO workflow_run (
-//	  workflow_id, status, created_at
+//	  workflow_id,
+//	  status,
+//	  created_at,
+//	  dry_run,
+//	  parent_run_id,
+//	  parent_node_run_id,
+//	  depth,
+//	  inputs,
+//	  trigger_source,
+//	  trigger_payload,
+//	  workflow_version_id
 //	) VALUES (
-//	  $1, 'running', $2
+//	  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 //	)
-//	RETURNING id, workflow_id, status, finished_at, created_at
+//	RETURNING id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
 func (q *Queries) CreateWorkflowRun(ctx context.Context, arg *CreateWorkflowRunParams) (*WorkflowRun, error) {
-	row := q.db.QueryRow(ctx, createWorkflowRun, arg.WorkflowID, arg.CreatedAt)
+	row := q.db.QueryRow(ctx, createWorkflowRun,
+		arg.WorkflowID,
+		arg.Status,
+		arg.CreatedAt,
+		arg.DryRun,
+		arg.ParentRunID,
+		arg.ParentNodeRunID,
+		arg.Depth,
+		arg.Inputs,
+		arg.TriggerSource,
+		arg.TriggerPayload,
+		arg.WorkflowVersionID,
+	)
 	var i WorkflowRun
 	err := row.Scan(
 		&i.ID,
@@ -66,6 +228,17 @@ func (q *Queries) CreateWorkflowRun(ctx context.Context, arg *CreateWorkflowRunP
 		&i.Status,
 		&i.FinishedAt,
 		&i.CreatedAt,
+		&i.DryRun,
+		&i.ParentRunID,
+		&i.ParentNodeRunID,
+		&i.Depth,
+		&i.Inputs,
+		&i.SkipReason,
+		&i.DeadlineAt,
+		&i.ErrorMessage,
+		&i.TriggerSource,
+		&i.TriggerPayload,
+		&i.WorkflowVersionID,
 	)
 	return &i, err
 }
@@ -77,14 +250,22 @@ SELECT
   wr.id as workflow_run_id,
   wr.status as workflow_run_status,
   wr.created_at as workflow_run_created_at,
-  wr.finished_at as workflow_run_finished_at
+  wr.finished_at as workflow_run_finished_at,
+  wr.dry_run as workflow_run_dry_run,
+  wr.inputs as workflow_run_inputs
 FROM workflow_run wr
 INNER JOIN workflow w ON wr.workflow_id = w.id
 WHERE w.user_id = $1
+  AND wr.dry_run = $2
 ORDER BY wr.created_at DESC
 LIMIT 25
 `
 
+type GetUserWorkflowRunsParams struct {
+	UserID string `json:"user_id"`
+	DryRun bool   `json:"dry_run"`
+}
+
 type GetUserWorkflowRunsRow struct {
 	WorkflowID            int32    `json:"workflow_id"`
 	WorkflowName          string   `json:"workflow_name"`
@@ -92,6 +273,8 @@ type GetUserWorkflowRunsRow struct {
 	WorkflowRunStatus     string   `json:"workflow_run_status"`
 	WorkflowRunCreatedAt  int64    `json:"workflow_run_created_at"`
 	WorkflowRunFinishedAt null.Int `json:"workflow_run_finished_at"`
+	WorkflowRunDryRun     bool     `json:"workflow_run_dry_run"`
+	WorkflowRunInputs     []byte   `json:"workflow_run_inputs"`
 }
 
 // GetUserWorkflowRuns
@@ -102,14 +285,17 @@ type GetUserWorkflowRunsRow struct {
 //	  wr.id as workflow_run_id,
 //	  wr.status as workflow_run_status,
 //	  wr.created_at as workflow_run_created_at,
-//	  wr.finished_at as workflow_run_finished_at
+//	  wr.finished_at as workflow_run_finished_at,
+//	  wr.dry_run as workflow_run_dry_run,
+//	  wr.inputs as workflow_run_inputs
 //	FROM workflow_run wr
 //	INNER JOIN workflow w ON wr.workflow_id = w.id
 //	WHERE w.user_id = $1
+//	  AND wr.dry_run = $2
 //	ORDER BY wr.created_at DESC
 //	LIMIT 25
-func (q *Queries) GetUserWorkflowRuns(ctx context.Context, userID string) ([]*GetUserWorkflowRunsRow, error) {
-	rows, err := q.db.Query(ctx, getUserWorkflowRuns, userID)
+func (q *Queries) GetUserWorkflowRuns(ctx context.Context, arg *GetUserWorkflowRunsParams) ([]*GetUserWorkflowRunsRow, error) {
+	rows, err := q.db.Query(ctx, getUserWorkflowRuns, arg.UserID, arg.DryRun)
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +310,8 @@ func (q *Queries) GetUserWorkflowRuns(ctx context.Context, userID string) ([]*Ge
 			&i.WorkflowRunStatus,
 			&i.WorkflowRunCreatedAt,
 			&i.WorkflowRunFinishedAt,
+			&i.WorkflowRunDryRun,
+			&i.WorkflowRunInputs,
 		); err != nil {
 			return nil, err
 		}
@@ -135,6 +323,41 @@ func (q *Queries) GetUserWorkflowRuns(ctx context.Context, userID string) ([]*Ge
 	return items, nil
 }
 
+const getWorkflowRunByID = `-- name: GetWorkflowRunByID :one
+SELECT id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
+FROM workflow_run
+WHERE id = $1
+`
+
+// GetWorkflowRunByID
+//
+//	SELECT id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
+//	FROM workflow_run
+//	WHERE id = $1
+func (q *Queries) GetWorkflowRunByID(ctx context.Context, id int32) (*WorkflowRun, error) {
+	row := q.db.QueryRow(ctx, getWorkflowRunByID, id)
+	var i WorkflowRun
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.Status,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.DryRun,
+		&i.ParentRunID,
+		&i.ParentNodeRunID,
+		&i.Depth,
+		&i.Inputs,
+		&i.SkipReason,
+		&i.DeadlineAt,
+		&i.ErrorMessage,
+		&i.TriggerSource,
+		&i.TriggerPayload,
+		&i.WorkflowVersionID,
+	)
+	return &i, err
+}
+
 const getWorkflowRunWithNodeRuns = `-- name: GetWorkflowRunWithNodeRuns :many
 SELECT
   wr.id AS workflow_run_id,
@@ -142,31 +365,51 @@ SELECT
   wr.status AS workflow_run_status,
   wr.finished_at AS workflow_run_finished_at,
   wr.created_at AS workflow_run_created_at,
+  wr.dry_run AS workflow_run_dry_run,
+  wr.parent_run_id AS workflow_run_parent_run_id,
+  wr.depth AS workflow_run_depth,
+  wr.inputs AS workflow_run_inputs,
+  wr.deadline_at AS workflow_run_deadline_at,
+  wr.error_message AS workflow_run_error_message,
+  wr.trigger_source AS workflow_run_trigger_source,
+  wr.trigger_payload AS workflow_run_trigger_payload,
+  wr.workflow_version_id AS workflow_run_workflow_version_id,
   wnr.id AS node_run_id,
   wnr.workflow_node_id,
   wnr.status AS node_run_status,
   wnr.started_at AS node_run_started_at,
   wnr.finished_at AS node_run_finished_at,
   wnr.metadata,
-  wnr.error_message
+  wnr.error_message,
+  wnr.iteration_index
 FROM workflow_run wr
 INNER JOIN workflow_node_run wnr ON wr.id = wnr.workflow_run_id
 WHERE wr.id = $1
 `
 
 type GetWorkflowRunWithNodeRunsRow struct {
-	WorkflowRunID         int32       `json:"workflow_run_id"`
-	WorkflowID            int32       `json:"workflow_id"`
-	WorkflowRunStatus     string      `json:"workflow_run_status"`
-	WorkflowRunFinishedAt null.Int    `json:"workflow_run_finished_at"`
-	WorkflowRunCreatedAt  int64       `json:"workflow_run_created_at"`
-	NodeRunID             int32       `json:"node_run_id"`
-	WorkflowNodeID        int32       `json:"workflow_node_id"`
-	NodeRunStatus         string      `json:"node_run_status"`
-	NodeRunStartedAt      null.Int    `json:"node_run_started_at"`
-	NodeRunFinishedAt     null.Int    `json:"node_run_finished_at"`
-	Metadata              []byte      `json:"metadata"`
-	ErrorMessage          null.String `json:"error_message"`
+	WorkflowRunID                int32       `json:"workflow_run_id"`
+	WorkflowID                   int32       `json:"workflow_id"`
+	WorkflowRunStatus            string      `json:"workflow_run_status"`
+	WorkflowRunFinishedAt        null.Int    `json:"workflow_run_finished_at"`
+	WorkflowRunCreatedAt         int64       `json:"workflow_run_created_at"`
+	WorkflowRunDryRun            bool        `json:"workflow_run_dry_run"`
+	WorkflowRunParentRunID       null.Int32  `json:"workflow_run_parent_run_id"`
+	WorkflowRunDepth             int32       `json:"workflow_run_depth"`
+	WorkflowRunInputs            []byte      `json:"workflow_run_inputs"`
+	WorkflowRunDeadlineAt        null.Int    `json:"workflow_run_deadline_at"`
+	WorkflowRunErrorMessage      null.String `json:"workflow_run_error_message"`
+	WorkflowRunTriggerSource     string      `json:"workflow_run_trigger_source"`
+	WorkflowRunTriggerPayload    []byte      `json:"workflow_run_trigger_payload"`
+	WorkflowRunWorkflowVersionID int32       `json:"workflow_run_workflow_version_id"`
+	NodeRunID                    int32       `json:"node_run_id"`
+	WorkflowNodeID               int32       `json:"workflow_node_id"`
+	NodeRunStatus                string      `json:"node_run_status"`
+	NodeRunStartedAt             null.Int    `json:"node_run_started_at"`
+	NodeRunFinishedAt            null.Int    `json:"node_run_finished_at"`
+	Metadata                     []byte      `json:"metadata"`
+	ErrorMessage                 null.String `json:"error_message"`
+	IterationIndex               int32       `json:"iteration_index"`
 }
 
 // GetWorkflowRunWithNodeRuns
@@ -177,13 +420,23 @@ type GetWorkflowRunWithNodeRunsRow struct {
 //	  wr.status AS workflow_run_status,
 //	  wr.finished_at AS workflow_run_finished_at,
 //	  wr.created_at AS workflow_run_created_at,
+//	  wr.dry_run AS workflow_run_dry_run,
+//	  wr.parent_run_id AS workflow_run_parent_run_id,
+//	  wr.depth AS workflow_run_depth,
+//	  wr.inputs AS workflow_run_inputs,
+//	  wr.deadline_at AS workflow_run_deadline_at,
+//	  wr.error_message AS workflow_run_error_message,
+//	  wr.trigger_source AS workflow_run_trigger_source,
+//	  wr.trigger_payload AS workflow_run_trigger_payload,
+//	  wr.workflow_version_id AS workflow_run_workflow_version_id,
 //	  wnr.id AS node_run_id,
 //	  wnr.workflow_node_id,
 //	  wnr.status AS node_run_status,
 //	  wnr.started_at AS node_run_started_at,
 //	  wnr.finished_at AS node_run_finished_at,
 //	  wnr.metadata,
-//	  wnr.error_message
+//	  wnr.error_message,
+//	  wnr.iteration_index
 //	FROM workflow_run wr
 //	INNER JOIN workflow_node_run wnr ON wr.id = wnr.workflow_run_id
 //	WHERE wr.id = $1
@@ -202,6 +455,15 @@ func (q *Queries) GetWorkflowRunWithNodeRuns(ctx context.Context, id int32) ([]*
 			&i.WorkflowRunStatus,
 			&i.WorkflowRunFinishedAt,
 			&i.WorkflowRunCreatedAt,
+			&i.WorkflowRunDryRun,
+			&i.WorkflowRunParentRunID,
+			&i.WorkflowRunDepth,
+			&i.WorkflowRunInputs,
+			&i.WorkflowRunDeadlineAt,
+			&i.WorkflowRunErrorMessage,
+			&i.WorkflowRunTriggerSource,
+			&i.WorkflowRunTriggerPayload,
+			&i.WorkflowRunWorkflowVersionID,
 			&i.NodeRunID,
 			&i.WorkflowNodeID,
 			&i.NodeRunStatus,
@@ -209,6 +471,150 @@ func (q *Queries) GetWorkflowRunWithNodeRuns(ctx context.Context, id int32) ([]*
 			&i.NodeRunFinishedAt,
 			&i.Metadata,
 			&i.ErrorMessage,
+			&i.IterationIndex,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActiveWorkflowRuns = `-- name: ListActiveWorkflowRuns :many
+SELECT id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
+FROM workflow_run
+WHERE workflow_id = $1
+  AND status IN ('running', 'queued')
+  AND dry_run = FALSE
+ORDER BY created_at ASC, id ASC
+`
+
+// ListActiveWorkflowRuns
+//
+//	SELECT id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
+//	FROM workflow_run
+//	WHERE workflow_id = $1
+//	  AND status IN ('running', 'queued')
+//	  AND dry_run = FALSE
+//	ORDER BY created_at ASC, id ASC
+func (q *Queries) ListActiveWorkflowRuns(ctx context.Context, workflowID int32) ([]*WorkflowRun, error) {
+	rows, err := q.db.Query(ctx, listActiveWorkflowRuns, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*WorkflowRun
+	for rows.Next() {
+		var i WorkflowRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowID,
+			&i.Status,
+			&i.FinishedAt,
+			&i.CreatedAt,
+			&i.DryRun,
+			&i.ParentRunID,
+			&i.ParentNodeRunID,
+			&i.Depth,
+			&i.Inputs,
+			&i.SkipReason,
+			&i.DeadlineAt,
+			&i.ErrorMessage,
+			&i.TriggerSource,
+			&i.TriggerPayload,
+			&i.WorkflowVersionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStuckWorkflowRuns = `-- name: ListStuckWorkflowRuns :many
+SELECT
+  wr.id,
+  wr.workflow_id,
+  w.user_id,
+  wr.deadline_at,
+  progress.last_progress_at::BIGINT AS last_progress_at
+FROM workflow_run wr
+INNER JOIN workflow w ON wr.workflow_id = w.id
+CROSS JOIN LATERAL (
+  SELECT
+    GREATEST(wr.created_at, MAX(wnr.started_at), MAX(wnr.finished_at)) AS last_progress_at,
+    COALESCE(BOOL_OR(wnr.status = 'waiting'), FALSE) AS waiting
+  FROM workflow_node_run wnr
+  WHERE wnr.workflow_run_id = wr.id
+) progress
+WHERE wr.status = 'running'
+  AND (
+    wr.deadline_at <= $1
+    OR (NOT progress.waiting AND progress.last_progress_at <= $2)
+  )
+ORDER BY wr.id ASC
+LIMIT $3
+`
+
+type ListStuckWorkflowRunsParams struct {
+	Now           null.Int `json:"now"`
+	StalledBefore int64    `json:"stalled_before"`
+	MaxRuns       int32    `json:"max_runs"`
+}
+
+type ListStuckWorkflowRunsRow struct {
+	ID             int32    `json:"id"`
+	WorkflowID     int32    `json:"workflow_id"`
+	UserID         string   `json:"user_id"`
+	DeadlineAt     null.Int `json:"deadline_at"`
+	LastProgressAt int64    `json:"last_progress_at"`
+}
+
+// ListStuckWorkflowRuns
+//
+//	SELECT
+//	  wr.id,
+//	  wr.workflow_id,
+//	  w.user_id,
+//	  wr.deadline_at,
+//	  progress.last_progress_at::BIGINT AS last_progress_at
+//	FROM workflow_run wr
+//	INNER JOIN workflow w ON wr.workflow_id = w.id
+//	CROSS JOIN LATERAL (
+//	  SELECT
+//	    GREATEST(wr.created_at, MAX(wnr.started_at), MAX(wnr.finished_at)) AS last_progress_at,
+//	    COALESCE(BOOL_OR(wnr.status = 'waiting'), FALSE) AS waiting
+//	  FROM workflow_node_run wnr
+//	  WHERE wnr.workflow_run_id = wr.id
+//	) progress
+//	WHERE wr.status = 'running'
+//	  AND (
+//	    wr.deadline_at <= $1
+//	    OR (NOT progress.waiting AND progress.last_progress_at <= $2)
+//	  )
+//	ORDER BY wr.id ASC
+//	LIMIT $3
+func (q *Queries) ListStuckWorkflowRuns(ctx context.Context, arg *ListStuckWorkflowRunsParams) ([]*ListStuckWorkflowRunsRow, error) {
+	rows, err := q.db.Query(ctx, listStuckWorkflowRuns, arg.Now, arg.StalledBefore, arg.MaxRuns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListStuckWorkflowRunsRow
+	for rows.Next() {
+		var i ListStuckWorkflowRunsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowID,
+			&i.UserID,
+			&i.DeadlineAt,
+			&i.LastProgressAt,
 		); err != nil {
 			return nil, err
 		}
@@ -221,22 +627,29 @@ func (q *Queries) GetWorkflowRunWithNodeRuns(ctx context.Context, id int32) ([]*
 }
 
 const listWorkflowRuns = `-- name: ListWorkflowRuns :many
-SELECT id, workflow_id, status, finished_at, created_at
+SELECT id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
 FROM workflow_run
 WHERE workflow_id = $1
+  AND dry_run = $2
 ORDER BY created_at DESC
 LIMIT 25
 `
 
+type ListWorkflowRunsParams struct {
+	WorkflowID int32 `json:"workflow_id"`
+	DryRun     bool  `json:"dry_run"`
+}
+
 // ListWorkflowRuns
 //
-//	SELECT id, workflow_id, status, finished_at, created_at
+//	SELECT id, workflow_id, status, finished_at, created_at, dry_run, parent_run_id, parent_node_run_id, depth, inputs, skip_reason, deadline_at, error_message, trigger_source, trigger_payload, workflow_version_id
 //	FROM workflow_run
 //	WHERE workflow_id = $1
+//	  AND dry_run = $2
 //	ORDER BY created_at DESC
 //	LIMIT 25
-func (q *Queries) ListWorkflowRuns(ctx context.Context, workflowID int32) ([]*WorkflowRun, error) {
-	rows, err := q.db.Query(ctx, listWorkflowRuns, workflowID)
+func (q *Queries) ListWorkflowRuns(ctx context.Context, arg *ListWorkflowRunsParams) ([]*WorkflowRun, error) {
+	rows, err := q.db.Query(ctx, listWorkflowRuns, arg.WorkflowID, arg.DryRun)
 	if err != nil {
 		return nil, err
 	}
@@ -250,6 +663,17 @@ func (q *Queries) ListWorkflowRuns(ctx context.Context, workflowID int32) ([]*Wo
 			&i.Status,
 			&i.FinishedAt,
 			&i.CreatedAt,
+			&i.DryRun,
+			&i.ParentRunID,
+			&i.ParentNodeRunID,
+			&i.Depth,
+			&i.Inputs,
+			&i.SkipReason,
+			&i.DeadlineAt,
+			&i.ErrorMessage,
+			&i.TriggerSource,
+			&i.TriggerPayload,
+			&i.WorkflowVersionID,
 		); err != nil {
 			return nil, err
 		}
@@ -260,3 +684,117 @@ func (q *Queries) ListWorkflowRuns(ctx context.Context, workflowID int32) ([]*Wo
 	}
 	return items, nil
 }
+
+const lockWorkflowRuns = `-- name: LockWorkflowRuns :exec
+SELECT id
+FROM workflow
+WHERE id = $1
+FOR UPDATE
+`
+
+// LockWorkflowRuns
+//
+//	SELECT id
+//	FROM workflow
+//	WHERE id = $1
+//	FOR UPDATE
+func (q *Queries) LockWorkflowRuns(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, lockWorkflowRuns, id)
+	return err
+}
+
+const resumeWorkflowRun = `-- name: ResumeWorkflowRun :execrows
+UPDATE workflow_run
+SET status = 'running',
+    finished_at = NULL
+WHERE id = $1
+  AND status = 'failed'
+`
+
+// ResumeWorkflowRun
+//
+//	UPDATE workflow_run
+//	SET status = 'running',
+//	    finished_at = NULL
+//	WHERE id = $1
+//	  AND status = 'failed'
+func (q *Queries) ResumeWorkflowRun(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, resumeWorkflowRun, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setWorkflowRunDeadline = `-- name: SetWorkflowRunDeadline :exec
+UPDATE workflow_run
+SET deadline_at = $2
+WHERE id = $1
+`
+
+type SetWorkflowRunDeadlineParams struct {
+	ID         int32    `json:"id"`
+	DeadlineAt null.Int `json:"deadline_at"`
+}
+
+// SetWorkflowRunDeadline
+//
+//	UPDATE workflow_run
+//	SET deadline_at = $2
+//	WHERE id = $1
+func (q *Queries) SetWorkflowRunDeadline(ctx context.Context, arg *SetWorkflowRunDeadlineParams) error {
+	_, err := q.db.Exec(ctx, setWorkflowRunDeadline, arg.ID, arg.DeadlineAt)
+	return err
+}
+
+const startQueuedWorkflowRun = `-- name: StartQueuedWorkflowRun :execrows
+UPDATE workflow_run
+SET status = 'running'
+WHERE id = $1
+  AND status = 'queued'
+`
+
+// StartQueuedWorkflowRun
+//
+//	UPDATE workflow_run
+//	SET status = 'running'
+//	WHERE id = $1
+//	  AND status = 'queued'
+func (q *Queries) StartQueuedWorkflowRun(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, startQueuedWorkflowRun, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const timeOutWorkflowRun = `-- name: TimeOutWorkflowRun :execrows
+UPDATE workflow_run
+SET status = 'timed_out',
+    finished_at = $2,
+    error_message = $3
+WHERE id = $1
+  AND status = 'running'
+`
+
+type TimeOutWorkflowRunParams struct {
+	ID           int32       `json:"id"`
+	FinishedAt   null.Int    `json:"finished_at"`
+	ErrorMessage null.String `json:"error_message"`
+}
+
+// TimeOutWorkflowRun
+//
+//	UPDATE workflow_run
+//	SET status = 'timed_out',
+//	    finished_at = $2,
+//	    error_message = $3
+//	WHERE id = $1
+//	  AND status = 'running'
+func (q *Queries) TimeOutWorkflowRun(ctx context.Context, arg *TimeOutWorkflowRunParams) (int64, error) {
+	result, err := q.db.Exec(ctx, timeOutWorkflowRun, arg.ID, arg.FinishedAt, arg.ErrorMessage)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -54,10 +54,11 @@ RETURNING *;
 INSERT INTO workflow_edge (
   workflow_id,
   source_node_id,
-  target_node_id
+  target_node_id,
+  label
 )
 VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4
 )
 RETURNING *;
 
@@ -73,7 +74,8 @@ SELECT
   node_type,
   config,
   source_node_id,
-  target_node_id
+  target_node_id,
+  label
 FROM workflow w
 INNER JOIN workflow_node wn ON w.id = wn.workflow_id
 LEFT JOIN workflow_edge we ON w.id = we.workflow_id
@@ -94,7 +96,8 @@ SELECT
   node_type,
   config,
   source_node_id,
-  target_node_id
+  target_node_id,
+  label
 FROM workflow w
 INNER JOIN workflow_node wn ON w.id = wn.workflow_id
 INNER JOIN workflow_node_ui wnu ON wn.id = wnu.id
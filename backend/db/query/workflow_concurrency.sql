@@ -0,0 +1,18 @@
+-- name: GetWorkflowConcurrency :one
+SELECT *
+FROM workflow_concurrency
+WHERE workflow_id = $1;
+
+-- name: UpsertWorkflowConcurrency :one
+INSERT INTO workflow_concurrency (
+  workflow_id,
+  max_concurrent_runs,
+  overlap_policy,
+  updated_at
+)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (workflow_id) DO UPDATE
+SET max_concurrent_runs = EXCLUDED.max_concurrent_runs,
+    overlap_policy = EXCLUDED.overlap_policy,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
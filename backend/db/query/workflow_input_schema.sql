@@ -0,0 +1,16 @@
+-- name: GetWorkflowInputSchema :one
+SELECT *
+FROM workflow_input_schema
+WHERE workflow_id = $1;
+
+-- name: UpsertWorkflowInputSchema :one
+INSERT INTO workflow_input_schema (
+  workflow_id,
+  inputs,
+  updated_at
+)
+VALUES ($1, $2, $3)
+ON CONFLICT (workflow_id) DO UPDATE
+SET inputs = EXCLUDED.inputs,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
@@ -3,31 +3,48 @@ INSERT INTO workflow_node_run (
   workflow_run_id,
   workflow_node_id,
   status,
-  metadata
+  metadata,
+  iteration_index
 )
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (workflow_run_id, workflow_node_id) DO NOTHING
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (workflow_run_id, workflow_node_id, iteration_index) DO NOTHING
 RETURNING *;
 
 -- name: GetWorkflowNodeRunByWorkflowRunIDAndNodeID :one
 SELECT *
 FROM workflow_node_run
 WHERE workflow_run_id = $1
-  AND workflow_node_id = $2;
+  AND workflow_node_id = $2
+  AND iteration_index = $3;
+
+-- name: GetWorkflowNodeRunByID :one
+SELECT *
+FROM workflow_node_run
+WHERE id = $1;
 
 -- name: GetParentWorkflowNodeRuns :many
-SELECT wnr.*
+SELECT wnr.*, we.label
 FROM workflow_node_run wnr
-INNER JOIN workflow_edge we ON wnr.workflow_node_id = we.source_node_id
-WHERE workflow_run_id = $1
-AND target_node_id = $2;
+INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+CROSS JOIN LATERAL jsonb_to_recordset(wv.graph -> 'edges')
+  AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+WHERE wnr.workflow_run_id = $1
+AND wnr.workflow_node_id = we.source_node_id
+AND we.target_node_id = $2
+AND (wnr.iteration_index = $3 OR wnr.iteration_index = -1);
 
 -- name: GetChildWorkflowNodeRuns :many
-SELECT wnr.*
+SELECT wnr.*, we.label
 FROM workflow_node_run wnr
-INNER JOIN workflow_edge we ON wnr.workflow_node_id = we.target_node_id
-WHERE workflow_run_id = $1
-AND source_node_id = $2;
+INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+CROSS JOIN LATERAL jsonb_to_recordset(wv.graph -> 'edges')
+  AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+WHERE wnr.workflow_run_id = $1
+AND wnr.workflow_node_id = we.target_node_id
+AND we.source_node_id = $2
+AND wnr.iteration_index = $3;
 
 -- name: GetWorkflowNodeRunsByRunID :many
 SELECT *
@@ -35,6 +52,81 @@ FROM workflow_node_run
 WHERE workflow_run_id = $1
 ORDER BY started_at ASC;
 
+-- name: GetLoopIterationNodeRuns :many
+SELECT *
+FROM workflow_node_run
+WHERE workflow_run_id = $1
+  AND workflow_node_id = ANY(sqlc.arg(node_ids)::int[])
+  AND iteration_index >= 0
+ORDER BY iteration_index ASC;
+
+-- name: CancelPendingWorkflowNodeRuns :exec
+UPDATE workflow_node_run
+SET status = 'cancelled',
+    finished_at = $2
+WHERE workflow_run_id = $1
+  AND status IN ('pending', 'waiting');
+
+-- name: FailRunningWorkflowNodeRuns :exec
+UPDATE workflow_node_run
+SET status = 'failed',
+    finished_at = $2,
+    error_message = $3
+WHERE workflow_run_id = $1
+  AND status = 'running';
+
+-- name: ListIdlePendingWorkflowNodeRuns :many
+SELECT
+  wnr.id,
+  wnr.workflow_run_id,
+  wnr.workflow_node_id,
+  wnr.iteration_index,
+  wr.workflow_id,
+  w.user_id
+FROM workflow_node_run wnr
+INNER JOIN workflow_run wr ON wnr.workflow_run_id = wr.id
+INNER JOIN workflow w ON wr.workflow_id = w.id
+INNER JOIN workflow_version wv ON wr.workflow_version_id = wv.id
+CROSS JOIN LATERAL (
+  SELECT
+    COALESCE(BOOL_AND(
+      p.status IN ('success', 'failed', 'skipped', 'cancelled')
+      OR (p.status = 'running' AND we.label = 'each')
+    ), TRUE) AS settled,
+    GREATEST(wr.created_at, MAX(p.started_at), MAX(p.finished_at)) AS ready_at
+  FROM jsonb_to_recordset(wv.graph -> 'edges')
+    AS we(source_node_id INTEGER, target_node_id INTEGER, label TEXT)
+  INNER JOIN workflow_node_run p
+    ON p.workflow_run_id = wnr.workflow_run_id
+    AND p.workflow_node_id = we.source_node_id
+    AND p.iteration_index IN (wnr.iteration_index, -1)
+  WHERE we.target_node_id = wnr.workflow_node_id
+) parents
+WHERE wnr.status = 'pending'
+  AND wr.status = 'running'
+  AND parents.settled
+  AND parents.ready_at <= sqlc.arg(idle_before)
+  AND NOT EXISTS (
+    SELECT 1
+    FROM workflow_task_outbox o
+    WHERE o.sent_at IS NULL
+      AND (o.payload ->> 'node_run_id')::INTEGER = wnr.id
+  )
+ORDER BY wnr.id ASC
+LIMIT sqlc.arg(max_node_runs);
+
+-- name: ResetWorkflowNodeRuns :exec
+UPDATE workflow_node_run
+SET status = 'pending',
+    retry_count = 0,
+    started_at = NULL,
+    finished_at = NULL,
+    metadata = NULL,
+    error_message = NULL
+WHERE workflow_run_id = $1
+  AND id = ANY(sqlc.arg(ids)::int[])
+  AND status IN ('failed', 'skipped');
+
 -- name: MarkWorkflowNodeAsRunning :exec
 UPDATE workflow_node_run
 SET status = 'running',
@@ -42,6 +134,35 @@ SET status = 'running',
     retry_count = $3
 WHERE id = $1;
 
+-- name: MarkWorkflowNodeAsWaiting :exec
+UPDATE workflow_node_run
+SET status = 'waiting',
+    metadata = $2
+WHERE id = $1;
+
+-- name: SetWorkflowNodeRunMetadata :exec
+UPDATE workflow_node_run
+SET metadata = $2
+WHERE id = $1;
+
+-- name: SetWorkflowNodeRunChildRun :exec
+UPDATE workflow_node_run
+SET metadata = COALESCE(metadata, '{}'::JSONB) || jsonb_build_object('child_run_id', $2::INTEGER)
+WHERE id = $1;
+
+-- name: SetWorkflowNodeRunRetryCount :exec
+UPDATE workflow_node_run
+SET retry_count = $2
+WHERE id = $1;
+
+-- name: CompleteLoopWorkflowNodeRun :execrows
+UPDATE workflow_node_run
+SET status = $2,
+    finished_at = $3,
+    metadata = $4
+WHERE id = $1
+  AND status = 'running';
+
 -- name: UpdateWorkflowNodeRun :exec
 UPDATE workflow_node_run
 SET status = $2,
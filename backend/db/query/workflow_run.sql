@@ -1,15 +1,122 @@
 -- name: CreateWorkflowRun :one
 INSERT INTO workflow_run (
-  workflow_id, status, created_at
+  workflow_id,
+  status,
+  created_at,
+  dry_run,
+  parent_run_id,
+  parent_node_run_id,
+  depth,
+  inputs,
+  trigger_source,
+  trigger_payload,
+  workflow_version_id
 ) VALUES (
-  $1, 'running', $2
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 )
 RETURNING *;
 
+-- name: CreateSkippedWorkflowRun :one
+INSERT INTO workflow_run (
+  workflow_id,
+  status,
+  created_at,
+  finished_at,
+  dry_run,
+  parent_run_id,
+  parent_node_run_id,
+  depth,
+  inputs,
+  skip_reason,
+  trigger_source,
+  trigger_payload,
+  workflow_version_id
+) VALUES (
+  $1, 'skipped', $2, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+RETURNING *;
+
+-- name: LockWorkflowRuns :exec
+SELECT id
+FROM workflow
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListActiveWorkflowRuns :many
+SELECT *
+FROM workflow_run
+WHERE workflow_id = $1
+  AND status IN ('running', 'queued')
+  AND dry_run = FALSE
+ORDER BY created_at ASC, id ASC;
+
+-- name: StartQueuedWorkflowRun :execrows
+UPDATE workflow_run
+SET status = 'running'
+WHERE id = $1
+  AND status = 'queued';
+
+-- name: SetWorkflowRunDeadline :exec
+UPDATE workflow_run
+SET deadline_at = $2
+WHERE id = $1;
+
+-- name: ListStuckWorkflowRuns :many
+SELECT
+  wr.id,
+  wr.workflow_id,
+  w.user_id,
+  wr.deadline_at,
+  progress.last_progress_at::BIGINT AS last_progress_at
+FROM workflow_run wr
+INNER JOIN workflow w ON wr.workflow_id = w.id
+CROSS JOIN LATERAL (
+  SELECT
+    GREATEST(wr.created_at, MAX(wnr.started_at), MAX(wnr.finished_at)) AS last_progress_at,
+    COALESCE(BOOL_OR(wnr.status = 'waiting'), FALSE) AS waiting
+  FROM workflow_node_run wnr
+  WHERE wnr.workflow_run_id = wr.id
+) progress
+WHERE wr.status = 'running'
+  AND (
+    wr.deadline_at <= sqlc.arg(now)
+    OR (NOT progress.waiting AND progress.last_progress_at <= sqlc.arg(stalled_before))
+  )
+ORDER BY wr.id ASC
+LIMIT sqlc.arg(max_runs);
+
+-- name: TimeOutWorkflowRun :execrows
+UPDATE workflow_run
+SET status = 'timed_out',
+    finished_at = $2,
+    error_message = $3
+WHERE id = $1
+  AND status = 'running';
+
 -- name: CompleteWorkflowRun :exec
 UPDATE workflow_run
 SET status = $2,
     finished_at = $3
+WHERE id = $1
+  AND status = 'running';
+
+-- name: CancelWorkflowRun :execrows
+UPDATE workflow_run
+SET status = 'cancelled',
+    finished_at = $2
+WHERE id = $1
+  AND status IN ('running', 'queued');
+
+-- name: ResumeWorkflowRun :execrows
+UPDATE workflow_run
+SET status = 'running',
+    finished_at = NULL
+WHERE id = $1
+  AND status = 'failed';
+
+-- name: GetWorkflowRunByID :one
+SELECT *
+FROM workflow_run
 WHERE id = $1;
 
 -- name: GetUserWorkflowRuns :many
@@ -19,10 +126,13 @@ SELECT
   wr.id as workflow_run_id,
   wr.status as workflow_run_status,
   wr.created_at as workflow_run_created_at,
-  wr.finished_at as workflow_run_finished_at
+  wr.finished_at as workflow_run_finished_at,
+  wr.dry_run as workflow_run_dry_run,
+  wr.inputs as workflow_run_inputs
 FROM workflow_run wr
 INNER JOIN workflow w ON wr.workflow_id = w.id
 WHERE w.user_id = $1
+  AND wr.dry_run = $2
 ORDER BY wr.created_at DESC
 LIMIT 25;
 
@@ -30,6 +140,7 @@ LIMIT 25;
 SELECT *
 FROM workflow_run
 WHERE workflow_id = $1
+  AND dry_run = $2
 ORDER BY created_at DESC
 LIMIT 25;
 
@@ -40,13 +151,23 @@ SELECT
   wr.status AS workflow_run_status,
   wr.finished_at AS workflow_run_finished_at,
   wr.created_at AS workflow_run_created_at,
+  wr.dry_run AS workflow_run_dry_run,
+  wr.parent_run_id AS workflow_run_parent_run_id,
+  wr.depth AS workflow_run_depth,
+  wr.inputs AS workflow_run_inputs,
+  wr.deadline_at AS workflow_run_deadline_at,
+  wr.error_message AS workflow_run_error_message,
+  wr.trigger_source AS workflow_run_trigger_source,
+  wr.trigger_payload AS workflow_run_trigger_payload,
+  wr.workflow_version_id AS workflow_run_workflow_version_id,
   wnr.id AS node_run_id,
   wnr.workflow_node_id,
   wnr.status AS node_run_status,
   wnr.started_at AS node_run_started_at,
   wnr.finished_at AS node_run_finished_at,
   wnr.metadata,
-  wnr.error_message
+  wnr.error_message,
+  wnr.iteration_index
 FROM workflow_run wr
 INNER JOIN workflow_node_run wnr ON wr.id = wnr.workflow_run_id
 WHERE wr.id = $1;
@@ -0,0 +1,14 @@
+-- name: GetWorkflowSideEffect :one
+SELECT *
+FROM workflow_side_effect
+WHERE idempotency_key = $1;
+
+-- name: InsertWorkflowSideEffect :exec
+INSERT INTO workflow_side_effect (
+  idempotency_key,
+  workflow_run_id,
+  output,
+  created_at
+)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (idempotency_key) DO NOTHING;
@@ -0,0 +1,37 @@
+-- name: InsertOutboxTask :exec
+INSERT INTO workflow_task_outbox (payload, delay_ms, created_at)
+VALUES ($1, $2, $3);
+
+-- name: ClaimOutboxTasks :many
+SELECT *
+FROM workflow_task_outbox
+WHERE sent_at IS NULL
+  AND dead_at IS NULL
+  AND (retry_at IS NULL OR retry_at <= $2)
+ORDER BY id ASC
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxTaskSent :exec
+UPDATE workflow_task_outbox
+SET sent_at = $2,
+    attempts = attempts + 1
+WHERE id = $1;
+
+-- name: MarkOutboxTaskFailed :exec
+UPDATE workflow_task_outbox
+SET attempts = attempts + 1,
+    last_error = $2,
+    retry_at = $3
+WHERE id = $1;
+
+-- name: MarkOutboxTaskDead :exec
+UPDATE workflow_task_outbox
+SET attempts = attempts + 1,
+    last_error = $2,
+    dead_at = $3
+WHERE id = $1;
+
+-- name: DeleteSentOutboxTasks :execrows
+DELETE FROM workflow_task_outbox
+WHERE sent_at < $1;
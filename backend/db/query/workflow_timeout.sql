@@ -0,0 +1,16 @@
+-- name: GetWorkflowTimeout :one
+SELECT *
+FROM workflow_timeout
+WHERE workflow_id = $1;
+
+-- name: UpsertWorkflowTimeout :one
+INSERT INTO workflow_timeout (
+  workflow_id,
+  run_timeout_seconds,
+  updated_at
+)
+VALUES ($1, $2, $3)
+ON CONFLICT (workflow_id) DO UPDATE
+SET run_timeout_seconds = EXCLUDED.run_timeout_seconds,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
@@ -0,0 +1,35 @@
+-- name: CreateWorkflowVersion :one
+INSERT INTO workflow_version (
+  workflow_id,
+  version,
+  graph,
+  created_at
+)
+SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3
+FROM workflow_version
+WHERE workflow_id = $1
+RETURNING *;
+
+-- name: LockWorkflowVersions :exec
+SELECT id
+FROM workflow
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetLatestWorkflowVersion :one
+SELECT *
+FROM workflow_version
+WHERE workflow_id = $1
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: GetWorkflowVersion :one
+SELECT *
+FROM workflow_version
+WHERE id = $1;
+
+-- name: GetWorkflowRunVersion :one
+SELECT wv.*
+FROM workflow_version wv
+INNER JOIN workflow_run wr ON wr.workflow_version_id = wv.id
+WHERE wr.id = $1;
@@ -0,0 +1,8 @@
+CREATE TABLE workflow_concurrency (
+    workflow_id INTEGER PRIMARY KEY REFERENCES workflow(id) ON DELETE CASCADE,
+    max_concurrent_runs INTEGER NOT NULL CHECK (max_concurrent_runs > 0),
+    overlap_policy TEXT NOT NULL CHECK (
+        overlap_policy IN ('allow', 'skip', 'queue', 'cancel_previous')
+    ),
+    updated_at BIGINT NOT NULL
+);
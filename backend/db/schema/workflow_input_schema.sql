@@ -0,0 +1,6 @@
+CREATE TABLE workflow_input_schema (
+    workflow_id INTEGER PRIMARY KEY REFERENCES workflow(id) ON DELETE CASCADE,
+    -- the declared inputs in the order the run form shows them, see models.WorkflowInput
+    inputs JSONB NOT NULL,
+    updated_at BIGINT NOT NULL
+);
@@ -1,13 +1,16 @@
 CREATE TABLE workflow_node_run (
   id SERIAL PRIMARY KEY,
   workflow_run_id INTEGER NOT NULL REFERENCES workflow_run(id) ON DELETE CASCADE,
-  workflow_node_id INTEGER NOT NULL REFERENCES workflow_node(id),
-  status TEXT NOT NULL CHECK (status IN ('pending', 'running', 'success', 'failed')),
+  -- a node of the run's workflow version, it may since have been deleted from the workflow
+  workflow_node_id INTEGER NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('pending', 'running', 'waiting', 'success', 'failed', 'skipped', 'cancelled')),
   retry_count INTEGER NOT NULL DEFAULT 0,
   started_at BIGINT,
   finished_at BIGINT,
   metadata JSONB,
   error_message TEXT,
+  -- position in the enclosing foreach loop, -1 for nodes outside of a loop
+  iteration_index INTEGER NOT NULL DEFAULT -1,
 
-  CONSTRAINT unique_node_per_run UNIQUE (workflow_run_id, workflow_node_id)
+  CONSTRAINT unique_node_iteration_per_run UNIQUE (workflow_run_id, workflow_node_id, iteration_index)
 );
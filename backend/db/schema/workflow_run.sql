@@ -1,7 +1,26 @@
 CREATE TABLE workflow_run (
   id SERIAL PRIMARY KEY,
   workflow_id INTEGER NOT NULL REFERENCES workflow(id) ON DELETE CASCADE,
-  status TEXT NOT NULL CHECK (status IN ('running', 'success', 'failed', 'cancelled')),
+  status TEXT NOT NULL CHECK (
+    status IN ('queued', 'running', 'success', 'failed', 'cancelled', 'skipped', 'timed_out')
+  ),
   finished_at BIGINT,
-  created_at BIGINT NOT NULL
+  created_at BIGINT NOT NULL,
+  dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+  -- set on runs started by a run_workflow node of another run
+  parent_run_id INTEGER REFERENCES workflow_run(id) ON DELETE SET NULL,
+  parent_node_run_id INTEGER,
+  depth INTEGER NOT NULL DEFAULT 0,
+  inputs JSONB,
+  -- why the run was skipped instead of started, e.g. the workflow's concurrency limit
+  skip_reason TEXT,
+  -- the run times out when it is still running past this time, set once the run starts
+  deadline_at BIGINT,
+  -- why the run failed or timed out
+  error_message TEXT,
+  -- what started the run, e.g. manual, schedule or calendar, and the data it was started with
+  trigger_source TEXT NOT NULL DEFAULT 'manual',
+  trigger_payload JSONB,
+  -- the saved graph the run executes, later edits of the workflow do not affect the run
+  workflow_version_id INTEGER NOT NULL REFERENCES workflow_version(id) ON DELETE CASCADE
 );
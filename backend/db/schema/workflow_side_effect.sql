@@ -0,0 +1,8 @@
+CREATE TABLE workflow_side_effect (
+    idempotency_key TEXT PRIMARY KEY,
+    workflow_run_id INTEGER NOT NULL REFERENCES workflow_run(id) ON DELETE CASCADE,
+    output JSONB NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX workflow_side_effect_run ON workflow_side_effect (workflow_run_id);
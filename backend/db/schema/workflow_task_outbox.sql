@@ -0,0 +1,17 @@
+CREATE TABLE workflow_task_outbox (
+  id BIGSERIAL PRIMARY KEY,
+  payload JSONB NOT NULL,
+  -- published through the delayed exchange when positive, counted from created_at
+  delay_ms BIGINT NOT NULL DEFAULT 0,
+  created_at BIGINT NOT NULL,
+  sent_at BIGINT,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  -- a task that failed to publish is not relayed again before then
+  retry_at BIGINT,
+  -- set once the task failed to publish too many times, it is no longer relayed
+  dead_at BIGINT
+);
+
+CREATE INDEX workflow_task_outbox_unsent ON workflow_task_outbox (id)
+  WHERE sent_at IS NULL AND dead_at IS NULL;
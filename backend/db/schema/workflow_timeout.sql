@@ -0,0 +1,5 @@
+CREATE TABLE workflow_timeout (
+    workflow_id INTEGER PRIMARY KEY REFERENCES workflow(id) ON DELETE CASCADE,
+    run_timeout_seconds INTEGER NOT NULL CHECK (run_timeout_seconds > 0),
+    updated_at BIGINT NOT NULL
+);
@@ -0,0 +1,11 @@
+CREATE TABLE workflow_version (
+    id SERIAL PRIMARY KEY,
+    workflow_id INTEGER NOT NULL REFERENCES workflow(id) ON DELETE CASCADE,
+    -- counts the saved graphs of the workflow, starting at 1
+    version INTEGER NOT NULL,
+    -- the nodes and edges as saved, never updated so runs pinned to the version keep them
+    graph JSONB NOT NULL,
+    created_at BIGINT NOT NULL,
+
+    CONSTRAINT unique_workflow_version UNIQUE (workflow_id, version)
+);
@@ -0,0 +1,25 @@
+package internal
+
+import "time"
+
+// NodeTypeDelay is the node type of delays. A delay node waits until its resume time before its
+// children run, without holding on to a worker in the meantime.
+const NodeTypeDelay = "delay"
+
+// DelayResumeAtKey is the output key of a delay node holding its resume time as RFC3339.
+const DelayResumeAtKey = "resume_at"
+
+// DelayResumeAt returns the time a delay node run resumes at.
+func DelayResumeAt(output map[string]any) (time.Time, bool) {
+	s, ok := output[DelayResumeAtKey].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
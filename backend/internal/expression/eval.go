@@ -0,0 +1,389 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidExpression = errors.New("invalid expression")
+
+// EvaluateBool evaluates a boolean expression such as
+//
+//	nodes.12.output.status == "sent" && !(run.id < 10)
+//
+// against scope. Operands are literals (numbers, quoted strings, true, false, null) or
+// dotted references resolved with Resolve. References or the whole expression may be wrapped in
+// {{ }}, the wrapped part is evaluated like a parenthesised sub-expression.
+func EvaluateBool(expr string, scope map[string]any) (bool, error) {
+	v, err := Evaluate(expr, scope)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: %q does not evaluate to a boolean", ErrInvalidExpression, expr)
+	}
+
+	return b, nil
+}
+
+// Evaluate parses and evaluates expr against scope and returns the resulting value.
+func Evaluate(expr string, scope map[string]any) (any, error) {
+	tokens, err := tokenize(templatePattern.ReplaceAllString(expr, "($1)"))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, scope: scope}
+
+	v, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidExpression, p.tokens[p.pos].text)
+	}
+
+	return v, nil
+}
+
+type tokenKind int
+
+const (
+	tokenOperator tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(expr) && rune(expr[end]) != c {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end >= len(expr) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidExpression)
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: unescape(expr[i+1 : end])})
+			i = end + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(expr) && unicode.IsDigit(rune(expr[i+1]))):
+			end := i + 1
+			for end < len(expr) && (unicode.IsDigit(rune(expr[end])) || expr[end] == '.') {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[i:end]})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i + 1
+			for end < len(expr) && isIdentChar(rune(expr[end])) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: expr[i:end]})
+			i = end
+		default:
+			matched := false
+
+			for _, op := range operators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op})
+					i += len(op)
+					matched = true
+
+					break
+				}
+			}
+
+			if !matched {
+				return nil, fmt.Errorf("%w: unexpected character %q", ErrInvalidExpression, c)
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+func isIdentChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.' || c == '-'
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\"`, `"`, `\'`, `'`, `\\`, `\`).Replace(s)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	scope  map[string]any
+}
+
+func (p *parser) peek(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return "", false
+	}
+
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			return op, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *parser) parseOr() (any, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peek("||"); !ok {
+			return left, nil
+		}
+
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		l, r, err := boolOperands("||", left, right)
+		if err != nil {
+			return nil, err
+		}
+
+		left = l || r
+	}
+}
+
+func (p *parser) parseAnd() (any, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peek("&&"); !ok {
+			return left, nil
+		}
+
+		p.pos++
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		l, r, err := boolOperands("&&", left, right)
+		if err != nil {
+			return nil, err
+		}
+
+		left = l && r
+	}
+}
+
+func (p *parser) parseNot() (any, error) {
+	if _, ok := p.peek("!"); ok {
+		p.pos++
+
+		v, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: operand of ! is not a boolean", ErrInvalidExpression)
+		}
+
+		return !b, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (any, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.peek("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+
+	p.pos++
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	return compare(op, left, right)
+}
+
+func (p *parser) parsePrimary() (any, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrInvalidExpression)
+	}
+
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad number %q", ErrInvalidExpression, t.text)
+		}
+
+		return f, nil
+	case tokenString:
+		return t.text, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+
+		return Resolve(t.text, p.scope)
+	default:
+		if t.text != "(" {
+			return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidExpression, t.text)
+		}
+
+		v, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := p.peek(")"); !ok {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidExpression)
+		}
+
+		p.pos++
+
+		return v, nil
+	}
+}
+
+func boolOperands(op string, left, right any) (bool, bool, error) {
+	l, lOk := left.(bool)
+	r, rOk := right.(bool)
+
+	if !lOk || !rOk {
+		return false, false, fmt.Errorf("%w: operands of %s must be booleans", ErrInvalidExpression, op)
+	}
+
+	return l, r, nil
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+func compare(op string, left, right any) (bool, error) {
+	if l, ok := toNumber(left); ok {
+		if r, ok := toNumber(right); ok {
+			switch op {
+			case "==":
+				return l == r, nil
+			case "!=":
+				return l != r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			default:
+				return l >= r, nil
+			}
+		}
+	}
+
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case "==":
+				return l == r, nil
+			case "!=":
+				return l != r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			default:
+				return l >= r, nil
+			}
+		}
+	}
+
+	switch op {
+	case "==", "!=":
+		equal := fmt.Sprintf("%T:%v", left, left) == fmt.Sprintf("%T:%v", right, right)
+		if op == "==" {
+			return equal, nil
+		}
+
+		return !equal, nil
+	default:
+		return false, fmt.Errorf(
+			"%w: cannot compare %T and %T with %s",
+			ErrInvalidExpression,
+			left,
+			right,
+			op,
+		)
+	}
+}
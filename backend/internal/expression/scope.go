@@ -0,0 +1,120 @@
+package expression
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// RunContext is the data a node config can reference while a workflow run executes.
+type RunContext struct {
+	RunID         int32
+	WorkflowID    int32
+	WorkflowName  string
+	TriggerSource string
+	TriggeredAt   time.Time
+	NodeRuns      []*models.WorkflowNodeRunCore
+	// TriggerPayload is the data the trigger fired with, e.g. the calendar event.
+	TriggerPayload map[string]any
+	// Inputs are the parameters the run was started with.
+	Inputs map[string]any
+	// Loop is set while a node runs inside an iteration of a foreach node.
+	Loop *LoopContext
+	// Error is set while a node runs behind the on_error edge of a failed node.
+	Error *ErrorContext
+}
+
+// LoopContext is the iteration a node run belongs to.
+type LoopContext struct {
+	Index int32
+	Item  any
+}
+
+// ErrorContext is the failure an error-branch node was started for.
+type ErrorContext struct {
+	NodeID   int32
+	NodeType string
+	Message  string
+	Attempts int32
+}
+
+// Scope builds the lookup tree for templates:
+//
+//	run.id, run.workflow_id
+//	workflow.id, workflow.name
+//	trigger.source, trigger.time, trigger.payload.<key>
+//	nodes.<node id>.status, nodes.<node id>.output.<key>, nodes.<node id>.error
+//	inputs.<name>
+//	loop.index, loop.item (inside a foreach loop)
+//	error.node_id, error.node_type, error.message, error.attempts (behind an on_error edge)
+//
+// Inside a loop, nodes of the loop body resolve to their run in the current iteration.
+func (c *RunContext) Scope() map[string]any {
+	nodes := make(map[string]any, len(c.NodeRuns))
+
+	for _, nodeRun := range c.NodeRuns {
+		if nodeRun.IterationIndex != models.NoIteration &&
+			(c.Loop == nil || nodeRun.IterationIndex != c.Loop.Index) {
+			continue
+		}
+
+		output := make(map[string]any, len(nodeRun.Metadata))
+		for k, v := range nodeRun.Metadata {
+			output[k] = v
+		}
+
+		nodes[strconv.Itoa(int(nodeRun.WorkflowNodeID))] = map[string]any{
+			"status": nodeRun.Status,
+			"output": output,
+			"error":  nodeRun.ErrorMessage.String,
+		}
+	}
+
+	triggerPayload := make(map[string]any, len(c.TriggerPayload))
+	for k, v := range c.TriggerPayload {
+		triggerPayload[k] = v
+	}
+
+	scope := map[string]any{
+		"run": map[string]any{
+			"id":          float64(c.RunID),
+			"workflow_id": float64(c.WorkflowID),
+		},
+		"workflow": map[string]any{
+			"id":   float64(c.WorkflowID),
+			"name": c.WorkflowName,
+		},
+		"trigger": map[string]any{
+			"source":  c.TriggerSource,
+			"time":    c.TriggeredAt.UTC().Format(time.RFC3339),
+			"payload": triggerPayload,
+		},
+		"nodes": nodes,
+	}
+
+	inputs := make(map[string]any, len(c.Inputs))
+	for k, v := range c.Inputs {
+		inputs[k] = v
+	}
+
+	scope["inputs"] = inputs
+
+	if c.Loop != nil {
+		scope["loop"] = map[string]any{
+			"index": float64(c.Loop.Index),
+			"item":  c.Loop.Item,
+		}
+	}
+
+	if c.Error != nil {
+		scope["error"] = map[string]any{
+			"node_id":   float64(c.Error.NodeID),
+			"node_type": c.Error.NodeType,
+			"message":   c.Error.Message,
+			"attempts":  float64(c.Error.Attempts),
+		}
+	}
+
+	return scope
+}
@@ -0,0 +1,179 @@
+package expression
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownReference = errors.New("unknown reference")
+
+	templatePattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+)
+
+// IsTemplate reports whether s contains at least one {{ ... }} expression.
+func IsTemplate(s string) bool {
+	return templatePattern.MatchString(s)
+}
+
+// ContainsTemplate reports whether value, or any string nested in its maps and lists, holds a
+// {{ ... }} expression.
+func ContainsTemplate(value any) bool {
+	switch v := value.(type) {
+	case string:
+		return IsTemplate(v)
+	case map[string]any:
+		for _, item := range v {
+			if ContainsTemplate(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if ContainsTemplate(item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// RenderConfig returns a copy of config with every {{ ... }} expression in its string
+// values resolved against scope. Nested maps and lists are rendered recursively.
+func RenderConfig(config map[string]any, scope map[string]any) (map[string]any, error) {
+	rendered := make(map[string]any, len(config))
+
+	for key, value := range config {
+		v, err := renderValue(value, scope)
+		if err != nil {
+			return nil, fmt.Errorf("template error in %q: %w", key, err)
+		}
+
+		rendered[key] = v
+	}
+
+	return rendered, nil
+}
+
+func renderValue(value any, scope map[string]any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return RenderString(v, scope)
+	case map[string]any:
+		return RenderConfig(v, scope)
+	case []any:
+		out := make([]any, len(v))
+
+		for i, item := range v {
+			r, err := renderValue(item, scope)
+			if err != nil {
+				return nil, err
+			}
+
+			out[i] = r
+		}
+
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// RenderString resolves the expressions in s. When s consists of a single expression the
+// referenced value is returned as is, so lists and objects can be passed between nodes.
+// Otherwise every expression is formatted and interpolated into the surrounding text.
+func RenderString(s string, scope map[string]any) (any, error) {
+	matches := templatePattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		return Resolve(s[matches[0][2]:matches[0][3]], scope)
+	}
+
+	var b strings.Builder
+
+	last := 0
+
+	for _, m := range matches {
+		b.WriteString(s[last:m[0]])
+
+		v, err := Resolve(s[m[2]:m[3]], scope)
+		if err != nil {
+			return nil, err
+		}
+
+		formatted, err := format(v)
+		if err != nil {
+			return nil, err
+		}
+
+		b.WriteString(formatted)
+
+		last = m[1]
+	}
+
+	b.WriteString(s[last:])
+
+	return b.String(), nil
+}
+
+// Resolve looks up a dotted path such as nodes.12.output.status in scope. Numeric
+// segments index into lists.
+func Resolve(path string, scope map[string]any) (any, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("%w: empty expression", ErrUnknownReference)
+	}
+
+	var current any = scope
+
+	for _, segment := range strings.Split(path, ".") {
+		switch c := current.(type) {
+		case map[string]any:
+			v, ok := c[segment]
+			if !ok {
+				return nil, fmt.Errorf("%w %q", ErrUnknownReference, path)
+			}
+
+			current = v
+		case []any:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(c) {
+				return nil, fmt.Errorf("%w %q: invalid list index %q", ErrUnknownReference, path, segment)
+			}
+
+			current = c[idx]
+		default:
+			return nil, fmt.Errorf("%w %q: cannot access %q", ErrUnknownReference, path, segment)
+		}
+	}
+
+	return current, nil
+}
+
+func format(v any) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case bool, int, int32, int64:
+		return fmt.Sprintf("%v", t), nil
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return "", fmt.Errorf("failed to format template value: %w", err)
+		}
+
+		return string(b), nil
+	}
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// FailurePolicyConfigKey is the node config key holding what happens once a node failed all of
+// its attempts.
+const FailurePolicyConfigKey = "on_failure"
+
+// EdgeLabelOnError marks the edges followed when their source node fails, they lead to the nodes
+// compensating for the failure.
+const EdgeLabelOnError = "on_error"
+
+const (
+	// FailurePolicyFailRun finishes the whole run as failed.
+	FailurePolicyFailRun = "fail_run"
+	// FailurePolicyContinue carries on with the node's children as if it succeeded.
+	FailurePolicyContinue = "continue"
+	// FailurePolicyErrorBranch only follows the node's on_error edges.
+	FailurePolicyErrorBranch = "error_branch"
+)
+
+// ParseFailurePolicy reads the failure policy from a node config. It returns an empty policy when
+// the config does not set one, see FailurePolicyOf for the default.
+func ParseFailurePolicy(config map[string]any) (string, error) {
+	raw, ok := config[FailurePolicyConfigKey]
+	if !ok || raw == nil {
+		return "", nil
+	}
+
+	policy, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid failure policy: must be a string")
+	}
+
+	switch policy {
+	case FailurePolicyFailRun, FailurePolicyContinue, FailurePolicyErrorBranch:
+		return policy, nil
+	default:
+		return "", fmt.Errorf(
+			"invalid failure policy %q: must be %s, %s or %s",
+			policy,
+			FailurePolicyFailRun,
+			FailurePolicyContinue,
+			FailurePolicyErrorBranch,
+		)
+	}
+}
+
+// HasErrorEdges reports whether nodeID has at least one outgoing on_error edge.
+func HasErrorEdges(graph *models.WorkflowGraph, nodeID int32) bool {
+	for _, edge := range graph.Edges {
+		if edge.SourceNodeID == nodeID && edge.Label == EdgeLabelOnError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FailurePolicyOf returns the failure policy of a node in the graph. Nodes that do not set one
+// follow their on_error edges when they have any, and fail the run otherwise. An error branch
+// without on_error edges fails the run too, there is nothing to handle the failure.
+func FailurePolicyOf(graph *models.WorkflowGraph, nodeID int32) string {
+	policy, _ := ParseFailurePolicy(nodeConfig(graph.Node(nodeID)))
+	if policy != "" && policy != FailurePolicyErrorBranch {
+		return policy
+	}
+
+	if HasErrorEdges(graph, nodeID) {
+		return FailurePolicyErrorBranch
+	}
+
+	return FailurePolicyFailRun
+}
+
+// IsFailureHandled reports whether a node run failed all of its attempts and its failure policy
+// lets the run go on without it. Inside a loop that tolerates failures, the foreach node takes
+// over the failures of its iterations.
+func IsFailureHandled(graph *models.WorkflowGraph, nodeRun *models.WorkflowNodeRunCore) bool {
+	if nodeRun.Status != "failed" {
+		return false
+	}
+
+	retryPolicy, _ := models.ParseRetryPolicy(nodeConfig(graph.Node(nodeRun.WorkflowNodeID)))
+	if nodeRun.RetryCount < retryPolicy.MaxAttempts {
+		return false
+	}
+
+	if FailurePolicyOf(graph, nodeRun.WorkflowNodeID) != FailurePolicyFailRun {
+		return true
+	}
+
+	return nodeRun.IterationIndex != models.NoIteration &&
+		LoopToleratesFailure(graph, nodeRun.WorkflowNodeID)
+}
+
+// LoopToleratesFailure reports whether the loop nodeID belongs to keeps going after one of its
+// iterations failed, which is the case when the foreach node does not fail the run.
+func LoopToleratesFailure(graph *models.WorkflowGraph, nodeID int32) bool {
+	body := FindLoopBody(graph, nodeID)
+
+	return body != nil && FailurePolicyOf(graph, body.LoopNodeID) != FailurePolicyFailRun
+}
+
+// ResumableNodeRuns returns the IDs of the node runs a failed run has to run again: the failed
+// node runs whose failure stopped the run, and the node runs skipped downstream of them. Failures
+// handled by their failure policy stay as they are, their branch of the run already went on.
+func ResumableNodeRuns(
+	graph *models.WorkflowGraph,
+	nodeRuns []*models.WorkflowNodeRunCore,
+) []int32 {
+	type nodeRunKey struct {
+		nodeID         int32
+		iterationIndex int32
+	}
+
+	byKey := make(map[nodeRunKey]*models.WorkflowNodeRunCore, len(nodeRuns))
+	queue := []*models.WorkflowNodeRunCore{}
+
+	for _, nodeRun := range nodeRuns {
+		byKey[nodeRunKey{nodeRun.WorkflowNodeID, nodeRun.IterationIndex}] = nodeRun
+
+		if nodeRun.Status == "failed" && !IsFailureHandled(graph, nodeRun) {
+			queue = append(queue, nodeRun)
+		}
+	}
+
+	// each edges are left out, the iterations of a loop are not skipped with their foreach node
+	children := make(map[int32][]int32)
+
+	for _, edge := range graph.Edges {
+		if edge.Label != EdgeLabelEach {
+			children[edge.SourceNodeID] = append(children[edge.SourceNodeID], edge.TargetNodeID)
+		}
+	}
+
+	ids := []int32{}
+	seen := make(map[int32]bool)
+
+	for len(queue) > 0 {
+		nodeRun := queue[0]
+		queue = queue[1:]
+
+		if seen[nodeRun.ID] {
+			continue
+		}
+
+		seen[nodeRun.ID] = true
+		ids = append(ids, nodeRun.ID)
+
+		for _, childID := range children[nodeRun.WorkflowNodeID] {
+			child, ok := byKey[nodeRunKey{childID, nodeRun.IterationIndex}]
+			if ok && child.Status == "skipped" {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	return ids
+}
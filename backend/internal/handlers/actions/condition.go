@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tinyautomator/tinyautomator-core/backend/internal"
+	"github.com/tinyautomator/tinyautomator-core/backend/internal/expression"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// ConditionHandler evaluates a boolean expression and reports the branch to follow.
+// Outgoing edges labelled "true" or "false" are taken depending on the result.
+//
+// The expression references values directly, e.g. nodes.12.output.status == "sent".
+// Wrapping the whole expression or its references in {{ }} is also accepted. The executor
+// does not render the expression, references are resolved against the scope when it is
+// evaluated so upstream values never become part of its source.
+type ConditionHandler struct{}
+
+func NewConditionHandler() ActionHandler {
+	return &ConditionHandler{}
+}
+
+func extractConditionExpression(input ActionNodeInput) (any, error) {
+	expr, ok := input.Config[internal.ConditionExpressionKey]
+	if !ok {
+		return nil, fmt.Errorf("expression is required")
+	}
+
+	switch e := expr.(type) {
+	case bool:
+		return e, nil
+	case string:
+		if strings.TrimSpace(e) == "" {
+			return nil, fmt.Errorf("expression is required")
+		}
+
+		return e, nil
+	default:
+		return nil, fmt.Errorf("expression must be a string")
+	}
+}
+
+func (h *ConditionHandler) Execute(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	expr, err := extractConditionExpression(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition config: %w", err)
+	}
+
+	result, ok := expr.(bool)
+	if !ok {
+		result, err = expression.EvaluateBool(expr.(string), input.Scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate condition: %w", err)
+		}
+	}
+
+	return ActionNodeOutput{
+		"result": result,
+	}, nil
+}
+
+// Simulate evaluates the condition like Execute does, it has no side effects.
+func (h *ConditionHandler) Simulate(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	return h.Execute(ctx, userID, input)
+}
+
+func (h *ConditionHandler) Spec() models.NodeTypeSpec {
+	return models.NodeTypeSpec{
+		Label:       "Condition",
+		Description: "Follows the true or false edges depending on an expression.",
+		ConfigSchema: map[string]any{
+			"type":     "object",
+			"required": []string{internal.ConditionExpressionKey},
+			"properties": map[string]any{
+				internal.ConditionExpressionKey: map[string]any{
+					"type":        []string{"string", "boolean"},
+					"description": "E.g. nodes.12.output.status == \"sent\".",
+				},
+			},
+		},
+		OutputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"result": map[string]any{"type": "boolean"}},
+		},
+	}
+}
+
+func (h *ConditionHandler) DefaultTimeout() time.Duration {
+	return 5 * time.Second
+}
+
+func (h *ConditionHandler) Validate(config ActionNodeInput) error {
+	_, err := extractConditionExpression(config)
+	return err
+}
+
+var _ ActionHandler = &ConditionHandler{}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tinyautomator/tinyautomator-core/backend/internal"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// DelayHandler works out when a delay node resumes. Either a fixed duration such as "48h" is
+// waited, or the node resumes at an absolute RFC3339 timestamp, which may be an expression like
+// {{nodes.12.output.end_time}}. A timestamp in the past resumes right away.
+type DelayHandler struct{}
+
+func NewDelayHandler() ActionHandler {
+	return &DelayHandler{}
+}
+
+type delayConfig struct {
+	duration time.Duration
+	until    time.Time
+	// deferred is set when duration or until is an expression only resolved once the run
+	// executes
+	deferred bool
+}
+
+func extractDelayConfig(input ActionNodeInput) (*delayConfig, error) {
+	rawDuration, hasDuration := input.Config["duration"]
+	rawUntil, hasUntil := input.Config["until"]
+
+	if hasDuration == hasUntil {
+		return nil, fmt.Errorf("exactly one of duration or until is required")
+	}
+
+	if hasDuration {
+		s, ok := rawDuration.(string)
+		if !ok {
+			return nil, fmt.Errorf("duration must be a string such as \"30m\" or \"48h\"")
+		}
+
+		if strings.Contains(s, "{{") {
+			return &delayConfig{deferred: true}, nil
+		}
+
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration: %w", err)
+		}
+
+		if d <= 0 {
+			return nil, fmt.Errorf("duration must be positive")
+		}
+
+		return &delayConfig{duration: d}, nil
+	}
+
+	s, ok := rawUntil.(string)
+	if !ok {
+		return nil, fmt.Errorf("until must be an RFC3339 timestamp")
+	}
+
+	if strings.Contains(s, "{{") {
+		return &delayConfig{deferred: true}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("until must be an RFC3339 timestamp: %w", err)
+	}
+
+	return &delayConfig{until: t}, nil
+}
+
+func (h *DelayHandler) Execute(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	config, err := extractDelayConfig(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delay config: %w", err)
+	}
+
+	if config.deferred {
+		return nil, fmt.Errorf("invalid delay config: duration or until did not resolve")
+	}
+
+	now := time.Now()
+
+	resumeAt := config.until
+	if config.duration > 0 {
+		resumeAt = now.Add(config.duration)
+	}
+
+	return ActionNodeOutput{
+		internal.DelayResumeAtKey: resumeAt.UTC().Format(time.RFC3339),
+		"delay_ms":                max(resumeAt.Sub(now).Milliseconds(), 0),
+	}, nil
+}
+
+// Simulate reports the resume time like Execute does. Dry runs do not wait for it.
+func (h *DelayHandler) Simulate(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	return h.Execute(ctx, userID, input)
+}
+
+func (h *DelayHandler) Spec() models.NodeTypeSpec {
+	return models.NodeTypeSpec{
+		Label:       "Delay",
+		Description: "Waits for a duration or until a point in time before carrying on.",
+		ConfigSchema: map[string]any{
+			"type": "object",
+			"oneOf": []any{
+				map[string]any{"required": []string{"duration"}},
+				map[string]any{"required": []string{"until"}},
+			},
+			"properties": map[string]any{
+				"duration": map[string]any{
+					"type":        "string",
+					"description": "A duration such as \"30m\" or \"48h\".",
+				},
+				"until": map[string]any{"type": "string", "format": "date-time"},
+			},
+		},
+		OutputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				internal.DelayResumeAtKey: map[string]any{"type": "string", "format": "date-time"},
+				"delay_ms":                map[string]any{"type": "integer"},
+			},
+		},
+	}
+}
+
+func (h *DelayHandler) DefaultTimeout() time.Duration {
+	return 5 * time.Second
+}
+
+func (h *DelayHandler) Validate(config ActionNodeInput) error {
+	_, err := extractDelayConfig(config)
+	return err
+}
+
+var _ ActionHandler = &DelayHandler{}
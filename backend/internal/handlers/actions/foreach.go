@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tinyautomator/tinyautomator-core/backend/internal"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// MaxForEachParallelism caps how many iterations of a loop may run at once.
+const MaxForEachParallelism = 50
+
+// ForEachHandler resolves the list a foreach node loops over. The nodes behind its "each" edges
+// then run once per item and can reference loop.index and loop.item.
+//
+// The items are either a static list or a reference to an earlier node's output, e.g.
+// {{nodes.12.output.rows}}. A string holding a JSON list is accepted as well.
+type ForEachHandler struct{}
+
+func NewForEachHandler() ActionHandler {
+	return &ForEachHandler{}
+}
+
+func extractForEachItems(input ActionNodeInput) ([]any, error) {
+	raw, ok := input.Config["items"]
+	if !ok || raw == nil {
+		return nil, fmt.Errorf("items is required")
+	}
+
+	switch items := raw.(type) {
+	case []any:
+		return items, nil
+	case string:
+		if strings.Contains(items, "{{") {
+			// a reference that is only resolved once the run executes
+			return nil, nil
+		}
+
+		var list []any
+		if err := json.Unmarshal([]byte(items), &list); err != nil {
+			return nil, fmt.Errorf("items must be a list")
+		}
+
+		return list, nil
+	default:
+		return nil, fmt.Errorf("items must be a list")
+	}
+}
+
+func extractForEachParallelism(input ActionNodeInput) (int, error) {
+	raw, ok := input.Config["parallelism"]
+	if !ok || raw == nil {
+		return internal.DefaultLoopParallelism, nil
+	}
+
+	p, ok := raw.(float64)
+	if !ok || p != float64(int(p)) {
+		return 0, fmt.Errorf("parallelism must be an integer")
+	}
+
+	if p < 1 || p > MaxForEachParallelism {
+		return 0, fmt.Errorf("parallelism must be between 1 and %d", MaxForEachParallelism)
+	}
+
+	return int(p), nil
+}
+
+func (h *ForEachHandler) Execute(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	items, err := extractForEachItems(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid foreach config: %w", err)
+	}
+
+	if items == nil {
+		return nil, fmt.Errorf("invalid foreach config: items did not resolve to a list")
+	}
+
+	parallelism, err := extractForEachParallelism(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid foreach config: %w", err)
+	}
+
+	return ActionNodeOutput{
+		"items":       items,
+		"count":       len(items),
+		"parallelism": parallelism,
+	}, nil
+}
+
+// Simulate resolves the items like Execute does, the iterations then run in dry-run mode too.
+func (h *ForEachHandler) Simulate(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	return h.Execute(ctx, userID, input)
+}
+
+func (h *ForEachHandler) Spec() models.NodeTypeSpec {
+	return models.NodeTypeSpec{
+		Label:       "For each",
+		Description: "Runs the nodes behind its each edges once per item of a list.",
+		ConfigSchema: map[string]any{
+			"type":     "object",
+			"required": []string{"items"},
+			"properties": map[string]any{
+				"items": map[string]any{
+					"type":        []string{"array", "string"},
+					"description": "A list, or a reference like {{nodes.12.output.rows}}.",
+				},
+				"parallelism": map[string]any{
+					"type":    "integer",
+					"minimum": 1,
+					"maximum": MaxForEachParallelism,
+					"default": internal.DefaultLoopParallelism,
+				},
+			},
+		},
+		OutputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"items":             map[string]any{"type": "array"},
+				"count":             map[string]any{"type": "integer"},
+				"parallelism":       map[string]any{"type": "integer"},
+				"failed_iterations": map[string]any{"type": "integer"},
+				"results": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"index": map[string]any{"type": "integer"},
+							"item":  map[string]any{},
+							"status": map[string]any{
+								"type": "string",
+								"enum": []string{"success", "failed"},
+							},
+							"outputs": map[string]any{"type": "object"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (h *ForEachHandler) DefaultTimeout() time.Duration {
+	return 5 * time.Second
+}
+
+func (h *ForEachHandler) Validate(config ActionNodeInput) error {
+	if _, err := extractForEachItems(config); err != nil {
+		return err
+	}
+
+	// a parallelism expression is checked once it is rendered, when the node runs
+	if s, ok := config.Config["parallelism"].(string); ok && strings.Contains(s, "{{") {
+		return nil
+	}
+
+	_, err := extractForEachParallelism(config)
+
+	return err
+}
+
+var _ ActionHandler = &ForEachHandler{}
@@ -2,18 +2,70 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/tinyautomator/tinyautomator-core/backend/clients/rabbitmq"
+	"github.com/tinyautomator/tinyautomator-core/backend/internal"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
 )
 
+// TimeoutConfigKey is the node config key overriding the handler's DefaultTimeout, in seconds.
+const TimeoutConfigKey = "timeout_seconds"
+
+// DryRunOutputKey is set on every output produced by Simulate.
+const DryRunOutputKey = "dry_run"
+
+var (
+	ErrActionTimedOut    = errors.New("timed_out")
+	ErrUnknownActionType = errors.New("unknown action type")
+)
+
+// ActionNodeOutput is the structured result of an action. It is persisted as the
+// node run metadata and handed to downstream nodes.
+type ActionNodeOutput map[string]any
+
 type ActionNodeInput struct {
 	Config map[string]any
+	// ParentOutputs holds the outputs of the node's parents in the current run, keyed by node ID.
+	ParentOutputs map[int32]ActionNodeOutput
+	// Scope is the run context node configs are rendered against. See expression.RunContext.
+	Scope map[string]any
+	// IdempotencyKey identifies the attempt the action runs for. It stays the same when a task is
+	// redelivered mid-attempt, so handlers can tell a repeated attempt from a new one.
+	IdempotencyKey string
 }
 
 type ActionHandler interface {
-	Execute(ctx context.Context, userID string, input ActionNodeInput) error
+	Execute(ctx context.Context, userID string, input ActionNodeInput) (ActionNodeOutput, error)
 	Validate(config ActionNodeInput) error
+	// Simulate reports what Execute would do with input without causing any side effects. It
+	// is used for dry runs, the returned output stands in for the real one downstream.
+	Simulate(ctx context.Context, userID string, input ActionNodeInput) (ActionNodeOutput, error)
+	// DefaultTimeout bounds a single execution when the node config does not set timeout_seconds.
+	DefaultTimeout() time.Duration
+	// Spec describes the action for the node type catalog. NodeType and Category are filled in
+	// by the registry, as are the settings every action node accepts.
+	Spec() models.NodeTypeSpec
+}
+
+// IdempotentActionHandler is implemented by handlers whose Execute has side effects outside of
+// the run, like sending an email. The executor records their outputs by idempotency key and does
+// not run an attempt again once its side effect happened.
+type IdempotentActionHandler interface {
+	ActionHandler
+	// FindCompleted looks up whether the side effect of the attempt with input's idempotency key
+	// already happened and returns its output if so. It covers a crash between the side effect
+	// and its output being recorded.
+	FindCompleted(
+		ctx context.Context,
+		userID string,
+		input ActionNodeInput,
+	) (ActionNodeOutput, bool, error)
 }
 
 type ActionRegistry struct {
@@ -30,17 +82,296 @@ func (r *ActionRegistry) Register(nodeType string, handler ActionHandler) {
 	r.handlers[nodeType] = handler
 }
 
-func (r *ActionRegistry) Execute(userID string, nodeType string, input ActionNodeInput) error {
+// Specs returns the specs of all registered actions, sorted by node type.
+func (r *ActionRegistry) Specs() []*models.NodeTypeSpec {
+	specs := make([]*models.NodeTypeSpec, 0, len(r.handlers))
+
+	for _, nodeType := range slices.Sorted(maps.Keys(r.handlers)) {
+		handler := r.handlers[nodeType]
+		spec := handler.Spec()
+		spec.NodeType = nodeType
+		spec.Category = "action"
+		spec.ConfigSchema = withNodeSettings(spec.ConfigSchema, handler.DefaultTimeout())
+		specs = append(specs, &spec)
+	}
+
+	return specs
+}
+
+// withNodeSettings adds the settings the engine reads from every action node config to the
+// properties of schema.
+func withNodeSettings(schema map[string]any, defaultTimeout time.Duration) map[string]any {
+	retryPolicy := models.DefaultRetryPolicy()
+	properties, _ := schema["properties"].(map[string]any)
+
+	settings := map[string]any{
+		TimeoutConfigKey: map[string]any{
+			"type":             "number",
+			"exclusiveMinimum": 0,
+			"maximum":          models.MaxNodeTimeout.Seconds(),
+			"default":          defaultTimeout.Seconds(),
+		},
+		models.RetryPolicyConfigKey: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"max_attempts": map[string]any{
+					"type":    "integer",
+					"minimum": 1,
+					"maximum": 25,
+					"default": retryPolicy.MaxAttempts,
+				},
+				"initial_delay_ms": map[string]any{
+					"type":    "integer",
+					"minimum": 0,
+					"default": retryPolicy.InitialDelayMs,
+				},
+				"backoff_multiplier": map[string]any{
+					"type":    "number",
+					"minimum": 1,
+					"default": retryPolicy.BackoffMultiplier,
+				},
+				"max_delay_ms": map[string]any{
+					"type":    "integer",
+					"minimum": 0,
+					"maximum": models.MaxRetryDelayMs,
+					"default": retryPolicy.MaxDelayMs,
+				},
+			},
+		},
+		internal.FailurePolicyConfigKey: map[string]any{
+			"type": "string",
+			"enum": []string{
+				internal.FailurePolicyFailRun,
+				internal.FailurePolicyContinue,
+				internal.FailurePolicyErrorBranch,
+			},
+		},
+		internal.JoinConfigKey: map[string]any{
+			"type":    "string",
+			"enum":    []string{internal.JoinAll, internal.JoinAny, internal.JoinAllSettled},
+			"default": internal.JoinAll,
+		},
+	}
+
+	merged := make(map[string]any, len(properties)+len(settings))
+	maps.Copy(merged, settings)
+	maps.Copy(merged, properties)
+
+	schema = maps.Clone(schema)
+	schema["properties"] = merged
+
+	return schema
+}
+
+// Validate checks a node config as saved with the workflow against the handler for nodeType.
+// Values holding {{ ... }} expressions only take their final shape once rendered, so handlers
+// skip those and check them when the node runs. Required keys must be set either way.
+func (r *ActionRegistry) Validate(nodeType string, config map[string]any) error {
 	handler, exists := r.handlers[nodeType]
 	if !exists {
-		// return fmt.Errorf("unknown action type: %s", nodeType)
-		return nil
+		return fmt.Errorf("%w: %s", ErrUnknownActionType, nodeType)
 	}
 
-	err := handler.Execute(context.Background(), userID, input)
-	if err != nil {
-		return fmt.Errorf("failed to execute action: %w", err)
+	if _, err := ParseTimeout(config, handler.DefaultTimeout()); err != nil {
+		return err
+	}
+
+	required, _ := handler.Spec().ConfigSchema["required"].([]string)
+	for _, key := range required {
+		if config[key] == nil {
+			return fmt.Errorf("invalid %s config: %s is required", nodeType, key)
+		}
+	}
+
+	if err := handler.Validate(ActionNodeInput{Config: config}); err != nil {
+		return fmt.Errorf("invalid %s config: %w", nodeType, err)
 	}
 
 	return nil
 }
+
+// HasSideEffects reports whether the handler for nodeType is an IdempotentActionHandler.
+func (r *ActionRegistry) HasSideEffects(nodeType string) bool {
+	_, ok := r.handlers[nodeType].(IdempotentActionHandler)
+
+	return ok
+}
+
+// FindCompleted asks the handler for nodeType whether the attempt with input's idempotency key
+// already completed, under the same timeout as Execute. Handlers without side effects never
+// completed one.
+func (r *ActionRegistry) FindCompleted(
+	ctx context.Context,
+	userID string,
+	nodeType string,
+	input ActionNodeInput,
+) (ActionNodeOutput, bool, error) {
+	handler, ok := r.handlers[nodeType].(IdempotentActionHandler)
+	if !ok {
+		return nil, false, nil
+	}
+
+	timeout, err := ParseTimeout(input.Config, handler.DefaultTimeout())
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid action config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, found, err := handler.FindCompleted(ctx, userID, input)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find completed action: %w", err)
+	}
+
+	return output, found, nil
+}
+
+// ParseTimeout returns the timeout configured on the node, or fallback when none is set.
+func ParseTimeout(config map[string]any, fallback time.Duration) (time.Duration, error) {
+	raw, ok := config[TimeoutConfigKey]
+	if !ok || raw == nil {
+		return fallback, nil
+	}
+
+	seconds, ok := raw.(float64)
+	if !ok || seconds <= 0 {
+		return fallback, fmt.Errorf("%s must be a positive number", TimeoutConfigKey)
+	}
+
+	if seconds > models.MaxNodeTimeout.Seconds() {
+		return fallback, fmt.Errorf(
+			"%s must be at most %.0f",
+			TimeoutConfigKey,
+			models.MaxNodeTimeout.Seconds(),
+		)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Execute validates the rendered input against the handler for nodeType and runs it, cancelling
+// it once the node's timeout elapses. A timeout is reported as ErrActionTimedOut, an invalid
+// input as a rabbitmq.PermanentError since another attempt would render it the same way.
+func (r *ActionRegistry) Execute(
+	ctx context.Context,
+	userID string,
+	nodeType string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	handler, exists := r.handlers[nodeType]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownActionType, nodeType)
+	}
+
+	timeout, err := ParseTimeout(input.Config, handler.DefaultTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("invalid action config: %w", err)
+	}
+
+	if err := handler.Validate(input); err != nil {
+		return nil, &rabbitmq.PermanentError{Err: fmt.Errorf("invalid action config: %w", err)}
+	}
+
+	execute := func(ctx context.Context) (ActionNodeOutput, error) {
+		return handler.Execute(ctx, userID, input)
+	}
+
+	output, err := runWithTimeout(ctx, nodeType, timeout, execute)
+	if errors.Is(err, ErrActionTimedOut) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to execute action: %w", err)
+	}
+
+	return output, nil
+}
+
+// Simulate validates input against the handler for nodeType and runs its Simulate step under
+// the same timeout as Execute. The output is marked with DryRunOutputKey.
+func (r *ActionRegistry) Simulate(
+	ctx context.Context,
+	userID string,
+	nodeType string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	handler, exists := r.handlers[nodeType]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownActionType, nodeType)
+	}
+
+	timeout, err := ParseTimeout(input.Config, handler.DefaultTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("invalid action config: %w", err)
+	}
+
+	if err := handler.Validate(input); err != nil {
+		return nil, &rabbitmq.PermanentError{Err: fmt.Errorf("invalid action config: %w", err)}
+	}
+
+	simulate := func(ctx context.Context) (ActionNodeOutput, error) {
+		return handler.Simulate(ctx, userID, input)
+	}
+
+	output, err := runWithTimeout(ctx, nodeType, timeout, simulate)
+	if errors.Is(err, ErrActionTimedOut) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to simulate action: %w", err)
+	}
+
+	output[DryRunOutputKey] = true
+
+	return output, nil
+}
+
+// runWithTimeout calls fn with a context that is cancelled after timeout. A timeout is
+// reported as ErrActionTimedOut, any other error is returned as is.
+func runWithTimeout(
+	ctx context.Context,
+	nodeType string,
+	timeout time.Duration,
+	fn func(ctx context.Context) (ActionNodeOutput, error),
+) (ActionNodeOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		output ActionNodeOutput
+		err    error
+	}
+
+	// buffered so a handler that ignores ctx can still finish without blocking forever
+	done := make(chan result, 1)
+
+	go func() {
+		output, err := fn(ctx)
+		done <- result{output, err}
+	}()
+
+	var res result
+
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %s action exceeded %s", ErrActionTimedOut, nodeType, timeout)
+		}
+
+		return nil, fmt.Errorf("action cancelled: %w", ctx.Err())
+	}
+
+	if res.err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %s action exceeded %s: %w", ErrActionTimedOut, nodeType, timeout, res.err)
+		}
+
+		return nil, res.err
+	}
+
+	if res.output == nil {
+		res.output = ActionNodeOutput{}
+	}
+
+	return res.output, nil
+}
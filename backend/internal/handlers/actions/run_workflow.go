@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// RunWorkflowHandler resolves which workflow a run_workflow node starts and with which inputs.
+// The executor starts the child run, and with wait set keeps the node waiting until the child
+// run finishes.
+type RunWorkflowHandler struct{}
+
+func NewRunWorkflowHandler() ActionHandler {
+	return &RunWorkflowHandler{}
+}
+
+type runWorkflowConfig struct {
+	workflowID int32
+	inputs     map[string]any
+	wait       bool
+	// deferred is set when workflow_id or wait is an expression only resolved once the run
+	// executes
+	deferred bool
+}
+
+func extractRunWorkflowConfig(input ActionNodeInput) (*runWorkflowConfig, error) {
+	config := &runWorkflowConfig{}
+
+	switch id := input.Config["workflow_id"].(type) {
+	case float64:
+		config.workflowID = int32(id)
+	case string:
+		if strings.Contains(id, "{{") {
+			config.deferred = true
+			break
+		}
+
+		parsed, err := strconv.ParseInt(id, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("workflow_id must be a number")
+		}
+
+		config.workflowID = int32(parsed)
+	case nil:
+		return nil, fmt.Errorf("workflow_id is required")
+	default:
+		return nil, fmt.Errorf("workflow_id must be a number")
+	}
+
+	if config.workflowID <= 0 && !config.deferred {
+		return nil, fmt.Errorf("workflow_id must be positive")
+	}
+
+	switch inputs := input.Config["inputs"].(type) {
+	case map[string]any:
+		config.inputs = inputs
+	case nil:
+		config.inputs = map[string]any{}
+	default:
+		return nil, fmt.Errorf("inputs must be an object")
+	}
+
+	switch wait := input.Config["wait"].(type) {
+	case bool:
+		config.wait = wait
+	case nil:
+	case string:
+		if !strings.Contains(wait, "{{") {
+			return nil, fmt.Errorf("wait must be a boolean")
+		}
+
+		config.deferred = true
+	default:
+		return nil, fmt.Errorf("wait must be a boolean")
+	}
+
+	return config, nil
+}
+
+func (h *RunWorkflowHandler) Execute(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	config, err := extractRunWorkflowConfig(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run_workflow config: %w", err)
+	}
+
+	if config.deferred {
+		return nil, fmt.Errorf("invalid run_workflow config: workflow_id or wait did not resolve")
+	}
+
+	return ActionNodeOutput{
+		"workflow_id": config.workflowID,
+		"inputs":      config.inputs,
+		"wait":        config.wait,
+	}, nil
+}
+
+// Simulate resolves the config like Execute does, the child workflow then runs as a dry run.
+func (h *RunWorkflowHandler) Simulate(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	return h.Execute(ctx, userID, input)
+}
+
+func (h *RunWorkflowHandler) Spec() models.NodeTypeSpec {
+	return models.NodeTypeSpec{
+		Label:       "Run workflow",
+		Description: "Starts a run of another workflow, optionally waiting for it to finish.",
+		ConfigSchema: map[string]any{
+			"type":     "object",
+			"required": []string{"workflow_id"},
+			"properties": map[string]any{
+				"workflow_id": map[string]any{"type": "integer", "minimum": 1},
+				"inputs":      map[string]any{"type": "object"},
+				"wait":        map[string]any{"type": "boolean", "default": false},
+			},
+		},
+		OutputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"workflow_id":  map[string]any{"type": "integer"},
+				"inputs":       map[string]any{"type": "object"},
+				"wait":         map[string]any{"type": "boolean"},
+				"child_run_id": map[string]any{"type": "integer"},
+				"status": map[string]any{
+					"type":        "string",
+					"description": "Only set when waiting for the child run.",
+				},
+				"outputs": map[string]any{
+					"type":        "object",
+					"description": "Only set when waiting for the child run.",
+				},
+			},
+		},
+	}
+}
+
+func (h *RunWorkflowHandler) DefaultTimeout() time.Duration {
+	return 5 * time.Second
+}
+
+func (h *RunWorkflowHandler) Validate(config ActionNodeInput) error {
+	_, err := extractRunWorkflowConfig(config)
+	return err
+}
+
+var _ ActionHandler = &RunWorkflowHandler{}
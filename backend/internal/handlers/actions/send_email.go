@@ -5,8 +5,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/mail"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/tinyautomator/tinyautomator-core/backend/clients/google"
@@ -50,16 +52,28 @@ func ExtractEmailConfig(input ActionNodeInput) (*EmailConfig, error) {
 	return &config, nil
 }
 
-func encodeSimpleText(to, from, subject, body string) (string, error) {
+// messageIDDomain is the right-hand side of the Message-ID headers of sent emails.
+const messageIDDomain = "tinyautomator"
+
+// messageIDFor derives the Message-ID of the email sent for an attempt, so a repeated attempt
+// can find the email it already sent.
+func messageIDFor(idempotencyKey string) string {
+	return fmt.Sprintf("<%s@%s>", idempotencyKey, messageIDDomain)
+}
+
+func encodeSimpleText(to, from, subject, body, messageID string) (string, error) {
 	if to == "" || from == "" || subject == "" {
 		return "", fmt.Errorf("to, from, and subject are required")
 	}
 
+	headers := fmt.Sprintf("To: %s\r\nFrom: %s\r\nSubject: %s\r\n", to, from, subject)
+	if messageID != "" {
+		headers += fmt.Sprintf("Message-ID: %s\r\n", messageID)
+	}
+
 	raw := fmt.Sprintf(
-		"To: %s\r\nFrom: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=\"UTF-8\"\r\n\r\n%s",
-		to,
-		from,
-		subject,
+		"%sContent-Type: text/plain; charset=\"UTF-8\"\r\n\r\n%s",
+		headers,
 		body,
 	)
 	encoded := base64.RawURLEncoding.EncodeToString([]byte(raw))
@@ -71,10 +85,10 @@ func (h *SendEmailHandler) Execute(
 	ctx context.Context,
 	userID string,
 	input ActionNodeInput,
-) error {
+) (ActionNodeOutput, error) {
 	c, err := ExtractEmailConfig(input)
 	if err != nil {
-		return fmt.Errorf("invalid email config: %w", err)
+		return nil, fmt.Errorf("invalid email config: %w", err)
 	}
 
 	h.logger.WithFields(logrus.Fields{
@@ -83,19 +97,14 @@ func (h *SendEmailHandler) Execute(
 		"body":       c.Message,
 	}).Info("sending email")
 
-	oauthToken, err := h.oauthIntegrationSvc.GetToken(ctx, userID, "google", h.googleOAuthConfig)
+	client, email, err := h.initGmailClient(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get oauth token: %w", err)
+		return nil, err
 	}
 
-	client, err := google.InitGmailClient(ctx, oauthToken, h.googleOAuthConfig)
-	if err != nil {
-		return fmt.Errorf("failed to get user email: %w", err)
-	}
-
-	email, err := client.GetUserEmail(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get user email: %w", err)
+	messageID := ""
+	if input.IdempotencyKey != "" {
+		messageID = messageIDFor(input.IdempotencyKey)
 	}
 
 	encoded, err := encodeSimpleText(
@@ -103,44 +112,187 @@ func (h *SendEmailHandler) Execute(
 		email,
 		c.Subject,
 		c.Message,
+		messageID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to encode email: %w", err)
+		return nil, fmt.Errorf("failed to encode email: %w", err)
 	}
 
-	err = client.SendRawEmail(ctx, encoded)
+	msg, err := client.SendRawEmail(ctx, encoded)
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		return nil, fmt.Errorf("failed to send email: %w", err)
 	}
 
-	return nil
+	return ActionNodeOutput{
+		"message_id": msg.Id,
+		"thread_id":  msg.ThreadId,
+		"from":       email,
+		"recipients": c.Recipients,
+		"subject":    c.Subject,
+	}, nil
 }
 
+// FindCompleted looks for the email sent by the attempt through its Message-ID.
+func (h *SendEmailHandler) FindCompleted(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, bool, error) {
+	if input.IdempotencyKey == "" {
+		return nil, false, nil
+	}
+
+	c, err := ExtractEmailConfig(input)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid email config: %w", err)
+	}
+
+	client, email, err := h.initGmailClient(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	msg, err := client.FindMessageByMessageID(ctx, messageIDFor(input.IdempotencyKey))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find sent email: %w", err)
+	}
+
+	if msg == nil {
+		return nil, false, nil
+	}
+
+	return ActionNodeOutput{
+		"message_id": msg.Id,
+		"thread_id":  msg.ThreadId,
+		"from":       email,
+		"recipients": c.Recipients,
+		"subject":    c.Subject,
+	}, true, nil
+}
+
+// initGmailClient returns a Gmail client acting as the user, along with the user's address.
+func (h *SendEmailHandler) initGmailClient(
+	ctx context.Context,
+	userID string,
+) (*google.GmailClient, string, error) {
+	oauthToken, err := h.oauthIntegrationSvc.GetToken(ctx, userID, "google", h.googleOAuthConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get oauth token: %w", err)
+	}
+
+	client, err := google.InitGmailClient(ctx, oauthToken, h.googleOAuthConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user email: %w", err)
+	}
+
+	email, err := client.GetUserEmail(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user email: %w", err)
+	}
+
+	return client, email, nil
+}
+
+// Simulate returns the email that would be sent. The sender and Gmail IDs are only known once
+// the email is actually sent, so they are left as placeholders.
+func (h *SendEmailHandler) Simulate(
+	ctx context.Context,
+	userID string,
+	input ActionNodeInput,
+) (ActionNodeOutput, error) {
+	c, err := ExtractEmailConfig(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email config: %w", err)
+	}
+
+	return ActionNodeOutput{
+		"message_id": "",
+		"thread_id":  "",
+		"from":       "",
+		"recipients": c.Recipients,
+		"subject":    c.Subject,
+		"message":    c.Message,
+	}, nil
+}
+
+func (h *SendEmailHandler) Spec() models.NodeTypeSpec {
+	return models.NodeTypeSpec{
+		Label:       "Send email",
+		Description: "Sends a plain text email from the user's Gmail account.",
+		ConfigSchema: map[string]any{
+			"type":     "object",
+			"required": []string{"recipients", "subject", "message"},
+			"properties": map[string]any{
+				"recipients": map[string]any{
+					"type":     "array",
+					"items":    map[string]any{"type": "string", "format": "email"},
+					"minItems": 1,
+				},
+				"subject": map[string]any{"type": "string", "maxLength": 256},
+				"message": map[string]any{"type": "string", "maxLength": 10000},
+			},
+		},
+		OutputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"message_id": map[string]any{"type": "string"},
+				"thread_id":  map[string]any{"type": "string"},
+				"from":       map[string]any{"type": "string"},
+				"recipients": map[string]any{
+					"type":  "array",
+					"items": map[string]any{"type": "string"},
+				},
+				"subject": map[string]any{"type": "string"},
+			},
+		},
+		OAuthProvider: "google",
+		OAuthScopes:   google.GmailScopes,
+	}
+}
+
+func (h *SendEmailHandler) DefaultTimeout() time.Duration {
+	return 30 * time.Second
+}
+
+// Validate checks the email config. Values holding {{ ... }} expressions only take their final
+// shape once rendered, they are checked when the node runs.
 func (h *SendEmailHandler) Validate(config ActionNodeInput) error {
+	recipients, ok := config.Config["recipients"].(string)
+	templatedRecipients := ok && strings.Contains(recipients, "{{")
+
+	if templatedRecipients {
+		config = ActionNodeInput{Config: maps.Clone(config.Config)}
+		delete(config.Config, "recipients")
+	}
+
 	c, err := ExtractEmailConfig(config)
 	if err != nil {
 		return err
 	}
 
-	if len(c.Recipients) == 0 {
+	if len(c.Recipients) == 0 && !templatedRecipients {
 		return fmt.Errorf("recipients must be an array of at least one email address")
 	}
 
 	for _, recipient := range c.Recipients {
+		if strings.Contains(recipient, "{{") {
+			continue
+		}
+
 		if _, err := mail.ParseAddress(recipient); err != nil {
 			return fmt.Errorf("invalid email address: %s", recipient)
 		}
 	}
 
-	if len(c.Subject) > 256 {
+	if len(c.Subject) > 256 && !strings.Contains(c.Subject, "{{") {
 		return fmt.Errorf("subject must be less than 256 characters")
 	}
 
-	if len(c.Message) > 10000 {
+	if len(c.Message) > 10000 && !strings.Contains(c.Message, "{{") {
 		return fmt.Errorf("message must be less than 10000 characters")
 	}
 
 	return nil
 }
 
-var _ ActionHandler = &SendEmailHandler{}
+var _ IdempotentActionHandler = &SendEmailHandler{}
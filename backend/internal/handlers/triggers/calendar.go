@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/tinyautomator/tinyautomator-core/backend/clients/google"
 	"github.com/tinyautomator/tinyautomator-core/backend/models"
 )
 
@@ -53,6 +54,40 @@ func (h *CalendarEventTriggerHandler) Validate(input TriggerNodeInput) error {
 	return nil
 }
 
+func (h *CalendarEventTriggerHandler) Spec() models.NodeTypeSpec {
+	return models.NodeTypeSpec{
+		Label:       "Calendar event",
+		Description: "Runs the workflow when a matching Google Calendar event changes.",
+		ConfigSchema: map[string]any{
+			"type":     "object",
+			"required": []string{"eventStatus"},
+			"properties": map[string]any{
+				"calendarID": map[string]any{
+					"type":        "string",
+					"description": "The calendar to watch, the primary calendar when left out.",
+				},
+				"keywords": map[string]any{
+					"type":     "array",
+					"items":    map[string]any{"type": "string"},
+					"maxItems": 20,
+				},
+				"timeCondition": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"maximum":     4 * 7 * 24 * 60,
+					"description": "Minutes before the event starts or ends.",
+				},
+				"eventStatus": map[string]any{
+					"type": "string",
+					"enum": []string{"cancelled", "starting", "ending"},
+				},
+			},
+		},
+		OAuthProvider: "google",
+		OAuthScopes:   google.CalendarScopes,
+	}
+}
+
 func (h *CalendarEventTriggerHandler) Execute(ctx context.Context, input TriggerNodeInput) error {
 	c, err := buildCalendarConfig(input)
 	if err != nil {
@@ -3,6 +3,10 @@ package triggers
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
 )
 
 type TriggerNodeInput struct {
@@ -13,6 +17,9 @@ type TriggerHandler interface {
 	Execute(ctx context.Context, input TriggerNodeInput) error
 	Validate(input TriggerNodeInput) error
 	Update(ctx context.Context, input TriggerNodeInput) error
+	// Spec describes the trigger for the node type catalog. NodeType and Category are filled
+	// in by the registry.
+	Spec() models.NodeTypeSpec
 }
 
 type TriggerRegistry struct {
@@ -29,6 +36,20 @@ func (r *TriggerRegistry) Register(nodeType string, handler TriggerHandler) {
 	r.handlers[nodeType] = handler
 }
 
+// Specs returns the specs of all registered triggers, sorted by node type.
+func (r *TriggerRegistry) Specs() []*models.NodeTypeSpec {
+	specs := make([]*models.NodeTypeSpec, 0, len(r.handlers))
+
+	for _, nodeType := range slices.Sorted(maps.Keys(r.handlers)) {
+		spec := r.handlers[nodeType].Spec()
+		spec.NodeType = nodeType
+		spec.Category = "trigger"
+		specs = append(specs, &spec)
+	}
+
+	return specs
+}
+
 func (r *TriggerRegistry) Validate(nodeType string, input TriggerNodeInput) error {
 	handler, exists := r.handlers[nodeType]
 	if !exists {
@@ -76,6 +76,28 @@ func (h *ScheduleTriggerHandler) Validate(input TriggerNodeInput) error {
 	return nil
 }
 
+func (h *ScheduleTriggerHandler) Spec() models.NodeTypeSpec {
+	return models.NodeTypeSpec{
+		Label:       "Schedule",
+		Description: "Runs the workflow once or on a recurring schedule.",
+		ConfigSchema: map[string]any{
+			"type":     "object",
+			"required": []string{"scheduleType", "scheduledDate"},
+			"properties": map[string]any{
+				"scheduleType": map[string]any{
+					"type": "string",
+					"enum": []string{"once", "daily", "weekly", "monthly"},
+				},
+				"scheduledDate": map[string]any{
+					"type":        "string",
+					"format":      "date-time",
+					"description": "When the first run happens, later runs repeat at the same time.",
+				},
+			},
+		},
+	}
+}
+
 func (h *ScheduleTriggerHandler) Execute(ctx context.Context, input TriggerNodeInput) error {
 	h.logger.WithFields(logrus.Fields{
 		"config": input.Config,
@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// JoinConfigKey is the node config key holding how a node with several parents joins them.
+const JoinConfigKey = "join"
+
+const (
+	// JoinAll runs a node once every parent that was not skipped succeeded, so branches rejoin
+	// after an if/else. It skips the node as soon as a parent failed, or when every parent was
+	// skipped.
+	JoinAll = "all"
+	// JoinAny runs a node as soon as one parent succeeded, and skips it when none did.
+	JoinAny = "any"
+	// JoinAllSettled runs a node once every parent finished, whatever the outcome. It is only
+	// skipped when none of its parents ran.
+	JoinAllSettled = "all_settled"
+)
+
+// JoinDecision is what happens to a node given the state of its parents.
+type JoinDecision int
+
+const (
+	JoinWait JoinDecision = iota
+	JoinRun
+	JoinSkip
+)
+
+// ParseJoinMode reads the join mode from a node config, defaulting to JoinAll.
+func ParseJoinMode(config map[string]any) (string, error) {
+	raw, ok := config[JoinConfigKey]
+	if !ok || raw == nil {
+		return JoinAll, nil
+	}
+
+	mode, ok := raw.(string)
+	if !ok {
+		return JoinAll, fmt.Errorf("invalid join mode: must be a string")
+	}
+
+	switch mode {
+	case JoinAll, JoinAny, JoinAllSettled:
+		return mode, nil
+	default:
+		return JoinAll, fmt.Errorf(
+			"invalid join mode %q: must be %s, %s or %s",
+			mode,
+			JoinAll,
+			JoinAny,
+			JoinAllSettled,
+		)
+	}
+}
+
+type parentOutcome int
+
+const (
+	parentPending parentOutcome = iota
+	parentSucceeded
+	parentSkipped
+	parentFailed
+)
+
+func nodeConfig(node *models.WorkflowNode) map[string]any {
+	if node == nil || node.Config == nil {
+		return nil
+	}
+
+	return *node.Config
+}
+
+// outcomeOf classifies a parent node run from the point of view of its child. A parent whose
+// branch was not taken counts as skipped, and a failed parent only counts as failed once it has
+// used up its retries and its failure policy does not route around the failure.
+func outcomeOf(graph *models.WorkflowGraph, parent *models.LinkedWorkflowNodeRun) parentOutcome {
+	onError := parent.EdgeLabel.String == EdgeLabelOnError
+
+	switch parent.Status {
+	case "success":
+		if onError || !isEdgeTaken(parent.EdgeLabel.String, parent.Metadata) {
+			return parentSkipped
+		}
+
+		return parentSucceeded
+	case "running":
+		// a foreach node stays running while the nodes behind its each edges run
+		if parent.EdgeLabel.String == EdgeLabelEach {
+			return parentSucceeded
+		}
+
+		return parentPending
+	case "skipped", "cancelled":
+		return parentSkipped
+	case "failed":
+		retryPolicy, _ := models.ParseRetryPolicy(
+			nodeConfig(graph.Node(parent.WorkflowNodeID)),
+		)
+		if parent.RetryCount < retryPolicy.MaxAttempts {
+			return parentPending
+		}
+
+		switch FailurePolicyOf(graph, parent.WorkflowNodeID) {
+		case FailurePolicyContinue:
+			if onError {
+				return parentSkipped
+			}
+
+			return parentSucceeded
+		case FailurePolicyErrorBranch:
+			if onError {
+				return parentSucceeded
+			}
+
+			return parentSkipped
+		default:
+			return parentFailed
+		}
+	default:
+		return parentPending
+	}
+}
+
+// JoinModeOf returns the join mode of a node in the graph. Invalid modes are rejected when the
+// workflow is saved, so they fall back to JoinAll here.
+func JoinModeOf(graph *models.WorkflowGraph, nodeID int32) string {
+	mode, _ := ParseJoinMode(nodeConfig(graph.Node(nodeID)))
+	return mode
+}
+
+// Join decides whether a node runs, keeps waiting or is skipped given its parent node runs.
+func Join(
+	graph *models.WorkflowGraph,
+	mode string,
+	parents []*models.LinkedWorkflowNodeRun,
+) JoinDecision {
+	if len(parents) == 0 {
+		return JoinRun
+	}
+
+	counts := make(map[parentOutcome]int)
+	for _, parent := range parents {
+		counts[outcomeOf(graph, parent)]++
+	}
+
+	switch mode {
+	case JoinAny:
+		if counts[parentSucceeded] > 0 {
+			return JoinRun
+		}
+
+		if counts[parentPending] == 0 {
+			return JoinSkip
+		}
+	case JoinAllSettled:
+		if counts[parentPending] > 0 {
+			return JoinWait
+		}
+
+		if counts[parentSkipped] == len(parents) {
+			return JoinSkip
+		}
+
+		return JoinRun
+	default:
+		if counts[parentFailed] > 0 {
+			return JoinSkip
+		}
+
+		if counts[parentPending] > 0 {
+			return JoinWait
+		}
+
+		if counts[parentSkipped] == len(parents) {
+			return JoinSkip
+		}
+
+		return JoinRun
+	}
+
+	return JoinWait
+}
+
+// ToleratesFailure reports whether a permanent failure of nodeID still lets one of its children
+// run, which is the case for children joining with JoinAny or JoinAllSettled.
+func ToleratesFailure(graph *models.WorkflowGraph, nodeID int32) bool {
+	for _, edge := range graph.Edges {
+		if edge.SourceNodeID != nodeID {
+			continue
+		}
+
+		if mode := JoinModeOf(graph, edge.TargetNodeID); mode != JoinAll {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// NodeTypeForEach is the node type of loops. A foreach node runs the nodes behind its "each"
+// edges once per item of a list, its other outgoing edges are followed once every iteration
+// has finished.
+const NodeTypeForEach = "foreach"
+
+// EdgeLabelEach marks the edges from a foreach node into its loop body.
+const EdgeLabelEach = "each"
+
+// DefaultLoopParallelism is how many iterations run at once when the foreach config does not
+// set a parallelism.
+const DefaultLoopParallelism = 5
+
+// LoopBody is the sub-graph a foreach node runs once per item.
+type LoopBody struct {
+	LoopNodeID int32
+	// RootNodeIDs are the targets of the each edges, every iteration starts with them.
+	RootNodeIDs []int32
+	// NodeIDs holds every node of the body, including the roots.
+	NodeIDs []int32
+}
+
+// GetLoopBodies returns the body of every foreach node in the graph keyed by the foreach node
+// ID. A body is made of every node reachable through the loop's each edges.
+func GetLoopBodies(graph *models.WorkflowGraph) map[int32]*LoopBody {
+	children := make(map[int32][]int32)
+	bodies := make(map[int32]*LoopBody)
+
+	for _, edge := range graph.Edges {
+		if edge.Label == EdgeLabelEach {
+			body, ok := bodies[edge.SourceNodeID]
+			if !ok {
+				body = &LoopBody{LoopNodeID: edge.SourceNodeID}
+				bodies[edge.SourceNodeID] = body
+			}
+
+			body.RootNodeIDs = append(body.RootNodeIDs, edge.TargetNodeID)
+
+			continue
+		}
+
+		children[edge.SourceNodeID] = append(children[edge.SourceNodeID], edge.TargetNodeID)
+	}
+
+	for _, body := range bodies {
+		queue := slices.Clone(body.RootNodeIDs)
+
+		for len(queue) > 0 {
+			nodeID := queue[0]
+			queue = queue[1:]
+
+			if slices.Contains(body.NodeIDs, nodeID) {
+				continue
+			}
+
+			body.NodeIDs = append(body.NodeIDs, nodeID)
+			queue = append(queue, children[nodeID]...)
+		}
+	}
+
+	return bodies
+}
+
+// FindLoopBody returns the loop body nodeID belongs to, or nil when it is not part of a loop.
+func FindLoopBody(graph *models.WorkflowGraph, nodeID int32) *LoopBody {
+	for _, body := range GetLoopBodies(graph) {
+		if slices.Contains(body.NodeIDs, nodeID) {
+			return body
+		}
+	}
+
+	return nil
+}
+
+// LoopItems returns the items and parallelism a foreach node run recorded when it started.
+func LoopItems(loopNodeRun *models.WorkflowNodeRunCore) ([]any, int) {
+	items, _ := loopNodeRun.Metadata["items"].([]any)
+
+	parallelism := DefaultLoopParallelism
+
+	switch p := loopNodeRun.Metadata["parallelism"].(type) {
+	case int:
+		parallelism = p
+	case float64:
+		parallelism = int(p)
+	}
+
+	return items, max(parallelism, 1)
+}
+
+// LoopProgress is the state of a loop after AdvanceLoop.
+type LoopProgress struct {
+	// Done is set once every iteration has finished.
+	Done bool
+	// Results holds one entry per item with the outputs of the iteration's nodes, once Done.
+	Results []any
+	// Failed counts the iterations with a node run whose own failure policy did not handle its
+	// failure, once Done. The foreach node fails when there is any.
+	Failed int
+}
+
+// isLoopNodeRunFinished reports whether a body node run no longer holds up its iteration.
+// Failed node runs may still be retried so they keep the iteration in flight, unless their
+// failure policy handles the failure.
+func isLoopNodeRunFinished(graph *models.WorkflowGraph, nodeRun *models.WorkflowNodeRunCore) bool {
+	return nodeRun.Status == "success" || nodeRun.Status == "skipped" ||
+		IsFailureHandled(graph, nodeRun)
+}
+
+// AdvanceLoop starts as many iterations of the loop as its parallelism allows and reports
+// whether every iteration has finished. Concurrent calls are safe, each iteration is only
+// created once.
+func AdvanceLoop(
+	ctx context.Context,
+	logger logrus.FieldLogger,
+	workflowRunRepo models.WorkflowRunRepository,
+	userID string,
+	workflowID int32,
+	workflowRunID int32,
+	graph *models.WorkflowGraph,
+	loopNodeRun *models.WorkflowNodeRunCore,
+	body *LoopBody,
+) (*LoopProgress, error) {
+	items, parallelism := LoopItems(loopNodeRun)
+
+	nodeRuns, err := workflowRunRepo.GetLoopIterationNodeRuns(ctx, workflowRunID, body.NodeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loop iteration node runs: %w", err)
+	}
+
+	iterations := make(map[int32][]*models.WorkflowNodeRunCore)
+	for _, nodeRun := range nodeRuns {
+		iterations[nodeRun.IterationIndex] = append(iterations[nodeRun.IterationIndex], nodeRun)
+	}
+
+	inFlight := 0
+	finished := 0
+
+	for _, iterationNodeRuns := range iterations {
+		done := true
+
+		for _, nodeRun := range iterationNodeRuns {
+			if !isLoopNodeRunFinished(graph, nodeRun) {
+				done = false
+				break
+			}
+		}
+
+		if done {
+			finished++
+		} else {
+			inFlight++
+		}
+	}
+
+	for i := int32(0); int(i) < len(items) && inFlight < parallelism; i++ {
+		if _, started := iterations[i]; started {
+			continue
+		}
+
+		created, err := workflowRunRepo.CreateLoopIteration(ctx, workflowRunID, i, body.NodeIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create loop iteration %d: %w", i, err)
+		}
+
+		inFlight++
+
+		if created == nil {
+			continue
+		}
+
+		for _, nodeRun := range created {
+			if !slices.Contains(body.RootNodeIDs, nodeRun.WorkflowNodeID) {
+				continue
+			}
+
+			if err := EnqueueNode(
+				ctx,
+				logger,
+				workflowRunRepo,
+				userID,
+				workflowID,
+				workflowRunID,
+				nodeRun.WorkflowNodeID,
+				i,
+			); err != nil {
+				return nil, fmt.Errorf("failed to enqueue loop iteration %d: %w", i, err)
+			}
+		}
+
+		logger.WithFields(logrus.Fields{
+			"workflow_run_id": workflowRunID,
+			"loop_node_id":    body.LoopNodeID,
+			"iteration_index": i,
+		}).Info("started loop iteration")
+	}
+
+	if finished < len(items) {
+		return &LoopProgress{}, nil
+	}
+
+	progress := &LoopProgress{Done: true, Results: make([]any, len(items))}
+
+	for i, item := range items {
+		outputs := make(map[string]any)
+		status := "success"
+		failed := false
+
+		for _, nodeRun := range iterations[int32(i)] {
+			switch nodeRun.Status {
+			case "success":
+				outputs[strconv.Itoa(int(nodeRun.WorkflowNodeID))] = nodeRun.Metadata
+			case "failed":
+				status = "failed"
+
+				if FailurePolicyOf(graph, nodeRun.WorkflowNodeID) == FailurePolicyFailRun {
+					failed = true
+				}
+			}
+		}
+
+		if failed {
+			progress.Failed++
+		}
+
+		progress.Results[i] = map[string]any{
+			"index":   i,
+			"item":    item,
+			"status":  status,
+			"outputs": outputs,
+		}
+	}
+
+	return progress, nil
+}
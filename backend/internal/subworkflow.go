@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tinyautomator/tinyautomator-core/backend/clients/redis"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// NodeTypeRunWorkflow is the node type that starts another workflow as a step. With wait set,
+// the node stays waiting until the child run finishes and outputs the child's results.
+const NodeTypeRunWorkflow = "run_workflow"
+
+// MaxSubWorkflowDepth caps how deeply runs started by run_workflow nodes nest, which also stops
+// workflows that end up calling themselves.
+const MaxSubWorkflowDepth = 5
+
+// ResumeParentNodeRun completes the run_workflow node run waiting on a finished child run, and
+// enqueues it again so the parent run carries on. Runs nobody waits on are left alone. A child
+// run that did not succeed fails the node without retries, the executor then applies the node's
+// failure policy.
+func ResumeParentNodeRun(
+	ctx context.Context,
+	logger logrus.FieldLogger,
+	workflowRunRepo models.WorkflowRunRepository,
+	redisClient redis.RedisClient,
+	userID string,
+	childRunID int32,
+	childStatus string,
+) error {
+	var resumed *ResumedNodeRun
+
+	err := workflowRunRepo.WithTransaction(
+		ctx,
+		func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+			var err error
+
+			resumed, err = CompleteParentNodeRun(ctx, txRepo, userID, childRunID, childStatus)
+
+			return err
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resume parent node run: %w", err)
+	}
+
+	PublishResumedNodeRun(ctx, logger, redisClient, resumed)
+
+	return nil
+}
+
+// ResumedNodeRun is a run_workflow node run moved on by its finished child run.
+type ResumedNodeRun struct {
+	RunID       int32
+	NodeID      int32
+	NodeRunID   int32
+	Status      string
+	ChildRunID  int32
+	ChildStatus string
+}
+
+// CompleteParentNodeRun is ResumeParentNodeRun within the caller's transaction, so the child run
+// finishing and its parent node moving on are committed together. The returned node run, nil
+// when nobody waits on the child, is published with PublishResumedNodeRun after the commit.
+func CompleteParentNodeRun(
+	ctx context.Context,
+	txRepo models.WorkflowRunRepository,
+	userID string,
+	childRunID int32,
+	childStatus string,
+) (*ResumedNodeRun, error) {
+	childRun, err := txRepo.GetWorkflowRunCore(ctx, childRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get child workflow run: %w", err)
+	}
+
+	if !childRun.ParentNodeRunID.Valid {
+		return nil, nil
+	}
+
+	parentNodeRun, err := txRepo.GetWorkflowNodeRunByID(ctx, childRun.ParentNodeRunID.Int32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent workflow node run: %w", err)
+	}
+
+	if parentNodeRun.Status != "waiting" {
+		return nil, nil
+	}
+
+	parentRun, err := txRepo.GetWorkflowRunCore(ctx, parentNodeRun.WorkflowRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent workflow run: %w", err)
+	}
+
+	childNodeRuns, err := txRepo.GetWorkflowNodeRuns(ctx, childRunID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get child workflow node runs: %w", err)
+	}
+
+	outputs := make(map[string]any)
+
+	for _, nodeRun := range childNodeRuns {
+		if nodeRun.Status == "success" && nodeRun.IterationIndex == models.NoIteration {
+			outputs[strconv.Itoa(int(nodeRun.WorkflowNodeID))] = nodeRun.Metadata
+		}
+	}
+
+	output := maps.Clone(parentNodeRun.Metadata)
+	if output == nil {
+		output = make(map[string]any)
+	}
+
+	output["child_run_id"] = childRunID
+	output["status"] = childStatus
+	output["outputs"] = outputs
+
+	status := "success"
+
+	var errMsg *string
+
+	if childStatus != "success" {
+		status = "failed"
+		msg := fmt.Sprintf("sub-workflow run %d finished as %s", childRunID, childStatus)
+		errMsg = &msg
+	}
+
+	taskBytes, err := models.BuildWorkflowNodeTaskPayload(
+		userID,
+		parentRun.WorkflowID,
+		parentRun.ID,
+		parentNodeRun.WorkflowNodeID,
+		parentNodeRun.ID,
+		parentNodeRun.IterationIndex,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task for parent node: %w", err)
+	}
+
+	// the parent task is queued along with the status update so the node always moves on
+	err = txRepo.UpdateWorkflowNodeRunStatus(ctx, parentNodeRun.ID, status, output, errMsg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete parent workflow node run: %w", err)
+	}
+
+	if err := txRepo.AddOutboxTask(ctx, taskBytes, 0); err != nil {
+		return nil, fmt.Errorf("failed to queue parent node task: %w", err)
+	}
+
+	return &ResumedNodeRun{
+		RunID:       parentRun.ID,
+		NodeID:      parentNodeRun.WorkflowNodeID,
+		NodeRunID:   parentNodeRun.ID,
+		Status:      status,
+		ChildRunID:  childRunID,
+		ChildStatus: childStatus,
+	}, nil
+}
+
+// PublishResumedNodeRun publishes the new status of a node run resumed by its child run.
+func PublishResumedNodeRun(
+	ctx context.Context,
+	logger logrus.FieldLogger,
+	redisClient redis.RedisClient,
+	resumed *ResumedNodeRun,
+) {
+	if resumed == nil {
+		return
+	}
+
+	kv := logrus.Fields{
+		"parent_run_id":      resumed.RunID,
+		"parent_node_id":     resumed.NodeID,
+		"parent_node_run_id": resumed.NodeRunID,
+		"child_run_id":       resumed.ChildRunID,
+		"child_status":       resumed.ChildStatus,
+	}
+
+	if err := redisClient.PublishNodeStatusUpdate(
+		ctx,
+		resumed.RunID,
+		resumed.NodeID,
+		resumed.Status,
+		nil,
+	); err != nil {
+		logger.WithError(err).WithFields(kv).Warn("failed to publish node status update")
+	}
+
+	logger.WithFields(kv).Info("resumed parent node run after sub-workflow finished")
+}
@@ -4,30 +4,104 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/sirupsen/logrus"
-	"github.com/tinyautomator/tinyautomator-core/backend/clients/rabbitmq"
+	"github.com/tinyautomator/tinyautomator-core/backend/clients/redis"
 	"github.com/tinyautomator/tinyautomator-core/backend/models"
 )
 
 var ErrNodeRunAlreadyExists = errors.New("node run already exists")
 
+// NodeTypeCondition is the node type of conditions. A condition node evaluates an expression
+// and follows its true or false edges depending on the result.
+const NodeTypeCondition = "condition"
+
+// ConditionExpressionKey is the condition node config key holding its expression. The
+// expression is evaluated against the run's scope, it is not rendered like other config values.
+const ConditionExpressionKey = "expression"
+
+// Edge labels used by condition nodes to pick the branch to follow.
+const (
+	EdgeLabelTrue  = "true"
+	EdgeLabelFalse = "false"
+)
+
+// isEdgeTaken reports whether a successful parent's output selects the edge with the given label.
+// Unlabelled edges are always taken.
+func isEdgeTaken(label string, parentOutput map[string]any) bool {
+	switch label {
+	case EdgeLabelTrue, EdgeLabelFalse:
+		result, _ := parentOutput["result"].(bool)
+		return strconv.FormatBool(result) == label
+	default:
+		return true
+	}
+}
+
+// skipNodeRun marks a pending node run as skipped. Its children are resolved by the caller, they
+// may still run depending on how they join their parents.
+func skipNodeRun(
+	ctx context.Context,
+	logger logrus.FieldLogger,
+	workflowRunRepo models.WorkflowRunRepository,
+	redisClient redis.RedisClient,
+	workflowRunID int32,
+	nodeRun *models.LinkedWorkflowNodeRun,
+) error {
+	err := workflowRunRepo.UpdateWorkflowNodeRunStatus(ctx, nodeRun.ID, "skipped", nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to mark node run %d as skipped: %w", nodeRun.ID, err)
+	}
+
+	kv := logrus.Fields{
+		"workflow_run_id": workflowRunID,
+		"node_id":         nodeRun.WorkflowNodeID,
+		"node_run_id":     nodeRun.ID,
+		"iteration_index": nodeRun.IterationIndex,
+	}
+
+	logger.WithFields(kv).Info("skipped node run whose parents did not run")
+
+	// skipped nodes never execute so they have to leave the running set here for the run to
+	// finalize, loop iterations are tracked by their foreach node instead
+	if nodeRun.IterationIndex == models.NoIteration {
+		if _, err := redisClient.MarkNodeCompleteAndCountRemaining(
+			ctx,
+			workflowRunID,
+			nodeRun.WorkflowNodeID,
+			"skipped",
+		); err != nil {
+			logger.WithError(err).
+				WithFields(kv).
+				Warn("failed to remove skipped node from running node set")
+		}
+	}
+
+	if err := redisClient.PublishNodeStatusUpdate(ctx, workflowRunID, nodeRun.WorkflowNodeID, "skipped", nil); err != nil {
+		logger.WithError(err).WithFields(kv).Warn("failed to publish node status update")
+	}
+
+	return nil
+}
+
 type NodeToEnqueue struct {
-	NodeID    int32
-	NodeRunID int32
+	NodeID         int32
+	NodeRunID      int32
+	IterationIndex int32
 }
 
 func EnqueueNode(
 	ctx context.Context,
 	logger logrus.FieldLogger,
 	workflowRunRepo models.WorkflowRunRepository,
-	rabbitMQClient rabbitmq.RabbitMQClient,
 	userID string,
 	workflowID int32,
 	workflowRunID int32,
 	nodeID int32,
+	iterationIndex int32,
 ) error {
-	nodeRun, err := workflowRunRepo.GetWorkflowNodeRun(ctx, workflowRunID, nodeID)
+	nodeRun, err := workflowRunRepo.GetWorkflowNodeRun(ctx, workflowRunID, nodeID, iterationIndex)
 	if err != nil {
 		return fmt.Errorf("failed to get workflow node run: %w", err)
 	}
@@ -40,6 +114,7 @@ func EnqueueNode(
 			"node_id":         nodeRun.WorkflowNodeID,
 			"node_run_id":     nodeRun.ID,
 			"node_status":     nodeRun.Status,
+			"iteration_index": iterationIndex,
 		}).Info("node run already in progress, skipping enqueue")
 
 		return nil
@@ -51,14 +126,14 @@ func EnqueueNode(
 		workflowRunID,
 		nodeID,
 		nodeRun.ID,
+		iterationIndex,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to marshal task for child node %d: %w", nodeID, err)
 	}
 
-	err = rabbitMQClient.Publish(ctx, taskBytes)
-	if err != nil {
-		return fmt.Errorf("failed to publish message for child node %d: %w", nodeID, err)
+	if err := workflowRunRepo.AddOutboxTask(ctx, taskBytes, 0); err != nil {
+		return fmt.Errorf("failed to add task for child node %d to outbox: %w", nodeID, err)
 	}
 
 	logger.WithFields(logrus.Fields{
@@ -67,22 +142,33 @@ func EnqueueNode(
 		"workflow_run_id": workflowRunID,
 		"node_id":         nodeID,
 		"node_run_id":     nodeRun.ID,
+		"iteration_index": iterationIndex,
 	}).Info("successfully enqueued node")
 
 	return nil
 }
 
+// EnqueueChildNodes resolves the children of a finished node. Children whose join is satisfied
+// are enqueued, children that can no longer run are skipped along with their own descendants,
+// and the rest keep waiting on their other parents.
 func EnqueueChildNodes(
 	ctx context.Context,
 	logger logrus.FieldLogger,
 	workflowRunRepo models.WorkflowRunRepository,
-	rabbitMQClient rabbitmq.RabbitMQClient,
+	redisClient redis.RedisClient,
+	workflowGraph *models.WorkflowGraph,
 	userID string,
 	workflowID int32,
 	parentNodeID int32,
 	workflowRunID int32,
+	iterationIndex int32,
 ) error {
-	c, err := workflowRunRepo.GetChildWorkflowNodeRuns(ctx, workflowRunID, parentNodeID)
+	c, err := workflowRunRepo.GetChildWorkflowNodeRuns(
+		ctx,
+		workflowRunID,
+		parentNodeID,
+		iterationIndex,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to get child workflow node runs: %w", err)
 	}
@@ -120,9 +206,56 @@ func EnqueueChildNodes(
 			continue
 		}
 
+		parents, err := workflowRunRepo.GetParentWorkflowNodeRuns(
+			ctx,
+			workflowRunID,
+			nodeRun.WorkflowNodeID,
+			iterationIndex,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get parent workflow node runs: %w", err)
+		}
+
+		mode := JoinModeOf(workflowGraph, nodeRun.WorkflowNodeID)
+
+		switch Join(workflowGraph, mode, parents) {
+		case JoinWait:
+			logger.WithFields(logrus.Fields{
+				"workflow_run_id": workflowRunID,
+				"node_id":         nodeRun.WorkflowNodeID,
+				"join":            mode,
+			}).Info("child node waiting on other parents")
+
+			continue
+		case JoinSkip:
+			err := skipNodeRun(ctx, logger, workflowRunRepo, redisClient, workflowRunID, nodeRun)
+			if err != nil {
+				return fmt.Errorf("failed to skip child node %d: %w", nodeRun.WorkflowNodeID, err)
+			}
+
+			if err := EnqueueChildNodes(
+				ctx,
+				logger,
+				workflowRunRepo,
+				redisClient,
+				workflowGraph,
+				userID,
+				workflowID,
+				nodeRun.WorkflowNodeID,
+				workflowRunID,
+				iterationIndex,
+			); err != nil {
+				return err
+			}
+
+			continue
+		case JoinRun:
+		}
+
 		nodesNotAlreadyQueued = append(nodesNotAlreadyQueued, NodeToEnqueue{
-			NodeID:    nodeRun.WorkflowNodeID,
-			NodeRunID: nodeRun.ID,
+			NodeID:         nodeRun.WorkflowNodeID,
+			NodeRunID:      nodeRun.ID,
+			IterationIndex: nodeRun.IterationIndex,
 		})
 	}
 
@@ -133,14 +266,14 @@ func EnqueueChildNodes(
 			workflowRunID,
 			n.NodeID,
 			n.NodeRunID,
+			n.IterationIndex,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to marshal task for child node %d: %w", n.NodeID, err)
 		}
 
-		err = rabbitMQClient.Publish(ctx, taskBytes)
-		if err != nil {
-			return fmt.Errorf("failed to publish message for child node %d: %w", n.NodeID, err)
+		if err := workflowRunRepo.AddOutboxTask(ctx, taskBytes, 0); err != nil {
+			return fmt.Errorf("failed to add task for child node %d to outbox: %w", n.NodeID, err)
 		}
 
 		logger.WithFields(logrus.Fields{
@@ -149,6 +282,7 @@ func EnqueueChildNodes(
 			"workflow_run_id": workflowRunID,
 			"node_id":         n.NodeID,
 			"node_run_id":     n.NodeRunID,
+			"iteration_index": n.IterationIndex,
 		}).Info("successfully enqueued child node")
 	}
 
@@ -28,6 +28,28 @@ type EnvironmentVariables struct {
 	// Redis
 	RedisUrl string `envconfig:"REDIS_URL"`
 
+	// Per-user limits, a quota of 0 is unlimited
+	RunRateLimitPerMinute float64 `envconfig:"RUN_RATE_LIMIT_PER_MINUTE" default:"30"`
+	RunRateLimitBurst     int     `envconfig:"RUN_RATE_LIMIT_BURST"      default:"10"`
+	MonthlyRunQuota       int64   `envconfig:"MONTHLY_RUN_QUOTA"         default:"10000"`
+	MonthlyActionQuota    int64   `envconfig:"MONTHLY_ACTION_QUOTA"      default:"50000"`
+
+	// Run reaper, runs without any node progress for RunStallTimeout time out
+	RunReaperInterval time.Duration `envconfig:"RUN_REAPER_INTERVAL" default:"1m"`
+	RunStallTimeout   time.Duration `envconfig:"RUN_STALL_TIMEOUT"   default:"30m"`
+
+	// Node run reconciler, pending node runs idle for NodeRunIdleThreshold get their task again
+	NodeRunReconcileInterval time.Duration `envconfig:"NODE_RUN_RECONCILE_INTERVAL" default:"1m"`
+	NodeRunIdleThreshold     time.Duration `envconfig:"NODE_RUN_IDLE_THRESHOLD"     default:"5m"`
+
+	// Outbox relay, sent tasks are kept for OutboxRetention before they are deleted
+	OutboxRelayInterval time.Duration `envconfig:"OUTBOX_RELAY_INTERVAL" default:"200ms"`
+	OutboxPurgeInterval time.Duration `envconfig:"OUTBOX_PURGE_INTERVAL" default:"1h"`
+	OutboxRetention     time.Duration `envconfig:"OUTBOX_RETENTION"      default:"24h"`
+
+	// Serves the scheduler's expvar metrics on /debug/vars when set
+	SchedulerMetricsPort string `envconfig:"SCHEDULER_METRICS_PORT"`
+
 	// Database
 	PostgresUrl string `envconfig:"POSTGRES_URL"`
 
@@ -62,6 +84,9 @@ type AppConfig interface {
 	GetRedisClient() redis.RedisClient
 	GetRabbitMQClient() rabbitmq.RabbitMQClient
 	GetWorkflowCalendarService() WorkflowCalendarService
+	GetRunReaperService() RunReaperService
+	GetNodeRunReconcilerService() NodeRunReconcilerService
+	GetOutboxRelayService() OutboxRelayService
 	CleanUp()
 }
 
@@ -85,8 +110,31 @@ type WorkflowRepository interface {
 		existingNodes []*WorkflowNodeDTO,
 	) error
 	GetWorkflowGraph(ctx context.Context, workflowID int32) (*WorkflowGraph, error)
+	// GetLatestWorkflowVersion returns the version created when the workflow was last saved.
+	GetLatestWorkflowVersion(ctx context.Context, workflowID int32) (*WorkflowVersion, error)
+	GetWorkflowVersion(ctx context.Context, id int32) (*WorkflowVersion, error)
+	// GetWorkflowRunGraph returns the graph of the version the run is pinned to.
+	GetWorkflowRunGraph(ctx context.Context, runID int32) (*WorkflowGraph, error)
 	RenderWorkflowGraph(ctx context.Context, workflowID int32) (*WorkflowGraphDTO, error)
 	ArchiveWorkflow(ctx context.Context, workflowID int32) error
+	// GetWorkflowConcurrency returns the workflow's concurrency settings, or the defaults when
+	// none were saved.
+	GetWorkflowConcurrency(ctx context.Context, workflowID int32) (*WorkflowConcurrency, error)
+	UpdateWorkflowConcurrency(
+		ctx context.Context,
+		concurrency *WorkflowConcurrency,
+	) (*WorkflowConcurrency, error)
+	// GetWorkflowTimeout returns the workflow's timeout settings, or the defaults when none were
+	// saved.
+	GetWorkflowTimeout(ctx context.Context, workflowID int32) (*WorkflowTimeout, error)
+	UpdateWorkflowTimeout(ctx context.Context, timeout *WorkflowTimeout) (*WorkflowTimeout, error)
+	// GetWorkflowInputSchema returns the inputs the workflow declares, none when it never
+	// declared any.
+	GetWorkflowInputSchema(ctx context.Context, workflowID int32) (*WorkflowInputSchema, error)
+	UpdateWorkflowInputSchema(
+		ctx context.Context,
+		schema *WorkflowInputSchema,
+	) (*WorkflowInputSchema, error)
 }
 
 type WorkflowRunRepository interface {
@@ -95,34 +143,162 @@ type WorkflowRunRepository interface {
 		fn func(ctx context.Context, txRepo WorkflowRunRepository) error,
 	) error
 	GetWorkflowRun(ctx context.Context, id int32) (*WorkflowRunWithNodesDTO, error)
-	GetWorkflowRuns(ctx context.Context, workflowID int32) ([]*WorkflowRunCore, error)
-	GetUserWorkflowRuns(ctx context.Context, userID string) ([]*UserWorkflowRunDTO, error)
+	GetWorkflowRunCore(ctx context.Context, id int32) (*WorkflowRunCore, error)
+	GetWorkflowRuns(ctx context.Context, workflowID int32, dryRun bool) ([]*WorkflowRunCore, error)
+	GetUserWorkflowRuns(
+		ctx context.Context,
+		userID string,
+		dryRun bool,
+	) ([]*UserWorkflowRunDTO, error)
 	GetWorkflowNodeRun(
 		ctx context.Context,
 		workflowRunID int32,
 		nodeID int32,
+		iterationIndex int32,
 	) (*WorkflowNodeRunCore, error)
 	GetWorkflowNodeRuns(
 		ctx context.Context,
 		workflowRunID int32,
 		status *string,
 	) ([]*WorkflowNodeRunCore, error)
+	// GetParentWorkflowNodeRuns returns the parents in the same loop iteration as well as the
+	// parents outside of any loop. Parents and children follow the edges of the run's workflow
+	// version, not the workflow as it is now.
 	GetParentWorkflowNodeRuns(
 		ctx context.Context,
 		workflowRunID int32,
 		nodeID int32,
-	) ([]*WorkflowNodeRunCore, error)
+		iterationIndex int32,
+	) ([]*LinkedWorkflowNodeRun, error)
+	// GetChildWorkflowNodeRuns returns the children in the given loop iteration.
 	GetChildWorkflowNodeRuns(
 		ctx context.Context,
 		workflowRunID int32,
 		nodeID int32,
+		iterationIndex int32,
+	) ([]*LinkedWorkflowNodeRun, error)
+	// GetLoopIterationNodeRuns returns the node runs of every started iteration of a loop body.
+	GetLoopIterationNodeRuns(
+		ctx context.Context,
+		workflowRunID int32,
+		nodeIDs []int32,
+	) ([]*WorkflowNodeRunCore, error)
+	// CreateLoopIteration creates pending node runs for one iteration of a loop body. It returns
+	// nil when the iteration was already created.
+	CreateLoopIteration(
+		ctx context.Context,
+		workflowRunID int32,
+		iterationIndex int32,
+		nodeIDs []int32,
 	) ([]*WorkflowNodeRunCore, error)
+	GetWorkflowNodeRunByID(
+		ctx context.Context,
+		workflowNodeRunID int32,
+	) (*WorkflowNodeRunCore, error)
+	// MarkWorkflowNodeAsWaiting parks a node run until a delayed task resumes it.
+	MarkWorkflowNodeAsWaiting(
+		ctx context.Context,
+		workflowNodeRunID int32,
+		metadata map[string]any,
+	) error
+	SetWorkflowNodeRunMetadata(
+		ctx context.Context,
+		workflowNodeRunID int32,
+		metadata map[string]any,
+	) error
+	// SetWorkflowNodeRunRetryCount records how many attempts the node run used, setting it to
+	// the maximum keeps the node from being retried.
+	SetWorkflowNodeRunRetryCount(
+		ctx context.Context,
+		workflowNodeRunID int32,
+		retryCount int32,
+	) error
+	// CompleteLoopWorkflowNodeRun finishes a running foreach node run. It reports false when the
+	// node run was no longer running.
+	CompleteLoopWorkflowNodeRun(
+		ctx context.Context,
+		workflowNodeRunID int32,
+		status string,
+		metadata map[string]any,
+	) (bool, error)
+	// CreateWorkflowRun creates a run in the given status, running or queued, with a pending node
+	// run per node.
 	CreateWorkflowRun(
 		ctx context.Context,
 		workflowID int32,
 		nodes []ValidateNode,
+		opts WorkflowRunOptions,
+		status string,
 	) (*WorkflowRunWithNodesDTO, error)
+	// CreateSkippedWorkflowRun records a run that was never started, with the reason why.
+	CreateSkippedWorkflowRun(
+		ctx context.Context,
+		workflowID int32,
+		opts WorkflowRunOptions,
+		reason string,
+	) (*WorkflowRunCore, error)
+	// LockWorkflowRuns serializes starting runs of a workflow until the transaction ends.
+	LockWorkflowRuns(ctx context.Context, workflowID int32) error
+	// GetActiveWorkflowRuns returns the running and queued runs of a workflow, oldest first.
+	// Dry runs are left out.
+	GetActiveWorkflowRuns(ctx context.Context, workflowID int32) ([]*WorkflowRunCore, error)
+	StartQueuedWorkflowRun(ctx context.Context, workflowRunID int32) (bool, error)
 	CompleteWorkflowRun(ctx context.Context, workflowRunID int32, status string) error
+	// CancelWorkflowRun cancels a running or queued run and its pending and waiting node runs. It
+	// reports false when the run was no longer running or queued.
+	CancelWorkflowRun(ctx context.Context, workflowRunID int32) (bool, error)
+	// AddOutboxTask stores a node task to publish once the transaction commits, delivered after
+	// delay. Tasks are only ever published through the outbox relay.
+	AddOutboxTask(ctx context.Context, payload []byte, delay time.Duration) error
+	// ClaimOutboxTasks locks up to limit unsent outbox tasks, oldest first, until the transaction
+	// ends. Tasks locked by another relay are left out.
+	ClaimOutboxTasks(ctx context.Context, limit int32) ([]*OutboxTask, error)
+	MarkOutboxTaskSent(ctx context.Context, id int64) error
+	// MarkOutboxTaskFailed records a failed publish, the task is claimed again from retryAt on.
+	MarkOutboxTaskFailed(
+		ctx context.Context,
+		id int64,
+		errorMessage string,
+		retryAt time.Time,
+	) error
+	// MarkOutboxTaskDead records the last failed publish of a task that is given up on.
+	MarkOutboxTaskDead(ctx context.Context, id int64, errorMessage string) error
+	// DeleteSentOutboxTasks deletes the tasks sent before the given time and returns how many.
+	DeleteSentOutboxTasks(ctx context.Context, sentBefore time.Time) (int64, error)
+	// GetSideEffect returns the output recorded for the side effect with the idempotency key, or
+	// nil when none was recorded.
+	GetSideEffect(ctx context.Context, idempotencyKey string) (map[string]any, error)
+	// RecordSideEffect stores the output of a completed side effect under its idempotency key.
+	// Recording a key again keeps the first output.
+	RecordSideEffect(
+		ctx context.Context,
+		workflowRunID int32,
+		idempotencyKey string,
+		output map[string]any,
+	) error
+	// GetIdlePendingWorkflowNodeRuns returns up to limit pending node runs of running runs whose
+	// parents all settled before idleBefore, leaving out those with a task still in the outbox.
+	GetIdlePendingWorkflowNodeRuns(
+		ctx context.Context,
+		idleBefore time.Time,
+		limit int32,
+	) ([]*IdleNodeRun, error)
+	SetWorkflowRunDeadline(ctx context.Context, workflowRunID int32, deadline time.Time) error
+	// GetStuckWorkflowRuns returns up to limit running runs that are past their deadline at now,
+	// or whose last progress was before stalledBefore. Runs waiting on a node are not stalled.
+	GetStuckWorkflowRuns(
+		ctx context.Context,
+		now time.Time,
+		stalledBefore time.Time,
+		limit int32,
+	) ([]*StuckWorkflowRun, error)
+	// TimeOutWorkflowRun marks a running run as timed out with the reason why. Its running node
+	// runs fail with the same reason, its pending and waiting node runs are cancelled. It reports
+	// false when the run was no longer running.
+	TimeOutWorkflowRun(ctx context.Context, workflowRunID int32, reason string) (bool, error)
+	// ResumeWorkflowRun puts a failed run back to running and resets the given failed or skipped
+	// node runs to pending. It reports false when the run had not failed.
+	ResumeWorkflowRun(ctx context.Context, workflowRunID int32, nodeRunIDs []int32) (bool, error)
 	MarkWorkflowNodeAsRunning(
 		ctx context.Context,
 		workflowNodeRunID int32,
@@ -133,6 +309,7 @@ type WorkflowRunRepository interface {
 		ctx context.Context,
 		workflowNodeRunID int32,
 		status string,
+		metadata map[string]any,
 		errorMessage *string,
 	) error
 }
@@ -197,11 +374,28 @@ type WorkflowCalendarRepository interface {
 }
 
 type OrchestratorService interface {
-	OrchestrateWorkflow(ctx context.Context, userID string, workflowID int32) (int32, error)
+	OrchestrateWorkflow(
+		ctx context.Context,
+		userID string,
+		workflowID int32,
+		opts WorkflowRunOptions,
+	) (int32, error)
+	// StartQueuedWorkflowRuns starts queued runs of the workflow as far as its concurrency limit
+	// allows.
+	StartQueuedWorkflowRuns(ctx context.Context, workflowID int32) error
 }
 
 type ExecutorService interface {
 	ExecuteWorkflowNode(ctx context.Context, msg []byte) error
+	// ExecuteSingleNode runs one action node outside of a workflow run, using parentOutputs in
+	// place of the outputs of its upstream nodes.
+	ExecuteSingleNode(
+		ctx context.Context,
+		userID string,
+		workflowID int32,
+		nodeID int32,
+		parentOutputs map[int32]map[string]any,
+	) (*NodeExecutionResult, error)
 }
 
 type ScheduleType string
@@ -283,6 +477,44 @@ type WorkflowService interface {
 		edges []*WorkflowEdgeDTO,
 	) error
 	ArchiveWorkflow(ctx context.Context, workflowID int32) error
+	GetWorkflowConcurrency(ctx context.Context, workflowID int32) (*WorkflowConcurrency, error)
+	UpdateWorkflowConcurrency(
+		ctx context.Context,
+		concurrency *WorkflowConcurrency,
+	) (*WorkflowConcurrency, error)
+	GetWorkflowTimeout(ctx context.Context, workflowID int32) (*WorkflowTimeout, error)
+	UpdateWorkflowTimeout(ctx context.Context, timeout *WorkflowTimeout) (*WorkflowTimeout, error)
+	GetNodeTypes(ctx context.Context, userID string) ([]*NodeTypeSpec, error)
+	GetWorkflowInputSchema(ctx context.Context, workflowID int32) (*WorkflowInputSchema, error)
+	UpdateWorkflowInputSchema(
+		ctx context.Context,
+		schema *WorkflowInputSchema,
+	) (*WorkflowInputSchema, error)
+}
+
+// RunReaperService times out runs that are past their deadline or stopped making progress, e.g.
+// after a lost message or a worker crash.
+type RunReaperService interface {
+	// ReapStuckWorkflowRuns times out the stuck runs and returns how many it timed out.
+	ReapStuckWorkflowRuns(ctx context.Context) (int, error)
+}
+
+// NodeRunReconcilerService republishes the tasks of node runs that are ready to run but were
+// never executed, e.g. because publishing their task failed.
+type NodeRunReconcilerService interface {
+	// ReconcilePendingNodeRuns republishes the tasks of idle pending node runs and returns how
+	// many it republished.
+	ReconcilePendingNodeRuns(ctx context.Context) (int, error)
+}
+
+// OutboxRelayService publishes the node tasks written to the outbox along with the run state
+// they belong to, so no task is lost when the broker is down.
+type OutboxRelayService interface {
+	// RelayOutboxTasks publishes the unsent outbox tasks and returns how many it published.
+	RelayOutboxTasks(ctx context.Context) (int, error)
+	// PurgeSentOutboxTasks deletes the tasks sent longer ago than the retention and returns how
+	// many it deleted.
+	PurgeSentOutboxTasks(ctx context.Context) (int64, error)
 }
 
 type AccountService interface {
@@ -0,0 +1,25 @@
+package models
+
+// NodeTypeSpec describes a node type the backend can run. The editor builds its block palette
+// and the node config forms from these.
+type NodeTypeSpec struct {
+	NodeType    string `json:"node_type"`
+	Category    string `json:"category"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	// ConfigSchema is the JSON Schema of the node config.
+	ConfigSchema map[string]any `json:"config_schema"`
+	// OutputSchema is the JSON Schema of the output downstream nodes can reference, nil for nodes
+	// that produce none.
+	OutputSchema map[string]any `json:"output_schema"`
+	// OAuthProvider is the integration the node acts through, empty when it needs none.
+	OAuthProvider string   `json:"oauth_provider,omitempty"`
+	OAuthScopes   []string `json:"oauth_scopes"`
+	// Available is false when the user is missing the integration or scopes the node needs.
+	Available bool `json:"available"`
+}
+
+// RequiresOAuth reports whether the node type acts through an integration of the user.
+func (s *NodeTypeSpec) RequiresOAuth() bool {
+	return s.OAuthProvider != ""
+}
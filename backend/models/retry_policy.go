@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// RetryPolicyConfigKey is the node config key holding a node's RetryPolicy.
+const RetryPolicyConfigKey = "retry_policy"
+
+// MaxRetryDelayMs caps max_delay_ms, 15 minutes. Runs waiting on a retry must not look stalled
+// to the run reaper.
+const MaxRetryDelayMs = 15 * 60 * 1000
+
+// RetryPolicy controls how often a failed node is attempted and how long to wait between attempts.
+type RetryPolicy struct {
+	MaxAttempts       int32   `json:"max_attempts"`
+	InitialDelayMs    int64   `json:"initial_delay_ms"`
+	BackoffMultiplier float64 `json:"backoff_multiplier"`
+	MaxDelayMs        int64   `json:"max_delay_ms"`
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       3,
+		InitialDelayMs:    2000, // 2 seconds
+		BackoffMultiplier: 2,
+		MaxDelayMs:        120000, // 2 minutes
+	}
+}
+
+// ParseRetryPolicy reads the retry policy from a node config. Fields that are not set fall back
+// to DefaultRetryPolicy.
+func ParseRetryPolicy(config map[string]any) (RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+
+	raw, ok := config[RetryPolicyConfigKey]
+	if !ok || raw == nil {
+		return policy, nil
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return policy, fmt.Errorf("failed to marshal retry policy: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &policy); err != nil {
+		return DefaultRetryPolicy(), fmt.Errorf("invalid retry policy: %w", err)
+	}
+
+	if err := policy.Validate(); err != nil {
+		return DefaultRetryPolicy(), err
+	}
+
+	return policy, nil
+}
+
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 || p.MaxAttempts > 25 {
+		return fmt.Errorf("invalid retry policy: max_attempts must be between 1 and 25")
+	}
+
+	if p.InitialDelayMs < 0 || p.MaxDelayMs < 0 {
+		return fmt.Errorf("invalid retry policy: delays must not be negative")
+	}
+
+	if p.MaxDelayMs > MaxRetryDelayMs {
+		return fmt.Errorf("invalid retry policy: max_delay_ms must be at most %d", MaxRetryDelayMs)
+	}
+
+	if p.BackoffMultiplier < 1 {
+		return fmt.Errorf("invalid retry policy: backoff_multiplier must be at least 1")
+	}
+
+	return nil
+}
+
+// Delay returns how long to wait before the next attempt after the given number of attempts.
+func (p RetryPolicy) Delay(attempts int32) time.Duration {
+	delay := float64(p.InitialDelayMs) * math.Pow(p.BackoffMultiplier, float64(max(attempts-1, 0)))
+	if delay > float64(p.MaxDelayMs) {
+		delay = float64(p.MaxDelayMs)
+	}
+
+	return time.Duration(delay) * time.Millisecond
+}
@@ -49,12 +49,76 @@ type WorkflowEdge struct {
 	WorkflowID   int32
 	SourceNodeID int32
 	TargetNodeID int32
+	Label        string
 }
 
 type WorkflowEdgeDTO struct {
 	ID           string `json:"id"`
 	SourceNodeID string `json:"source_node_id"`
 	TargetNodeID string `json:"target_node_id"`
+	Label        string `json:"label,omitempty"`
+}
+
+const (
+	// OverlapPolicyAllow starts every run, MaxConcurrentRuns is not enforced.
+	OverlapPolicyAllow = "allow"
+	// OverlapPolicySkip records the run as skipped when the limit is reached.
+	OverlapPolicySkip = "skip"
+	// OverlapPolicyQueue holds the run as queued until an earlier run finishes.
+	OverlapPolicyQueue = "queue"
+	// OverlapPolicyCancelPrevious cancels the oldest runs to make room for the new one.
+	OverlapPolicyCancelPrevious = "cancel_previous"
+)
+
+var OverlapPolicies = []string{
+	OverlapPolicyAllow,
+	OverlapPolicySkip,
+	OverlapPolicyQueue,
+	OverlapPolicyCancelPrevious,
+}
+
+// WorkflowConcurrency limits how many runs of a workflow are in progress at once and what
+// happens to a run started beyond the limit. Dry runs are not limited.
+type WorkflowConcurrency struct {
+	WorkflowID        int32  `json:"workflow_id"`
+	MaxConcurrentRuns int32  `json:"max_concurrent_runs"`
+	OverlapPolicy     string `json:"overlap_policy"`
+}
+
+// DefaultWorkflowConcurrency is used by workflows that have no concurrency settings.
+func DefaultWorkflowConcurrency(workflowID int32) *WorkflowConcurrency {
+	return &WorkflowConcurrency{
+		WorkflowID:        workflowID,
+		MaxConcurrentRuns: 1,
+		OverlapPolicy:     OverlapPolicyAllow,
+	}
+}
+
+// DefaultRunTimeout is how long runs of workflows without timeout settings may take.
+const DefaultRunTimeout = 24 * time.Hour
+
+// MaxNodeTimeout caps the timeout_seconds of action nodes. A run makes no progress while a node
+// runs, so it has to stay below the run reaper's RunStallTimeout.
+const MaxNodeTimeout = 15 * time.Minute
+
+// WorkflowTimeout bounds how long a run of a workflow may take once it started. Runs still going
+// past the deadline are marked timed_out by the run reaper.
+type WorkflowTimeout struct {
+	WorkflowID        int32 `json:"workflow_id"`
+	RunTimeoutSeconds int32 `json:"run_timeout_seconds"`
+}
+
+// DefaultWorkflowTimeout is used by workflows that have no timeout settings.
+func DefaultWorkflowTimeout(workflowID int32) *WorkflowTimeout {
+	return &WorkflowTimeout{
+		WorkflowID:        workflowID,
+		RunTimeoutSeconds: int32(DefaultRunTimeout / time.Second),
+	}
+}
+
+// RunTimeout returns the timeout as a duration.
+func (t *WorkflowTimeout) RunTimeout() time.Duration {
+	return time.Duration(t.RunTimeoutSeconds) * time.Second
 }
 
 type WorkflowGraph struct {
@@ -63,6 +127,27 @@ type WorkflowGraph struct {
 	Edges []*WorkflowEdge
 }
 
+// Node returns the node of the graph with the given ID, nil if the graph has none.
+func (g *WorkflowGraph) Node(id int32) *WorkflowNode {
+	for _, node := range g.Nodes {
+		if node.ID == id {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// WorkflowVersion is a graph of a workflow as it was saved. Versions are never changed, runs are
+// pinned to the version that was current when they were created and execute its node configs.
+type WorkflowVersion struct {
+	ID         int32          `json:"id"`
+	WorkflowID int32          `json:"workflow_id"`
+	Version    int32          `json:"version"`
+	Graph      *WorkflowGraph `json:"-"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
 type WorkflowGraphDTO struct {
 	ID          int32              `json:"id"`
 	Name        string             `json:"name"`
@@ -131,11 +216,92 @@ type WorkflowCalendar struct {
 	LastSyncedAt   time.Time              `json:"last_synced_at"`
 }
 type WorkflowRunCore struct {
-	ID         int32     `json:"id"`
-	WorkflowID int32     `json:"workflow_id"`
-	Status     string    `json:"status"`
-	FinishedAt null.Time `json:"finished_at"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID              int32          `json:"id"`
+	WorkflowID      int32          `json:"workflow_id"`
+	Status          string         `json:"status"`
+	FinishedAt      null.Time      `json:"finished_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+	DryRun          bool           `json:"dry_run"`
+	ParentRunID     null.Int32     `json:"parent_run_id"`
+	ParentNodeRunID null.Int32     `json:"parent_node_run_id"`
+	Depth           int32          `json:"depth"`
+	Inputs          map[string]any `json:"inputs"`
+	SkipReason      null.String    `json:"skip_reason"`
+	// DeadlineAt is when the run times out, set once it starts.
+	DeadlineAt null.Time `json:"deadline_at"`
+	// ErrorMessage records why the run failed or timed out.
+	ErrorMessage null.String `json:"error_message"`
+	// TriggerSource and TriggerPayload record what started the run, see WorkflowRunOptions.
+	TriggerSource  string         `json:"trigger_source"`
+	TriggerPayload map[string]any `json:"trigger_payload"`
+	// WorkflowVersionID is the version of the workflow the run executes.
+	WorkflowVersionID int32 `json:"workflow_version_id"`
+}
+
+// OutboxTask is a node task waiting in the outbox to be published to the queue.
+type OutboxTask struct {
+	ID      int64
+	Payload []byte
+	// Delay is how long after CreatedAt the task should be delivered.
+	Delay     time.Duration
+	CreatedAt time.Time
+	Attempts  int32
+}
+
+// IdleNodeRun is a pending node run whose parents settled a while ago without it being
+// executed, most likely because its task message was lost.
+type IdleNodeRun struct {
+	ID             int32
+	WorkflowRunID  int32
+	WorkflowID     int32
+	UserID         string
+	WorkflowNodeID int32
+	IterationIndex int32
+}
+
+// StuckWorkflowRun is a running run the reaper found past its deadline or without progress.
+type StuckWorkflowRun struct {
+	ID             int32
+	WorkflowID     int32
+	UserID         string
+	DeadlineAt     null.Time
+	LastProgressAt time.Time
+}
+
+// Trigger sources recorded on a run, telling what started it.
+const (
+	TriggerSourceManual   = "manual"
+	TriggerSourceSchedule = "schedule"
+	TriggerSourceCalendar = "calendar"
+	// TriggerSourceWorkflow marks runs started by a run_workflow node of another run.
+	TriggerSourceWorkflow = "workflow"
+)
+
+// WorkflowRunOptions describes how a workflow run is started.
+type WorkflowRunOptions struct {
+	// DryRun runs every node through its handler's Simulate step instead of Execute, so no
+	// side effects happen. Dry runs are listed separately from real runs.
+	DryRun bool `json:"dry_run"`
+	// Inputs are the parameters the run starts with, node configs reference them as
+	// {{inputs.<name>}}. They are checked against the workflow's WorkflowInputSchema.
+	Inputs map[string]any `json:"inputs"`
+	// ParentRunID and ParentNodeRunID link a run started by a run_workflow node to the node run
+	// that started it, Depth counts how deeply such runs are nested.
+	ParentRunID     null.Int32 `json:"-"`
+	ParentNodeRunID null.Int32 `json:"-"`
+	Depth           int32      `json:"-"`
+	// Manual is set on runs a user starts through the API. When they hit a usage limit they
+	// are rejected, while runs started by triggers are recorded as skipped.
+	Manual bool `json:"-"`
+	// TriggerSource is what started the run, one of the TriggerSource constants. It is derived
+	// for manual runs and runs started by another run, triggers set their own.
+	TriggerSource string `json:"-"`
+	// TriggerPayload is the data the trigger fired with, e.g. the calendar event. Node configs
+	// reference it as {{trigger.payload.<key>}}.
+	TriggerPayload map[string]any `json:"-"`
+	// WorkflowVersionID pins the run to a version of the workflow, OrchestrateWorkflow sets it
+	// to the latest version.
+	WorkflowVersionID int32 `json:"-"`
 }
 
 type UserWorkflowRunDTO struct {
@@ -145,6 +311,9 @@ type UserWorkflowRunDTO struct {
 	Status        string    `json:"status"`
 	CreatedAt     time.Time `json:"created_at"`
 	FinishedAt    null.Time `json:"finished_at"`
+	DryRun        bool      `json:"dry_run"`
+	// Inputs are the parameters the run was started with.
+	Inputs map[string]any `json:"inputs"`
 }
 
 type WorkflowRunWithNodesDTO struct {
@@ -152,16 +321,37 @@ type WorkflowRunWithNodesDTO struct {
 	Nodes []*WorkflowNodeRunCore `json:"nodes"`
 }
 
+// NoIteration is the iteration index of node runs that are not part of a foreach loop.
+const NoIteration int32 = -1
+
 type WorkflowNodeRunCore struct {
-	ID             int32       `json:"id"`
-	WorkflowRunID  int32       `json:"workflow_run_id"`
-	WorkflowNodeID int32       `json:"workflow_node_id"`
-	Status         string      `json:"status"`
-	RetryCount     int32       `json:"retry_count"`
-	StartedAt      null.Time   `json:"started_at"`
-	FinishedAt     null.Time   `json:"finished_at"`
-	Metadata       null.String `json:"metadata"`
-	ErrorMessage   null.String `json:"error_message"`
+	ID             int32          `json:"id"`
+	WorkflowRunID  int32          `json:"workflow_run_id"`
+	WorkflowNodeID int32          `json:"workflow_node_id"`
+	Status         string         `json:"status"`
+	RetryCount     int32          `json:"retry_count"`
+	StartedAt      null.Time      `json:"started_at"`
+	FinishedAt     null.Time      `json:"finished_at"`
+	Metadata       map[string]any `json:"metadata"`
+	ErrorMessage   null.String    `json:"error_message"`
+	IterationIndex int32          `json:"iteration_index"`
+}
+
+// NodeExecutionResult is the outcome of executing a single node outside of a workflow run.
+type NodeExecutionResult struct {
+	NodeID     int32          `json:"node_id"`
+	NodeType   string         `json:"node_type"`
+	Status     string         `json:"status"`
+	Output     map[string]any `json:"output"`
+	Error      null.String    `json:"error"`
+	DurationMs int64          `json:"duration_ms"`
+}
+
+// LinkedWorkflowNodeRun is a parent or child node run together with the label of the edge
+// that connects it to the node it was looked up from.
+type LinkedWorkflowNodeRun struct {
+	WorkflowNodeRunCore
+	EdgeLabel null.String `json:"edge_label"`
 }
 
 type ValidateNode struct {
@@ -173,28 +363,43 @@ type ValidateNode struct {
 type ValidateEdge struct {
 	SourceNodeID string `json:"source_node_id"`
 	TargetNodeID string `json:"target_node_id"`
+	Label        string `json:"label"`
 }
 
 type WorkflowNodeTask struct {
-	UserID     string `json:"user_id"`
-	WorkflowID int32  `json:"workflow_id"`
-	RunID      int32  `json:"run_id"`
-	NodeID     int32  `json:"node_id"`
-	NodeRunID  int32  `json:"node_run_id"`
-	RetryCount int32  `json:"retry_count,omitempty"`
-	Status     string `json:"status,omitempty"`
+	UserID         string `json:"user_id"`
+	WorkflowID     int32  `json:"workflow_id"`
+	RunID          int32  `json:"run_id"`
+	NodeID         int32  `json:"node_id"`
+	NodeRunID      int32  `json:"node_run_id"`
+	IterationIndex int32  `json:"iteration_index"`
+	RetryCount     int32  `json:"retry_count,omitempty"`
+	Status         string `json:"status,omitempty"`
+}
+
+// IdempotencyKey identifies the current attempt of the task's node run. A redelivered task that
+// resumes the attempt gets the same key, a retry gets a new one.
+func (t *WorkflowNodeTask) IdempotencyKey() string {
+	return fmt.Sprintf(
+		"run%d.node%d.iteration%d.attempt%d",
+		t.RunID,
+		t.NodeID,
+		t.IterationIndex,
+		t.RetryCount,
+	)
 }
 
 func BuildWorkflowNodeTaskPayload(
 	userID string,
-	workflowID, runID, nodeID, nodeRunID int32,
+	workflowID, runID, nodeID, nodeRunID, iterationIndex int32,
 ) ([]byte, error) {
 	task := WorkflowNodeTask{
-		UserID:     userID,
-		WorkflowID: workflowID,
-		RunID:      runID,
-		NodeID:     nodeID,
-		NodeRunID:  nodeRunID,
+		UserID:         userID,
+		WorkflowID:     workflowID,
+		RunID:          runID,
+		NodeID:         nodeID,
+		NodeRunID:      nodeRunID,
+		IterationIndex: iterationIndex,
 	}
 
 	data, err := json.Marshal(task)
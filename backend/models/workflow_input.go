@@ -0,0 +1,213 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// MaxWorkflowInputs caps how many inputs a workflow may declare.
+const MaxWorkflowInputs = 50
+
+// Types a workflow input may have.
+const (
+	InputTypeString  = "string"
+	InputTypeNumber  = "number"
+	InputTypeBoolean = "boolean"
+	InputTypeEmail   = "email"
+	// InputTypeDate takes a date such as 2025-01-31 or an RFC3339 timestamp.
+	InputTypeDate = "date"
+)
+
+// inputNamePattern keeps input names referenceable as {{inputs.<name>}}.
+var inputNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,63}$`)
+
+// WorkflowInput declares a parameter runs of a workflow take.
+type WorkflowInput struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+	// Default is used when a run does not set the input.
+	Default any `json:"default"`
+}
+
+// WorkflowInputSchema is the inputs a workflow declares, in the order the run form shows them.
+type WorkflowInputSchema struct {
+	WorkflowID int32            `json:"workflow_id"`
+	Inputs     []*WorkflowInput `json:"inputs"`
+}
+
+// DefaultWorkflowInputSchema is used by workflows that declare no inputs.
+func DefaultWorkflowInputSchema(workflowID int32) *WorkflowInputSchema {
+	return &WorkflowInputSchema{
+		WorkflowID: workflowID,
+		Inputs:     []*WorkflowInput{},
+	}
+}
+
+func (s *WorkflowInputSchema) Validate() error {
+	if len(s.Inputs) > MaxWorkflowInputs {
+		return fmt.Errorf("a workflow may declare at most %d inputs", MaxWorkflowInputs)
+	}
+
+	seen := make(map[string]bool, len(s.Inputs))
+
+	for _, input := range s.Inputs {
+		if input == nil {
+			return fmt.Errorf("inputs must not be null")
+		}
+
+		if !inputNamePattern.MatchString(input.Name) {
+			return fmt.Errorf(
+				"invalid input name %q: must be a letter or underscore followed by up to 63 "+
+					"letters, digits or underscores",
+				input.Name,
+			)
+		}
+
+		if seen[input.Name] {
+			return fmt.Errorf("duplicate input %q", input.Name)
+		}
+
+		seen[input.Name] = true
+
+		switch input.Type {
+		case InputTypeString, InputTypeNumber, InputTypeBoolean, InputTypeEmail, InputTypeDate:
+		default:
+			return fmt.Errorf("invalid type %q of input %q", input.Type, input.Name)
+		}
+
+		if !input.isEmpty(input.Default) {
+			if _, err := input.Coerce(input.Default); err != nil {
+				return fmt.Errorf("invalid default of input %q: %w", input.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Resolve checks the values a run is started with against the schema and fills in defaults. It
+// returns the run's inputs, or why each invalid value was rejected keyed by input name.
+func (s *WorkflowInputSchema) Resolve(values map[string]any) (map[string]any, map[string]string) {
+	resolved := make(map[string]any, len(s.Inputs))
+	errs := make(map[string]string)
+	declared := make(map[string]bool, len(s.Inputs))
+
+	for _, input := range s.Inputs {
+		declared[input.Name] = true
+
+		value, ok := values[input.Name]
+		if !ok || input.isEmpty(value) {
+			value = input.Default
+		}
+
+		if input.isEmpty(value) {
+			if input.Required {
+				errs[input.Name] = "is required"
+			}
+
+			continue
+		}
+
+		coerced, err := input.Coerce(value)
+		if err != nil {
+			errs[input.Name] = err.Error()
+			continue
+		}
+
+		resolved[input.Name] = coerced
+	}
+
+	for name := range values {
+		if !declared[name] {
+			errs[name] = "is not an input of the workflow"
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return resolved, nil
+}
+
+// isEmpty reports whether value leaves the input unset. An empty string sets no string, email
+// or date, forms send one for a field left blank.
+func (i *WorkflowInput) isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+
+	switch i.Type {
+	case InputTypeString, InputTypeEmail, InputTypeDate:
+		return value == ""
+	}
+
+	return false
+}
+
+// Coerce checks value against the input's type and returns it in its canonical form. Values
+// rendered from templates arrive as strings, so numbers and booleans are parsed from those too.
+func (i *WorkflowInput) Coerce(value any) (any, error) {
+	switch i.Type {
+	case InputTypeNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+
+			return f, nil
+		}
+
+		return nil, fmt.Errorf("must be a number")
+	case InputTypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("must be a boolean")
+			}
+
+			return b, nil
+		}
+
+		return nil, fmt.Errorf("must be a boolean")
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("must be a string")
+	}
+
+	switch i.Type {
+	case InputTypeEmail:
+		addr, err := mail.ParseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("must be an email address")
+		}
+
+		return addr.Address, nil
+	case InputTypeDate:
+		if _, err := time.Parse(time.DateOnly, s); err == nil {
+			return s, nil
+		}
+
+		if _, err := time.Parse(time.RFC3339, s); err == nil {
+			return s, nil
+		}
+
+		return nil, fmt.Errorf("must be a date such as 2025-01-31 or an RFC3339 timestamp")
+	}
+
+	return s, nil
+}
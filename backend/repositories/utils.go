@@ -17,3 +17,16 @@ func marshalConfig(config any) ([]byte, error) {
 
 	return jsonBytes, nil
 }
+
+func unmarshalMetadata(metadata []byte) (map[string]any, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	m := make(map[string]any)
+	if err := json.Unmarshal(metadata, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow node run metadata: %w", err)
+	}
+
+	return m, nil
+}
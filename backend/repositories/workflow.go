@@ -1,12 +1,17 @@
 package repositories
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
+	"github.com/guregu/null/v6"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/tinyautomator/tinyautomator-core/backend/db/dao"
@@ -200,6 +205,7 @@ func (r *workflowRepo) CreateWorkflow(
 			WorkflowID:   w.ID,
 			SourceNodeID: createdNodeIDMap[edge.SourceNodeID],
 			TargetNodeID: createdNodeIDMap[edge.TargetNodeID],
+			Label:        null.NewString(edge.Label, edge.Label != ""),
 		})
 		if err != nil {
 			return nil, fmt.Errorf("db error create workflow edges: %w", err)
@@ -209,9 +215,14 @@ func (r *workflowRepo) CreateWorkflow(
 			SourceNodeID: e.SourceNodeID,
 			TargetNodeID: e.TargetNodeID,
 			WorkflowID:   w.ID,
+			Label:        e.Label.String,
 		})
 	}
 
+	if err = createWorkflowVersion(ctx, qtx, wg); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction in create workflow: %w", err)
 	}
@@ -379,12 +390,27 @@ func (r workflowRepo) UpdateWorkflow(
 			WorkflowID:   workflowID,
 			SourceNodeID: nodeIDMap[e.SourceNodeID],
 			TargetNodeID: nodeIDMap[e.TargetNodeID],
+			Label:        null.NewString(e.Label, e.Label != ""),
 		})
 		if err != nil {
 			return fmt.Errorf("db error create workflow edges: %w", err)
 		}
 	}
 
+	rows, err := qtx.GetWorkflowGraph(ctx, workflowID)
+	if err != nil {
+		return fmt.Errorf("db error get updated workflow graph: %w", err)
+	}
+
+	graph, err := toWorkflowGraph(workflowID, rows)
+	if err != nil {
+		return err
+	}
+
+	if err = createWorkflowVersion(ctx, qtx, graph); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("commit tx failed: %w", err)
 	}
@@ -405,6 +431,13 @@ func (r workflowRepo) GetWorkflowGraph(
 		return nil, fmt.Errorf("no rows found for workflow graph")
 	}
 
+	return toWorkflowGraph(workflowID, rows)
+}
+
+func toWorkflowGraph(
+	workflowID int32,
+	rows []*dao.GetWorkflowGraphRow,
+) (*models.WorkflowGraph, error) {
 	nodeMap := make(map[int32]*models.WorkflowNode)
 
 	var edges []*models.WorkflowEdge
@@ -431,6 +464,7 @@ func (r workflowRepo) GetWorkflowGraph(
 				SourceNodeID: row.SourceNodeID.Int32,
 				TargetNodeID: row.TargetNodeID.Int32,
 				WorkflowID:   row.WorkflowID,
+				Label:        row.Label.String,
 			})
 		}
 	}
@@ -501,6 +535,7 @@ func (r workflowRepo) RenderWorkflowGraph(
 				ID:           fmt.Sprintf("%d-%d", row.SourceNodeID.Int32, row.TargetNodeID.Int32),
 				SourceNodeID: fmt.Sprintf("%d", row.SourceNodeID.Int32),
 				TargetNodeID: fmt.Sprintf("%d", row.TargetNodeID.Int32),
+				Label:        row.Label.String,
 			})
 		}
 	}
@@ -535,4 +570,304 @@ func (r *workflowRepo) ArchiveWorkflow(
 	return nil
 }
 
+func (r *workflowRepo) GetWorkflowConcurrency(
+	ctx context.Context,
+	workflowID int32,
+) (*models.WorkflowConcurrency, error) {
+	c, err := r.q.GetWorkflowConcurrency(ctx, workflowID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.DefaultWorkflowConcurrency(workflowID), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("db error get workflow concurrency: %w", err)
+	}
+
+	return &models.WorkflowConcurrency{
+		WorkflowID:        c.WorkflowID,
+		MaxConcurrentRuns: c.MaxConcurrentRuns,
+		OverlapPolicy:     c.OverlapPolicy,
+	}, nil
+}
+
+func (r *workflowRepo) UpdateWorkflowConcurrency(
+	ctx context.Context,
+	concurrency *models.WorkflowConcurrency,
+) (*models.WorkflowConcurrency, error) {
+	c, err := r.q.UpsertWorkflowConcurrency(ctx, &dao.UpsertWorkflowConcurrencyParams{
+		WorkflowID:        concurrency.WorkflowID,
+		MaxConcurrentRuns: concurrency.MaxConcurrentRuns,
+		OverlapPolicy:     concurrency.OverlapPolicy,
+		UpdatedAt:         time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db error upsert workflow concurrency: %w", err)
+	}
+
+	return &models.WorkflowConcurrency{
+		WorkflowID:        c.WorkflowID,
+		MaxConcurrentRuns: c.MaxConcurrentRuns,
+		OverlapPolicy:     c.OverlapPolicy,
+	}, nil
+}
+
+func (r *workflowRepo) GetWorkflowTimeout(
+	ctx context.Context,
+	workflowID int32,
+) (*models.WorkflowTimeout, error) {
+	t, err := r.q.GetWorkflowTimeout(ctx, workflowID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.DefaultWorkflowTimeout(workflowID), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("db error get workflow timeout: %w", err)
+	}
+
+	return &models.WorkflowTimeout{
+		WorkflowID:        t.WorkflowID,
+		RunTimeoutSeconds: t.RunTimeoutSeconds,
+	}, nil
+}
+
+func (r *workflowRepo) UpdateWorkflowTimeout(
+	ctx context.Context,
+	timeout *models.WorkflowTimeout,
+) (*models.WorkflowTimeout, error) {
+	t, err := r.q.UpsertWorkflowTimeout(ctx, &dao.UpsertWorkflowTimeoutParams{
+		WorkflowID:        timeout.WorkflowID,
+		RunTimeoutSeconds: timeout.RunTimeoutSeconds,
+		UpdatedAt:         time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db error upsert workflow timeout: %w", err)
+	}
+
+	return &models.WorkflowTimeout{
+		WorkflowID:        t.WorkflowID,
+		RunTimeoutSeconds: t.RunTimeoutSeconds,
+	}, nil
+}
+
+func (r *workflowRepo) GetWorkflowInputSchema(
+	ctx context.Context,
+	workflowID int32,
+) (*models.WorkflowInputSchema, error) {
+	row, err := r.q.GetWorkflowInputSchema(ctx, workflowID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.DefaultWorkflowInputSchema(workflowID), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("db error get workflow input schema: %w", err)
+	}
+
+	return toWorkflowInputSchema(row)
+}
+
+func (r *workflowRepo) UpdateWorkflowInputSchema(
+	ctx context.Context,
+	schema *models.WorkflowInputSchema,
+) (*models.WorkflowInputSchema, error) {
+	inputs, err := json.Marshal(schema.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workflow inputs: %w", err)
+	}
+
+	row, err := r.q.UpsertWorkflowInputSchema(ctx, &dao.UpsertWorkflowInputSchemaParams{
+		WorkflowID: schema.WorkflowID,
+		Inputs:     inputs,
+		UpdatedAt:  time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db error upsert workflow input schema: %w", err)
+	}
+
+	return toWorkflowInputSchema(row)
+}
+
+func toWorkflowInputSchema(row *dao.WorkflowInputSchema) (*models.WorkflowInputSchema, error) {
+	schema := models.DefaultWorkflowInputSchema(row.WorkflowID)
+	if err := json.Unmarshal(row.Inputs, &schema.Inputs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow inputs: %w", err)
+	}
+
+	return schema, nil
+}
+
 var _ models.WorkflowRepository = (*workflowRepo)(nil)
+
+func (r *workflowRepo) GetLatestWorkflowVersion(
+	ctx context.Context,
+	workflowID int32,
+) (*models.WorkflowVersion, error) {
+	row, err := r.q.GetLatestWorkflowVersion(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("db error get latest workflow version: %w", err)
+	}
+
+	return toWorkflowVersion(row)
+}
+
+func (r *workflowRepo) GetWorkflowVersion(
+	ctx context.Context,
+	id int32,
+) (*models.WorkflowVersion, error) {
+	row, err := r.q.GetWorkflowVersion(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("db error get workflow version: %w", err)
+	}
+
+	return toWorkflowVersion(row)
+}
+
+func (r *workflowRepo) GetWorkflowRunGraph(
+	ctx context.Context,
+	runID int32,
+) (*models.WorkflowGraph, error) {
+	row, err := r.q.GetWorkflowRunVersion(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("db error get workflow run version: %w", err)
+	}
+
+	version, err := toWorkflowVersion(row)
+	if err != nil {
+		return nil, err
+	}
+
+	return version.Graph, nil
+}
+
+// versionGraph is how a workflow version stores its graph.
+type versionGraph struct {
+	Nodes []*models.WorkflowNodeCore `json:"nodes"`
+	Edges []*versionEdge             `json:"edges"`
+}
+
+type versionEdge struct {
+	SourceNodeID int32  `json:"source_node_id"`
+	TargetNodeID int32  `json:"target_node_id"`
+	Label        string `json:"label,omitempty"`
+}
+
+// marshalVersionGraph encodes the graph with its nodes and edges sorted, so saving the same graph
+// twice yields the same bytes.
+func marshalVersionGraph(graph *models.WorkflowGraph) ([]byte, error) {
+	vg := versionGraph{
+		Nodes: make([]*models.WorkflowNodeCore, 0, len(graph.Nodes)),
+		Edges: make([]*versionEdge, 0, len(graph.Edges)),
+	}
+
+	for _, node := range graph.Nodes {
+		vg.Nodes = append(vg.Nodes, &node.WorkflowNodeCore)
+	}
+
+	for _, edge := range graph.Edges {
+		vg.Edges = append(vg.Edges, &versionEdge{
+			SourceNodeID: edge.SourceNodeID,
+			TargetNodeID: edge.TargetNodeID,
+			Label:        edge.Label,
+		})
+	}
+
+	slices.SortFunc(vg.Nodes, func(a, b *models.WorkflowNodeCore) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	slices.SortFunc(vg.Edges, func(a, b *versionEdge) int {
+		return cmp.Or(
+			cmp.Compare(a.SourceNodeID, b.SourceNodeID),
+			cmp.Compare(a.TargetNodeID, b.TargetNodeID),
+			cmp.Compare(a.Label, b.Label),
+		)
+	})
+
+	data, err := json.Marshal(vg)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling workflow version graph: %w", err)
+	}
+
+	return data, nil
+}
+
+// createWorkflowVersion records the saved graph as the workflow's next version, unless it is the
+// same as the latest version.
+func createWorkflowVersion(
+	ctx context.Context,
+	qtx *dao.Queries,
+	graph *models.WorkflowGraph,
+) error {
+	data, err := marshalVersionGraph(graph)
+	if err != nil {
+		return err
+	}
+
+	// concurrent saves of the workflow would otherwise number their versions the same
+	if err := qtx.LockWorkflowVersions(ctx, graph.ID); err != nil {
+		return fmt.Errorf("db error lock workflow versions: %w", err)
+	}
+
+	latest, err := qtx.GetLatestWorkflowVersion(ctx, graph.ID)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return fmt.Errorf("db error get latest workflow version: %w", err)
+	default:
+		version, err := toWorkflowVersion(latest)
+		if err != nil {
+			return err
+		}
+
+		// JSONB does not keep the bytes as written, so compare the re-encoded graph
+		latestData, err := marshalVersionGraph(version.Graph)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(data, latestData) {
+			return nil
+		}
+	}
+
+	_, err = qtx.CreateWorkflowVersion(ctx, &dao.CreateWorkflowVersionParams{
+		WorkflowID: graph.ID,
+		Graph:      data,
+		CreatedAt:  time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return fmt.Errorf("db error create workflow version: %w", err)
+	}
+
+	return nil
+}
+
+func toWorkflowVersion(row *dao.WorkflowVersion) (*models.WorkflowVersion, error) {
+	var vg versionGraph
+	if err := json.Unmarshal(row.Graph, &vg); err != nil {
+		return nil, fmt.Errorf("error unmarshalling workflow version graph: %w", err)
+	}
+
+	graph := &models.WorkflowGraph{
+		ID:    row.WorkflowID,
+		Nodes: make([]*models.WorkflowNode, 0, len(vg.Nodes)),
+		Edges: make([]*models.WorkflowEdge, 0, len(vg.Edges)),
+	}
+
+	for _, node := range vg.Nodes {
+		graph.Nodes = append(graph.Nodes, &models.WorkflowNode{
+			WorkflowNodeCore: *node,
+			WorkflowID:       row.WorkflowID,
+		})
+	}
+
+	for _, edge := range vg.Edges {
+		graph.Edges = append(graph.Edges, &models.WorkflowEdge{
+			WorkflowID:   row.WorkflowID,
+			SourceNodeID: edge.SourceNodeID,
+			TargetNodeID: edge.TargetNodeID,
+			Label:        edge.Label,
+		})
+	}
+
+	return &models.WorkflowVersion{
+		ID:         row.ID,
+		WorkflowID: row.WorkflowID,
+		Version:    row.Version,
+		Graph:      graph,
+		CreatedAt:  time.UnixMilli(row.CreatedAt),
+	}, nil
+}
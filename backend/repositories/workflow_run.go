@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -41,6 +42,7 @@ func (r *workflowRunRepo) WithTransaction(
 
 	txRepo := &workflowRunRepo{
 		q:  r.q.WithTx(tx),
+		db: r.db,
 		tx: &tx,
 	}
 
@@ -55,16 +57,69 @@ func (r *workflowRunRepo) WithTransaction(
 	return nil
 }
 
+// beginTx starts a transaction, or a savepoint when the repository already runs in one.
+func (r *workflowRunRepo) beginTx(ctx context.Context) (pgx.Tx, error) {
+	var (
+		tx  pgx.Tx
+		err error
+	)
+
+	if r.tx != nil {
+		tx, err = (*r.tx).Begin(ctx)
+	} else {
+		tx, err = r.db.BeginTx(ctx, pgx.TxOptions{})
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error begin tx: %w", err)
+	}
+
+	return tx, nil
+}
+
+func toWorkflowRunCore(run *dao.WorkflowRun) (*models.WorkflowRunCore, error) {
+	inputs, err := unmarshalMetadata(run.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	triggerPayload, err := unmarshalMetadata(run.TriggerPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WorkflowRunCore{
+		ID:                run.ID,
+		WorkflowID:        run.WorkflowID,
+		Status:            run.Status,
+		FinishedAt:        null.TimeFrom(time.UnixMilli(run.FinishedAt.Int64)),
+		CreatedAt:         time.UnixMilli(run.CreatedAt),
+		DryRun:            run.DryRun,
+		ParentRunID:       run.ParentRunID,
+		ParentNodeRunID:   run.ParentNodeRunID,
+		Depth:             run.Depth,
+		Inputs:            inputs,
+		SkipReason:        run.SkipReason,
+		DeadlineAt:        null.NewTime(time.UnixMilli(run.DeadlineAt.Int64), run.DeadlineAt.Valid),
+		ErrorMessage:      run.ErrorMessage,
+		TriggerSource:     run.TriggerSource,
+		TriggerPayload:    triggerPayload,
+		WorkflowVersionID: run.WorkflowVersionID,
+	}, nil
+}
+
 func (r *workflowRunRepo) CreateWorkflowRun(
 	ctx context.Context,
 	workflowID int32,
 	nodes []models.ValidateNode,
+	opts models.WorkflowRunOptions,
+	status string,
 ) (*models.WorkflowRunWithNodesDTO, error) {
 	if len(nodes) == 0 {
 		return nil, fmt.Errorf("no node ids provided")
 	}
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := r.beginTx(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("db error failed to begin tx in create workflow run: %w", err)
 	}
@@ -79,14 +134,45 @@ func (r *workflowRunRepo) CreateWorkflowRun(
 
 	now := time.Now().UnixMilli()
 
+	inputs, err := marshalRunInputs(opts.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	triggerPayload, err := marshalTriggerPayload(opts.TriggerPayload)
+	if err != nil {
+		return nil, err
+	}
+
 	run, err := qtx.CreateWorkflowRun(ctx, &dao.CreateWorkflowRunParams{
-		WorkflowID: workflowID,
-		CreatedAt:  now,
+		WorkflowID:        workflowID,
+		Status:            status,
+		CreatedAt:         now,
+		DryRun:            opts.DryRun,
+		ParentRunID:       opts.ParentRunID,
+		ParentNodeRunID:   opts.ParentNodeRunID,
+		Depth:             opts.Depth,
+		Inputs:            inputs,
+		TriggerSource:     opts.TriggerSource,
+		TriggerPayload:    triggerPayload,
+		WorkflowVersionID: opts.WorkflowVersionID,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("db error create workflow run: %w", err)
 	}
 
+	// the node that starts the run learns about it in the same transaction, so it never starts
+	// the run twice
+	if opts.ParentNodeRunID.Valid {
+		err = qtx.SetWorkflowNodeRunChildRun(ctx, &dao.SetWorkflowNodeRunChildRunParams{
+			ID:         opts.ParentNodeRunID.Int32,
+			ChildRunID: run.ID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("db error link child workflow run: %w", err)
+		}
+	}
+
 	n := make([]*models.WorkflowNodeRunCore, len(nodes))
 
 	for i, node := range nodes {
@@ -95,15 +181,16 @@ func (r *workflowRunRepo) CreateWorkflowRun(
 			return nil, fmt.Errorf("db error parse node id: %w", err)
 		}
 
-		status := "pending"
+		nodeStatus := "pending"
 		if node.Category == "trigger" {
-			status = "success"
+			nodeStatus = "success"
 		}
 
 		nr, err := qtx.CreateWorkflowNodeRun(ctx, &dao.CreateWorkflowNodeRunParams{
 			WorkflowRunID:  run.ID,
 			WorkflowNodeID: int32(nID),
-			Status:         status,
+			Status:         nodeStatus,
+			IterationIndex: models.NoIteration,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("db error create workflow node run: %w", err)
@@ -114,6 +201,7 @@ func (r *workflowRunRepo) CreateWorkflowRun(
 			WorkflowRunID:  nr.WorkflowRunID,
 			WorkflowNodeID: nr.WorkflowNodeID,
 			Status:         nr.Status,
+			IterationIndex: nr.IterationIndex,
 		}
 	}
 
@@ -121,16 +209,120 @@ func (r *workflowRunRepo) CreateWorkflowRun(
 		return nil, fmt.Errorf("db error commit tx in create workflow run: %w", err)
 	}
 
+	runCore, err := toWorkflowRunCore(run)
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.WorkflowRunWithNodesDTO{
-		WorkflowRunCore: models.WorkflowRunCore{
-			ID:         run.ID,
-			WorkflowID: run.WorkflowID,
-			Status:     run.Status,
-		},
-		Nodes: n,
+		WorkflowRunCore: *runCore,
+		Nodes:           n,
 	}, nil
 }
 
+func marshalRunInputs(inputs map[string]any) ([]byte, error) {
+	if inputs == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workflow run inputs: %w", err)
+	}
+
+	return b, nil
+}
+
+func marshalTriggerPayload(payload map[string]any) ([]byte, error) {
+	if payload == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workflow run trigger payload: %w", err)
+	}
+
+	return b, nil
+}
+
+func (r *workflowRunRepo) CreateSkippedWorkflowRun(
+	ctx context.Context,
+	workflowID int32,
+	opts models.WorkflowRunOptions,
+	reason string,
+) (*models.WorkflowRunCore, error) {
+	inputs, err := marshalRunInputs(opts.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	triggerPayload, err := marshalTriggerPayload(opts.TriggerPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := r.q.CreateSkippedWorkflowRun(ctx, &dao.CreateSkippedWorkflowRunParams{
+		WorkflowID:        workflowID,
+		CreatedAt:         time.Now().UnixMilli(),
+		DryRun:            opts.DryRun,
+		ParentRunID:       opts.ParentRunID,
+		ParentNodeRunID:   opts.ParentNodeRunID,
+		Depth:             opts.Depth,
+		Inputs:            inputs,
+		SkipReason:        null.StringFrom(reason),
+		TriggerSource:     opts.TriggerSource,
+		TriggerPayload:    triggerPayload,
+		WorkflowVersionID: opts.WorkflowVersionID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db error create skipped workflow run: %w", err)
+	}
+
+	return toWorkflowRunCore(run)
+}
+
+func (r *workflowRunRepo) LockWorkflowRuns(ctx context.Context, workflowID int32) error {
+	if err := r.q.LockWorkflowRuns(ctx, workflowID); err != nil {
+		return fmt.Errorf("db error lock workflow runs: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) GetActiveWorkflowRuns(
+	ctx context.Context,
+	workflowID int32,
+) ([]*models.WorkflowRunCore, error) {
+	rows, err := r.q.ListActiveWorkflowRuns(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("db error list active workflow runs: %w", err)
+	}
+
+	workflowRuns := make([]*models.WorkflowRunCore, len(rows))
+
+	for i, row := range rows {
+		workflowRuns[i], err = toWorkflowRunCore(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return workflowRuns, nil
+}
+
+func (r *workflowRunRepo) StartQueuedWorkflowRun(
+	ctx context.Context,
+	workflowRunID int32,
+) (bool, error) {
+	n, err := r.q.StartQueuedWorkflowRun(ctx, workflowRunID)
+	if err != nil {
+		return false, fmt.Errorf("db error start queued workflow run: %w", err)
+	}
+
+	return n > 0, nil
+}
+
 func (r *workflowRunRepo) CompleteWorkflowRun(
 	ctx context.Context,
 	workflowRunID int32,
@@ -150,8 +342,12 @@ func (r *workflowRunRepo) CompleteWorkflowRun(
 func (r *workflowRunRepo) GetWorkflowRuns(
 	ctx context.Context,
 	workflowID int32,
+	dryRun bool,
 ) ([]*models.WorkflowRunCore, error) {
-	rows, err := r.q.ListWorkflowRuns(ctx, workflowID)
+	rows, err := r.q.ListWorkflowRuns(ctx, &dao.ListWorkflowRunsParams{
+		WorkflowID: workflowID,
+		DryRun:     dryRun,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("db error list workflow runs: %w", err)
 	}
@@ -159,18 +355,377 @@ func (r *workflowRunRepo) GetWorkflowRuns(
 	workflowRuns := make([]*models.WorkflowRunCore, len(rows))
 
 	for i, row := range rows {
-		workflowRuns[i] = &models.WorkflowRunCore{
-			ID:         row.ID,
-			WorkflowID: row.WorkflowID,
-			Status:     row.Status,
-			FinishedAt: null.TimeFrom(time.UnixMilli(row.FinishedAt.Int64)),
-			CreatedAt:  time.UnixMilli(row.CreatedAt),
+		workflowRuns[i], err = toWorkflowRunCore(row)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	return workflowRuns, nil
 }
 
+func (r *workflowRunRepo) GetWorkflowRunCore(
+	ctx context.Context,
+	workflowRunID int32,
+) (*models.WorkflowRunCore, error) {
+	run, err := r.q.GetWorkflowRunByID(ctx, workflowRunID)
+	if err != nil {
+		return nil, fmt.Errorf("db error get workflow run by id: %w", err)
+	}
+
+	return toWorkflowRunCore(run)
+}
+
+func (r *workflowRunRepo) CancelWorkflowRun(ctx context.Context, workflowRunID int32) (bool, error) {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("db error failed to begin tx in cancel workflow run: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qtx := r.q.WithTx(tx)
+	now := null.IntFrom(time.Now().UnixMilli())
+
+	n, err := qtx.CancelWorkflowRun(ctx, &dao.CancelWorkflowRunParams{
+		ID:         workflowRunID,
+		FinishedAt: now,
+	})
+	if err != nil {
+		return false, fmt.Errorf("db error cancel workflow run: %w", err)
+	}
+
+	if n == 0 {
+		return false, nil
+	}
+
+	if err := qtx.CancelPendingWorkflowNodeRuns(ctx, &dao.CancelPendingWorkflowNodeRunsParams{
+		WorkflowRunID: workflowRunID,
+		FinishedAt:    now,
+	}); err != nil {
+		return false, fmt.Errorf("db error cancel pending workflow node runs: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("db error commit tx in cancel workflow run: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *workflowRunRepo) AddOutboxTask(
+	ctx context.Context,
+	payload []byte,
+	delay time.Duration,
+) error {
+	err := r.q.InsertOutboxTask(ctx, &dao.InsertOutboxTaskParams{
+		Payload:   payload,
+		DelayMs:   max(delay.Milliseconds(), 0),
+		CreatedAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return fmt.Errorf("db error insert outbox task: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) ClaimOutboxTasks(
+	ctx context.Context,
+	limit int32,
+) ([]*models.OutboxTask, error) {
+	rows, err := r.q.ClaimOutboxTasks(ctx, &dao.ClaimOutboxTasksParams{
+		Limit:   limit,
+		RetryAt: null.IntFrom(time.Now().UnixMilli()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db error claim outbox tasks: %w", err)
+	}
+
+	tasks := make([]*models.OutboxTask, len(rows))
+	for i, row := range rows {
+		tasks[i] = &models.OutboxTask{
+			ID:        row.ID,
+			Payload:   row.Payload,
+			Delay:     time.Duration(row.DelayMs) * time.Millisecond,
+			CreatedAt: time.UnixMilli(row.CreatedAt),
+			Attempts:  row.Attempts,
+		}
+	}
+
+	return tasks, nil
+}
+
+func (r *workflowRunRepo) MarkOutboxTaskSent(ctx context.Context, id int64) error {
+	err := r.q.MarkOutboxTaskSent(ctx, &dao.MarkOutboxTaskSentParams{
+		ID:     id,
+		SentAt: null.IntFrom(time.Now().UnixMilli()),
+	})
+	if err != nil {
+		return fmt.Errorf("db error mark outbox task sent: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) MarkOutboxTaskFailed(
+	ctx context.Context,
+	id int64,
+	errorMessage string,
+	retryAt time.Time,
+) error {
+	err := r.q.MarkOutboxTaskFailed(ctx, &dao.MarkOutboxTaskFailedParams{
+		ID:        id,
+		LastError: null.StringFrom(errorMessage),
+		RetryAt:   null.IntFrom(retryAt.UnixMilli()),
+	})
+	if err != nil {
+		return fmt.Errorf("db error mark outbox task failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) MarkOutboxTaskDead(
+	ctx context.Context,
+	id int64,
+	errorMessage string,
+) error {
+	err := r.q.MarkOutboxTaskDead(ctx, &dao.MarkOutboxTaskDeadParams{
+		ID:        id,
+		LastError: null.StringFrom(errorMessage),
+		DeadAt:    null.IntFrom(time.Now().UnixMilli()),
+	})
+	if err != nil {
+		return fmt.Errorf("db error mark outbox task dead: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) DeleteSentOutboxTasks(
+	ctx context.Context,
+	sentBefore time.Time,
+) (int64, error) {
+	n, err := r.q.DeleteSentOutboxTasks(ctx, null.IntFrom(sentBefore.UnixMilli()))
+	if err != nil {
+		return 0, fmt.Errorf("db error delete sent outbox tasks: %w", err)
+	}
+
+	return n, nil
+}
+
+func (r *workflowRunRepo) GetSideEffect(
+	ctx context.Context,
+	idempotencyKey string,
+) (map[string]any, error) {
+	sideEffect, err := r.q.GetWorkflowSideEffect(ctx, idempotencyKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("db error get side effect: %w", err)
+	}
+
+	output, err := unmarshalMetadata(sideEffect.Output)
+	if err != nil {
+		return nil, fmt.Errorf("db error get side effect: %w", err)
+	}
+
+	return output, nil
+}
+
+func (r *workflowRunRepo) RecordSideEffect(
+	ctx context.Context,
+	workflowRunID int32,
+	idempotencyKey string,
+	output map[string]any,
+) error {
+	// an empty object rather than null, so the side effect is found again
+	if output == nil {
+		output = map[string]any{}
+	}
+
+	b, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Errorf("failed to marshal side effect output: %w", err)
+	}
+
+	err = r.q.InsertWorkflowSideEffect(ctx, &dao.InsertWorkflowSideEffectParams{
+		IdempotencyKey: idempotencyKey,
+		WorkflowRunID:  workflowRunID,
+		Output:         b,
+		CreatedAt:      time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return fmt.Errorf("db error insert side effect: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) GetIdlePendingWorkflowNodeRuns(
+	ctx context.Context,
+	idleBefore time.Time,
+	limit int32,
+) ([]*models.IdleNodeRun, error) {
+	rows, err := r.q.ListIdlePendingWorkflowNodeRuns(
+		ctx,
+		&dao.ListIdlePendingWorkflowNodeRunsParams{
+			IdleBefore:  idleBefore.UnixMilli(),
+			MaxNodeRuns: limit,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("db error list idle pending workflow node runs: %w", err)
+	}
+
+	nodeRuns := make([]*models.IdleNodeRun, len(rows))
+	for i, row := range rows {
+		nodeRuns[i] = &models.IdleNodeRun{
+			ID:             row.ID,
+			WorkflowRunID:  row.WorkflowRunID,
+			WorkflowID:     row.WorkflowID,
+			UserID:         row.UserID,
+			WorkflowNodeID: row.WorkflowNodeID,
+			IterationIndex: row.IterationIndex,
+		}
+	}
+
+	return nodeRuns, nil
+}
+
+func (r *workflowRunRepo) SetWorkflowRunDeadline(
+	ctx context.Context,
+	workflowRunID int32,
+	deadline time.Time,
+) error {
+	err := r.q.SetWorkflowRunDeadline(ctx, &dao.SetWorkflowRunDeadlineParams{
+		ID:         workflowRunID,
+		DeadlineAt: null.IntFrom(deadline.UnixMilli()),
+	})
+	if err != nil {
+		return fmt.Errorf("db error set workflow run deadline: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) GetStuckWorkflowRuns(
+	ctx context.Context,
+	now time.Time,
+	stalledBefore time.Time,
+	limit int32,
+) ([]*models.StuckWorkflowRun, error) {
+	rows, err := r.q.ListStuckWorkflowRuns(ctx, &dao.ListStuckWorkflowRunsParams{
+		Now:           null.IntFrom(now.UnixMilli()),
+		StalledBefore: stalledBefore.UnixMilli(),
+		MaxRuns:       limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db error list stuck workflow runs: %w", err)
+	}
+
+	runs := make([]*models.StuckWorkflowRun, len(rows))
+	for i, row := range rows {
+		runs[i] = &models.StuckWorkflowRun{
+			ID:             row.ID,
+			WorkflowID:     row.WorkflowID,
+			UserID:         row.UserID,
+			DeadlineAt:     null.NewTime(time.UnixMilli(row.DeadlineAt.Int64), row.DeadlineAt.Valid),
+			LastProgressAt: time.UnixMilli(row.LastProgressAt),
+		}
+	}
+
+	return runs, nil
+}
+
+func (r *workflowRunRepo) TimeOutWorkflowRun(
+	ctx context.Context,
+	workflowRunID int32,
+	reason string,
+) (bool, error) {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("db error failed to begin tx in time out workflow run: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qtx := r.q.WithTx(tx)
+	now := null.IntFrom(time.Now().UnixMilli())
+
+	n, err := qtx.TimeOutWorkflowRun(ctx, &dao.TimeOutWorkflowRunParams{
+		ID:           workflowRunID,
+		FinishedAt:   now,
+		ErrorMessage: null.StringFrom(reason),
+	})
+	if err != nil {
+		return false, fmt.Errorf("db error time out workflow run: %w", err)
+	}
+
+	if n == 0 {
+		return false, nil
+	}
+
+	if err := qtx.FailRunningWorkflowNodeRuns(ctx, &dao.FailRunningWorkflowNodeRunsParams{
+		WorkflowRunID: workflowRunID,
+		FinishedAt:    now,
+		ErrorMessage:  null.StringFrom(reason),
+	}); err != nil {
+		return false, fmt.Errorf("db error fail running workflow node runs: %w", err)
+	}
+
+	if err := qtx.CancelPendingWorkflowNodeRuns(ctx, &dao.CancelPendingWorkflowNodeRunsParams{
+		WorkflowRunID: workflowRunID,
+		FinishedAt:    now,
+	}); err != nil {
+		return false, fmt.Errorf("db error cancel pending workflow node runs: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("db error commit tx in time out workflow run: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *workflowRunRepo) ResumeWorkflowRun(
+	ctx context.Context,
+	workflowRunID int32,
+	nodeRunIDs []int32,
+) (bool, error) {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("db error failed to begin tx in resume workflow run: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qtx := r.q.WithTx(tx)
+
+	n, err := qtx.ResumeWorkflowRun(ctx, workflowRunID)
+	if err != nil {
+		return false, fmt.Errorf("db error resume workflow run: %w", err)
+	}
+
+	if n == 0 {
+		return false, nil
+	}
+
+	if err := qtx.ResetWorkflowNodeRuns(ctx, &dao.ResetWorkflowNodeRunsParams{
+		WorkflowRunID: workflowRunID,
+		Ids:           nodeRunIDs,
+	}); err != nil {
+		return false, fmt.Errorf("db error reset workflow node runs: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("db error commit tx in resume workflow run: %w", err)
+	}
+
+	return true, nil
+}
+
 func (r *workflowRunRepo) GetWorkflowRun(
 	ctx context.Context,
 	workflowRunID int32,
@@ -181,13 +736,27 @@ func (r *workflowRunRepo) GetWorkflowRun(
 	}
 
 	if len(rows) == 0 {
-		return nil, fmt.Errorf("no rows found for workflow run")
+		// skipped runs never get node runs
+		run, err := r.GetWorkflowRunCore(ctx, workflowRunID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.WorkflowRunWithNodesDTO{
+			WorkflowRunCore: *run,
+			Nodes:           []*models.WorkflowNodeRunCore{},
+		}, nil
 	}
 
 	nodeMap := make(map[int32]*models.WorkflowNodeRunCore)
 
 	for _, row := range rows {
 		if _, exists := nodeMap[row.NodeRunID]; !exists {
+			metadata, err := unmarshalMetadata(row.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("db error get workflow run: %w", err)
+			}
+
 			nodeMap[row.NodeRunID] = &models.WorkflowNodeRunCore{
 				ID:             row.NodeRunID,
 				WorkflowRunID:  row.WorkflowRunID,
@@ -195,8 +764,9 @@ func (r *workflowRunRepo) GetWorkflowRun(
 				Status:         row.NodeRunStatus,
 				StartedAt:      null.TimeFrom(time.UnixMilli(row.NodeRunStartedAt.Int64)),
 				FinishedAt:     null.TimeFrom(time.UnixMilli(row.NodeRunFinishedAt.Int64)),
-				Metadata:       null.StringFrom(string(row.Metadata)),
+				Metadata:       metadata,
 				ErrorMessage:   null.StringFrom(row.ErrorMessage.String),
+				IterationIndex: row.IterationIndex,
 			}
 		}
 	}
@@ -206,13 +776,35 @@ func (r *workflowRunRepo) GetWorkflowRun(
 		nodes = append(nodes, node)
 	}
 
+	inputs, err := unmarshalMetadata(rows[0].WorkflowRunInputs)
+	if err != nil {
+		return nil, fmt.Errorf("db error get workflow run: %w", err)
+	}
+
+	triggerPayload, err := unmarshalMetadata(rows[0].WorkflowRunTriggerPayload)
+	if err != nil {
+		return nil, fmt.Errorf("db error get workflow run: %w", err)
+	}
+
 	return &models.WorkflowRunWithNodesDTO{
 		WorkflowRunCore: models.WorkflowRunCore{
-			ID:         rows[0].WorkflowRunID,
-			WorkflowID: rows[0].WorkflowID,
-			Status:     rows[0].WorkflowRunStatus,
-			FinishedAt: null.TimeFrom(time.UnixMilli(rows[0].WorkflowRunFinishedAt.Int64)),
-			CreatedAt:  time.UnixMilli(rows[0].WorkflowRunCreatedAt),
+			ID:          rows[0].WorkflowRunID,
+			WorkflowID:  rows[0].WorkflowID,
+			Status:      rows[0].WorkflowRunStatus,
+			FinishedAt:  null.TimeFrom(time.UnixMilli(rows[0].WorkflowRunFinishedAt.Int64)),
+			CreatedAt:   time.UnixMilli(rows[0].WorkflowRunCreatedAt),
+			DryRun:      rows[0].WorkflowRunDryRun,
+			ParentRunID: rows[0].WorkflowRunParentRunID,
+			Depth:       rows[0].WorkflowRunDepth,
+			Inputs:      inputs,
+			DeadlineAt: null.NewTime(
+				time.UnixMilli(rows[0].WorkflowRunDeadlineAt.Int64),
+				rows[0].WorkflowRunDeadlineAt.Valid,
+			),
+			ErrorMessage:      rows[0].WorkflowRunErrorMessage,
+			TriggerSource:     rows[0].WorkflowRunTriggerSource,
+			TriggerPayload:    triggerPayload,
+			WorkflowVersionID: rows[0].WorkflowRunWorkflowVersionID,
 		},
 		Nodes: nodes,
 	}, nil
@@ -221,8 +813,12 @@ func (r *workflowRunRepo) GetWorkflowRun(
 func (r *workflowRunRepo) GetUserWorkflowRuns(
 	ctx context.Context,
 	userID string,
+	dryRun bool,
 ) ([]*models.UserWorkflowRunDTO, error) {
-	rows, err := r.q.GetUserWorkflowRuns(ctx, userID)
+	rows, err := r.q.GetUserWorkflowRuns(ctx, &dao.GetUserWorkflowRunsParams{
+		UserID: userID,
+		DryRun: dryRun,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("db error get user workflow runs: %w", err)
 	}
@@ -230,6 +826,11 @@ func (r *workflowRunRepo) GetUserWorkflowRuns(
 	userWorkflowRuns := make([]*models.UserWorkflowRunDTO, len(rows))
 
 	for i, row := range rows {
+		inputs, err := unmarshalMetadata(row.WorkflowRunInputs)
+		if err != nil {
+			return nil, fmt.Errorf("db error get user workflow runs: %w", err)
+		}
+
 		userWorkflowRuns[i] = &models.UserWorkflowRunDTO{
 			WorkflowID:    row.WorkflowID,
 			WorkflowName:  row.WorkflowName,
@@ -237,6 +838,8 @@ func (r *workflowRunRepo) GetUserWorkflowRuns(
 			Status:        row.WorkflowRunStatus,
 			CreatedAt:     time.UnixMilli(row.WorkflowRunCreatedAt),
 			FinishedAt:    null.TimeFrom(time.UnixMilli(row.WorkflowRunFinishedAt.Int64)),
+			DryRun:        row.WorkflowRunDryRun,
+			Inputs:        inputs,
 		}
 	}
 
@@ -247,23 +850,39 @@ func (r *workflowRunRepo) GetWorkflowNodeRun(
 	ctx context.Context,
 	workflowRunID int32,
 	nodeID int32,
+	iterationIndex int32,
 ) (*models.WorkflowNodeRunCore, error) {
 	row, err := r.q.GetWorkflowNodeRunByWorkflowRunIDAndNodeID(
 		ctx,
 		&dao.GetWorkflowNodeRunByWorkflowRunIDAndNodeIDParams{
 			WorkflowRunID:  workflowRunID,
 			WorkflowNodeID: nodeID,
+			IterationIndex: iterationIndex,
 		},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("db error get workflow node run: %w", err)
 	}
 
-	var metadataJSON null.String
-	if row.Metadata != nil {
-		if err := json.Unmarshal(row.Metadata, &metadataJSON); err != nil {
-			return nil, fmt.Errorf("db error unmarshal workflow node run metadata: %w", err)
-		}
+	return toWorkflowNodeRunCore(row)
+}
+
+func (r *workflowRunRepo) GetWorkflowNodeRunByID(
+	ctx context.Context,
+	workflowNodeRunID int32,
+) (*models.WorkflowNodeRunCore, error) {
+	row, err := r.q.GetWorkflowNodeRunByID(ctx, workflowNodeRunID)
+	if err != nil {
+		return nil, fmt.Errorf("db error get workflow node run by id: %w", err)
+	}
+
+	return toWorkflowNodeRunCore(row)
+}
+
+func toWorkflowNodeRunCore(row *dao.WorkflowNodeRun) (*models.WorkflowNodeRunCore, error) {
+	metadata, err := unmarshalMetadata(row.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("db error get workflow node run: %w", err)
 	}
 
 	return &models.WorkflowNodeRunCore{
@@ -274,7 +893,9 @@ func (r *workflowRunRepo) GetWorkflowNodeRun(
 		RetryCount:     row.RetryCount,
 		StartedAt:      null.TimeFrom(time.UnixMilli(row.StartedAt.Int64)),
 		FinishedAt:     null.TimeFrom(time.UnixMilli(row.FinishedAt.Int64)),
-		Metadata:       metadataJSON,
+		Metadata:       metadata,
+		ErrorMessage:   row.ErrorMessage,
+		IterationIndex: row.IterationIndex,
 	}, nil
 }
 
@@ -291,23 +912,15 @@ func (r *workflowRunRepo) GetWorkflowNodeRuns(
 	workflowRunNodeRuns := []*models.WorkflowNodeRunCore{}
 
 	for _, row := range rows {
-		var matadata null.String
-
-		if len(row.Metadata) > 0 {
-			var metadataJSON string
-			if err := json.Unmarshal(row.Metadata, &metadataJSON); err != nil {
-				return nil, fmt.Errorf("db error unmarshal workflow node run metadata: %w", err)
-			}
-
-			matadata = null.StringFrom(metadataJSON)
-		} else {
-			matadata = null.String{}
-		}
-
 		if status != nil && *status != row.Status {
 			continue
 		}
 
+		metadata, err := unmarshalMetadata(row.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("db error get workflow node runs: %w", err)
+		}
+
 		workflowRunNodeRuns = append(workflowRunNodeRuns, &models.WorkflowNodeRunCore{
 			ID:             row.ID,
 			WorkflowRunID:  row.WorkflowRunID,
@@ -316,7 +929,9 @@ func (r *workflowRunRepo) GetWorkflowNodeRuns(
 			RetryCount:     row.RetryCount,
 			StartedAt:      null.TimeFrom(time.UnixMilli(row.StartedAt.Int64)),
 			FinishedAt:     null.TimeFrom(time.UnixMilli(row.FinishedAt.Int64)),
-			Metadata:       matadata,
+			Metadata:       metadata,
+			ErrorMessage:   row.ErrorMessage,
+			IterationIndex: row.IterationIndex,
 		})
 	}
 
@@ -327,28 +942,39 @@ func (r *workflowRunRepo) GetParentWorkflowNodeRuns(
 	ctx context.Context,
 	workflowRunID int32,
 	nodeID int32,
-) ([]*models.WorkflowNodeRunCore, error) {
+	iterationIndex int32,
+) ([]*models.LinkedWorkflowNodeRun, error) {
 	rows, err := r.q.GetParentWorkflowNodeRuns(ctx, &dao.GetParentWorkflowNodeRunsParams{
-		WorkflowRunID: workflowRunID,
-		TargetNodeID:  nodeID,
+		WorkflowRunID:  workflowRunID,
+		TargetNodeID:   nodeID,
+		IterationIndex: iterationIndex,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("db error get parent workflow node runs: %w", err)
 	}
 
-	workflowRunNodeRuns := []*models.WorkflowNodeRunCore{}
+	workflowRunNodeRuns := []*models.LinkedWorkflowNodeRun{}
 
 	for _, row := range rows {
-		workflowRunNodeRuns = append(workflowRunNodeRuns, &models.WorkflowNodeRunCore{
-			ID:             row.ID,
-			WorkflowRunID:  row.WorkflowRunID,
-			WorkflowNodeID: row.WorkflowNodeID,
-			Status:         row.Status,
-			RetryCount:     row.RetryCount,
-			StartedAt:      null.TimeFrom(time.UnixMilli(row.StartedAt.Int64)),
-			FinishedAt:     null.TimeFrom(time.UnixMilli(row.FinishedAt.Int64)),
-			Metadata:       null.StringFrom(string(row.Metadata)),
-			ErrorMessage:   null.StringFrom(row.ErrorMessage.String),
+		metadata, err := unmarshalMetadata(row.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("db error get related workflow node runs: %w", err)
+		}
+
+		workflowRunNodeRuns = append(workflowRunNodeRuns, &models.LinkedWorkflowNodeRun{
+			WorkflowNodeRunCore: models.WorkflowNodeRunCore{
+				ID:             row.ID,
+				WorkflowRunID:  row.WorkflowRunID,
+				WorkflowNodeID: row.WorkflowNodeID,
+				Status:         row.Status,
+				RetryCount:     row.RetryCount,
+				StartedAt:      null.TimeFrom(time.UnixMilli(row.StartedAt.Int64)),
+				FinishedAt:     null.TimeFrom(time.UnixMilli(row.FinishedAt.Int64)),
+				Metadata:       metadata,
+				ErrorMessage:   row.ErrorMessage,
+				IterationIndex: row.IterationIndex,
+			},
+			EdgeLabel: row.Label,
 		})
 	}
 
@@ -359,19 +985,67 @@ func (r *workflowRunRepo) GetChildWorkflowNodeRuns(
 	ctx context.Context,
 	workflowRunID int32,
 	nodeID int32,
-) ([]*models.WorkflowNodeRunCore, error) {
+	iterationIndex int32,
+) ([]*models.LinkedWorkflowNodeRun, error) {
 	rows, err := r.q.GetChildWorkflowNodeRuns(ctx, &dao.GetChildWorkflowNodeRunsParams{
-		WorkflowRunID: workflowRunID,
-		SourceNodeID:  nodeID,
+		WorkflowRunID:  workflowRunID,
+		SourceNodeID:   nodeID,
+		IterationIndex: iterationIndex,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("db error get child workflow node runs: %w", err)
 	}
 
-	workflowRunNodeRuns := []*models.WorkflowNodeRunCore{}
+	workflowRunNodeRuns := []*models.LinkedWorkflowNodeRun{}
 
 	for _, row := range rows {
-		workflowRunNodeRuns = append(workflowRunNodeRuns, &models.WorkflowNodeRunCore{
+		metadata, err := unmarshalMetadata(row.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("db error get related workflow node runs: %w", err)
+		}
+
+		workflowRunNodeRuns = append(workflowRunNodeRuns, &models.LinkedWorkflowNodeRun{
+			WorkflowNodeRunCore: models.WorkflowNodeRunCore{
+				ID:             row.ID,
+				WorkflowRunID:  row.WorkflowRunID,
+				WorkflowNodeID: row.WorkflowNodeID,
+				Status:         row.Status,
+				RetryCount:     row.RetryCount,
+				StartedAt:      null.TimeFrom(time.UnixMilli(row.StartedAt.Int64)),
+				FinishedAt:     null.TimeFrom(time.UnixMilli(row.FinishedAt.Int64)),
+				Metadata:       metadata,
+				ErrorMessage:   row.ErrorMessage,
+				IterationIndex: row.IterationIndex,
+			},
+			EdgeLabel: row.Label,
+		})
+	}
+
+	return workflowRunNodeRuns, nil
+}
+
+func (r *workflowRunRepo) GetLoopIterationNodeRuns(
+	ctx context.Context,
+	workflowRunID int32,
+	nodeIDs []int32,
+) ([]*models.WorkflowNodeRunCore, error) {
+	rows, err := r.q.GetLoopIterationNodeRuns(ctx, &dao.GetLoopIterationNodeRunsParams{
+		WorkflowRunID: workflowRunID,
+		NodeIds:       nodeIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db error get loop iteration node runs: %w", err)
+	}
+
+	nodeRuns := make([]*models.WorkflowNodeRunCore, len(rows))
+
+	for i, row := range rows {
+		metadata, err := unmarshalMetadata(row.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("db error get loop iteration node runs: %w", err)
+		}
+
+		nodeRuns[i] = &models.WorkflowNodeRunCore{
 			ID:             row.ID,
 			WorkflowRunID:  row.WorkflowRunID,
 			WorkflowNodeID: row.WorkflowNodeID,
@@ -379,12 +1053,138 @@ func (r *workflowRunRepo) GetChildWorkflowNodeRuns(
 			RetryCount:     row.RetryCount,
 			StartedAt:      null.TimeFrom(time.UnixMilli(row.StartedAt.Int64)),
 			FinishedAt:     null.TimeFrom(time.UnixMilli(row.FinishedAt.Int64)),
-			Metadata:       null.StringFrom(string(row.Metadata)),
-			ErrorMessage:   null.StringFrom(row.ErrorMessage.String),
+			Metadata:       metadata,
+			ErrorMessage:   row.ErrorMessage,
+			IterationIndex: row.IterationIndex,
+		}
+	}
+
+	return nodeRuns, nil
+}
+
+func (r *workflowRunRepo) CreateLoopIteration(
+	ctx context.Context,
+	workflowRunID int32,
+	iterationIndex int32,
+	nodeIDs []int32,
+) ([]*models.WorkflowNodeRunCore, error) {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("db error failed to begin tx in create loop iteration: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qtx := r.q.WithTx(tx)
+	nodeRuns := make([]*models.WorkflowNodeRunCore, len(nodeIDs))
+
+	for i, nodeID := range nodeIDs {
+		nr, err := qtx.CreateWorkflowNodeRun(ctx, &dao.CreateWorkflowNodeRunParams{
+			WorkflowRunID:  workflowRunID,
+			WorkflowNodeID: nodeID,
+			Status:         "pending",
+			IterationIndex: iterationIndex,
 		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// another worker already started this iteration
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("db error create loop iteration node run: %w", err)
+		}
+
+		nodeRuns[i] = &models.WorkflowNodeRunCore{
+			ID:             nr.ID,
+			WorkflowRunID:  nr.WorkflowRunID,
+			WorkflowNodeID: nr.WorkflowNodeID,
+			Status:         nr.Status,
+			IterationIndex: nr.IterationIndex,
+		}
 	}
 
-	return workflowRunNodeRuns, nil
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("db error commit tx in create loop iteration: %w", err)
+	}
+
+	return nodeRuns, nil
+}
+
+func (r *workflowRunRepo) MarkWorkflowNodeAsWaiting(
+	ctx context.Context,
+	workflowNodeRunID int32,
+	metadata map[string]any,
+) error {
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow node run metadata: %w", err)
+	}
+
+	if err := r.q.MarkWorkflowNodeAsWaiting(ctx, &dao.MarkWorkflowNodeAsWaitingParams{
+		ID:       workflowNodeRunID,
+		Metadata: b,
+	}); err != nil {
+		return fmt.Errorf("db error mark workflow node as waiting: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) SetWorkflowNodeRunMetadata(
+	ctx context.Context,
+	workflowNodeRunID int32,
+	metadata map[string]any,
+) error {
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow node run metadata: %w", err)
+	}
+
+	if err := r.q.SetWorkflowNodeRunMetadata(ctx, &dao.SetWorkflowNodeRunMetadataParams{
+		ID:       workflowNodeRunID,
+		Metadata: b,
+	}); err != nil {
+		return fmt.Errorf("db error set workflow node run metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) SetWorkflowNodeRunRetryCount(
+	ctx context.Context,
+	workflowNodeRunID int32,
+	retryCount int32,
+) error {
+	if err := r.q.SetWorkflowNodeRunRetryCount(ctx, &dao.SetWorkflowNodeRunRetryCountParams{
+		ID:         workflowNodeRunID,
+		RetryCount: retryCount,
+	}); err != nil {
+		return fmt.Errorf("db error set workflow node run retry count: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workflowRunRepo) CompleteLoopWorkflowNodeRun(
+	ctx context.Context,
+	workflowNodeRunID int32,
+	status string,
+	metadata map[string]any,
+) (bool, error) {
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal workflow node run metadata: %w", err)
+	}
+
+	n, err := r.q.CompleteLoopWorkflowNodeRun(ctx, &dao.CompleteLoopWorkflowNodeRunParams{
+		ID:         workflowNodeRunID,
+		Status:     status,
+		FinishedAt: null.IntFrom(time.Now().UnixMilli()),
+		Metadata:   b,
+	})
+	if err != nil {
+		return false, fmt.Errorf("db error complete loop workflow node run: %w", err)
+	}
+
+	return n > 0, nil
 }
 
 func (r *workflowRunRepo) MarkWorkflowNodeAsRunning(
@@ -408,6 +1208,7 @@ func (r *workflowRunRepo) UpdateWorkflowNodeRunStatus(
 	ctx context.Context,
 	workflowNodeRunID int32,
 	status string,
+	metadata map[string]any,
 	errorMessage *string,
 ) error {
 	errMsg := null.String{}
@@ -415,11 +1216,22 @@ func (r *workflowRunRepo) UpdateWorkflowNodeRunStatus(
 		errMsg = null.StringFrom(*errorMessage)
 	}
 
+	var metadataBytes []byte
+
+	if metadata != nil {
+		b, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal workflow node run metadata: %w", err)
+		}
+
+		metadataBytes = b
+	}
+
 	if err := r.q.UpdateWorkflowNodeRun(ctx, &dao.UpdateWorkflowNodeRunParams{
 		ID:           workflowNodeRunID,
 		Status:       status,
 		FinishedAt:   null.IntFrom(time.Now().UnixMilli()),
-		Metadata:     nil,
+		Metadata:     metadataBytes,
 		ErrorMessage: errMsg,
 	}); err != nil {
 		return fmt.Errorf("db error update workflow node run: %w", err)
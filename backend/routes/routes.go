@@ -37,14 +37,29 @@ func RegisterRoutes(r *gin.Engine, cfg models.AppConfig, ctx context.Context) {
 			timeout.WithHandler(workflowController.UpdateWorkflow),
 		))
 		workflowGroup.PATCH("/:workflowID/archive", workflowController.ArchiveWorkflow)
+		workflowGroup.POST(
+			"/:workflowID/nodes/:nodeID/execute",
+			workflowController.ExecuteWorkflowNode,
+		)
+		workflowGroup.GET("/:workflowID/concurrency", workflowController.GetWorkflowConcurrency)
+		workflowGroup.PUT("/:workflowID/concurrency", workflowController.UpdateWorkflowConcurrency)
+		workflowGroup.GET("/:workflowID/timeout", workflowController.GetWorkflowTimeout)
+		workflowGroup.PUT("/:workflowID/timeout", workflowController.UpdateWorkflowTimeout)
+		workflowGroup.GET("/:workflowID/inputs", workflowController.GetWorkflowInputSchema)
+		workflowGroup.PUT("/:workflowID/inputs", workflowController.UpdateWorkflowInputSchema)
 	}
 
+	r.GET("/api/node-types", workflowController.GetNodeTypes)
+
 	workflowRunController := controllers.NewWorkflowRunController(cfg, ctx)
 	workflowRunGroup := r.Group("/api/workflow-run")
 	{
 		workflowRunGroup.GET("/:runID", workflowRunController.GetWorkflowRun)
+		// gin requires wildcards in the same position to share a name, so POST routes use :id
 		// TODO: add timeout
-		workflowRunGroup.POST("/:workflowID", workflowRunController.RunWorkflow)
+		workflowRunGroup.POST("/:id", workflowRunController.RunWorkflow)
+		workflowRunGroup.POST("/:id/cancel", workflowRunController.CancelWorkflowRun)
+		workflowRunGroup.POST("/:id/resume", workflowRunController.ResumeWorkflowRun)
 	}
 
 	r.GET(
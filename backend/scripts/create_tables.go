@@ -189,11 +189,17 @@ func loadSQLFiles(dir string) ([]string, error) {
 	inOrderOfDependency := []string{
 		"workflow",
 		"workflow_schedule",
+		"workflow_concurrency",
+		"workflow_timeout",
+		"workflow_input_schema",
 		"workflow_node",
 		"workflow_node_ui",
 		"workflow_edge",
+		"workflow_version",
 		"workflow_run",
 		"workflow_node_run",
+		"workflow_task_outbox",
+		"workflow_side_effect",
 		"workflow_calendar",
 		"workflow_email",
 		"oauth_integration",
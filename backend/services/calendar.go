@@ -361,16 +361,30 @@ func (s *WorkflowCalendarService) checkEventChanges(
 		timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 		defer cancel()
 
-		runID, err := s.orchestrator.OrchestrateWorkflow(timeoutCtx, c.UserID, c.WorkflowID)
-		if err != nil || runID == -1 {
+		runID, err := s.orchestrator.OrchestrateWorkflow(
+			timeoutCtx,
+			c.UserID,
+			c.WorkflowID,
+			models.WorkflowRunOptions{
+				TriggerSource:  models.TriggerSourceCalendar,
+				TriggerPayload: calendarEventPayload(c, triggerEvent),
+			},
+		)
+		if errors.Is(err, ErrWorkflowRunSkipped) {
+			// the skipped run is recorded, the event is not retried
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"workflow_id": c.WorkflowID,
+				"event_id":    triggerEvent.Id,
+			}).Info("workflow execution skipped")
+		} else if err != nil || runID == -1 {
 			return fmt.Errorf("workflow execution failed for event %s: %w", triggerEvent.Id, err)
+		} else {
+			s.logger.WithFields(logrus.Fields{
+				"workflow_id": c.WorkflowID,
+				"event_id":    triggerEvent.Id,
+				"run_id":      runID,
+			}).Info("workflow execution started successfully")
 		}
-
-		s.logger.WithFields(logrus.Fields{
-			"workflow_id": c.WorkflowID,
-			"event_id":    triggerEvent.Id,
-			"run_id":      runID,
-		}).Info("workflow execution started successfully")
 	} else {
 		s.logger.WithFields(logrus.Fields{
 			"workflow_id": c.WorkflowID,
@@ -454,6 +468,55 @@ func (s *WorkflowCalendarService) shouldTriggerWorkflow(
 	return true, nil
 }
 
+// calendarEventPayload is the trigger payload of a run started for event, so the run's nodes can
+// reference the event that started it.
+func calendarEventPayload(c *models.WorkflowCalendar, event *calendar.Event) map[string]any {
+	payload := map[string]any{
+		"calendar_id": c.Config.CalendarID,
+		"event_id":    event.Id,
+		"status":      event.Status,
+		"summary":     event.Summary,
+		"description": event.Description,
+		"location":    event.Location,
+		"html_link":   event.HtmlLink,
+		"start":       eventDateTime(event.Start),
+		"end":         eventDateTime(event.End),
+	}
+
+	if event.Organizer != nil {
+		payload["organizer"] = map[string]any{
+			"name":  event.Organizer.DisplayName,
+			"email": event.Organizer.Email,
+		}
+	}
+
+	attendees := make([]any, 0, len(event.Attendees))
+	for _, attendee := range event.Attendees {
+		attendees = append(attendees, map[string]any{
+			"name":            attendee.DisplayName,
+			"email":           attendee.Email,
+			"response_status": attendee.ResponseStatus,
+		})
+	}
+
+	payload["attendees"] = attendees
+
+	return payload
+}
+
+// eventDateTime returns the RFC3339 time of t, or its date for all-day events.
+func eventDateTime(t *calendar.EventDateTime) string {
+	if t == nil {
+		return ""
+	}
+
+	if t.DateTime != "" {
+		return t.DateTime
+	}
+
+	return t.Date
+}
+
 func matchesKeywords(event *calendar.Event, keywords []string) bool {
 	if len(keywords) == 0 {
 		return true
@@ -5,56 +5,109 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"time"
 
+	"github.com/guregu/null/v6"
+	"github.com/jackc/pgx/v5"
 	"github.com/sirupsen/logrus"
 	"github.com/tinyautomator/tinyautomator-core/backend/clients/rabbitmq"
 	"github.com/tinyautomator/tinyautomator-core/backend/clients/redis"
 	"github.com/tinyautomator/tinyautomator-core/backend/internal"
+	"github.com/tinyautomator/tinyautomator-core/backend/internal/expression"
 	handlers "github.com/tinyautomator/tinyautomator-core/backend/internal/handlers/actions"
 	"github.com/tinyautomator/tinyautomator-core/backend/models"
 )
 
+var (
+	ErrWorkflowNodeNotFound = errors.New("workflow node not found")
+	ErrNodeNotExecutable    = errors.New("only action nodes can be executed")
+)
+
 type ExecutorService struct {
 	logger          logrus.FieldLogger
-	rabbitMQClient  rabbitmq.RabbitMQClient
 	redisClient     redis.RedisClient
 	workflowRepo    models.WorkflowRepository
 	workflowRunRepo models.WorkflowRunRepository
 	actionRegistry  *handlers.ActionRegistry
+	orchestrator    models.OrchestratorService
+	limiter         *UsageLimiter
+}
+
+// newActionRegistry registers the handler of every action node type. The executor runs the
+// actions through it, the workflow service validates them when a workflow is saved.
+func newActionRegistry(cfg models.AppConfig) *handlers.ActionRegistry {
+	actionRegistry := handlers.NewActionRegistry(cfg.GetLogger())
+	actionRegistry.Register("send_email", handlers.NewSendEmailHandler(cfg))
+	actionRegistry.Register(internal.NodeTypeCondition, handlers.NewConditionHandler())
+	actionRegistry.Register(internal.NodeTypeForEach, handlers.NewForEachHandler())
+	actionRegistry.Register(internal.NodeTypeDelay, handlers.NewDelayHandler())
+	actionRegistry.Register(internal.NodeTypeRunWorkflow, handlers.NewRunWorkflowHandler())
+
+	return actionRegistry
 }
 
 func NewExecutorService(cfg models.AppConfig) models.ExecutorService {
 	logger := cfg.GetLogger()
-	actionRegistry := handlers.NewActionRegistry(logger)
-	actionRegistry.Register("send_email", handlers.NewSendEmailHandler(cfg))
+	actionRegistry := newActionRegistry(cfg)
 
 	return &ExecutorService{
 		logger:          logger,
-		rabbitMQClient:  cfg.GetRabbitMQClient(),
 		redisClient:     cfg.GetRedisClient(),
 		workflowRepo:    cfg.GetWorkflowRepository(),
 		workflowRunRepo: cfg.GetWorkflowRunRepository(),
 		actionRegistry:  actionRegistry,
+		orchestrator:    NewOrchestratorService(cfg),
+		limiter:         NewUsageLimiter(cfg),
+	}
+}
+
+// getRetryPolicy returns the node's retry policy, falling back to the default when the config
+// holds an invalid one.
+func (s *ExecutorService) getRetryPolicy(workflowNode *models.WorkflowNode) models.RetryPolicy {
+	if workflowNode.Config == nil {
+		return models.DefaultRetryPolicy()
+	}
+
+	policy, err := models.ParseRetryPolicy(*workflowNode.Config)
+	if err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"workflow_id": workflowNode.WorkflowID,
+			"node_id":     workflowNode.ID,
+		}).Warn("invalid retry policy in node config, using default")
 	}
+
+	return policy
 }
 
 func (s *ExecutorService) shouldSkipNodeTaskExecution(
 	ctx context.Context,
 	task *models.WorkflowNodeTask,
+	retryPolicy models.RetryPolicy,
 ) (bool, error) {
 	var shouldSkipExecution bool
 
-	workflowNodeRun, err := s.workflowRunRepo.GetWorkflowNodeRun(ctx, task.RunID, task.NodeID)
+	workflowNodeRun, err := s.workflowRunRepo.GetWorkflowNodeRun(
+		ctx,
+		task.RunID,
+		task.NodeID,
+		task.IterationIndex,
+	)
 	if err != nil {
 		s.logger.WithError(err).Warn("failed to get workflow node run status from db")
 		return false, fmt.Errorf("failed to get workflow node run status from db: %w", err)
 	}
 
-	shouldSkipExecution = workflowNodeRun.Status == "success"
+	shouldSkipExecution = workflowNodeRun.Status == "success" || workflowNodeRun.Status == "skipped"
 	task.RetryCount = workflowNodeRun.RetryCount
 
-	if task.RetryCount == 3 {
+	// a node run left running lost its worker mid-attempt, e.g. to a crash. The attempt is
+	// resumed instead of counted again so it keeps its idempotency key.
+	if workflowNodeRun.Status == "running" {
+		task.RetryCount = max(task.RetryCount-1, 0)
+	}
+
+	if task.RetryCount >= retryPolicy.MaxAttempts {
 		shouldSkipExecution = true
 		return shouldSkipExecution, nil
 	}
@@ -100,9 +153,7 @@ func (s *ExecutorService) shouldFinalizeWorkflowRun(
 	)
 	if err != nil {
 		// cache load failed, need to get the remaining nodes from the database
-		pending := "pending"
-
-		workflowNodeRuns, err := s.workflowRunRepo.GetWorkflowNodeRuns(ctx, task.RunID, &pending)
+		workflowNodeRuns, err := s.workflowRunRepo.GetWorkflowNodeRuns(ctx, task.RunID, nil)
 		if err != nil {
 			return false, fmt.Errorf(
 				"failed to get workflow node runs and count remaining: %w",
@@ -110,13 +161,23 @@ func (s *ExecutorService) shouldFinalizeWorkflowRun(
 			)
 		}
 
-		if len(workflowNodeRuns) == 0 {
-			return true, nil
+		// a foreach node stays running until its iterations have finished, and the iterations
+		// themselves are tracked through it
+		nIDs := []int32{}
+
+		for _, nodeRun := range workflowNodeRuns {
+			if nodeRun.IterationIndex != models.NoIteration {
+				continue
+			}
+
+			switch nodeRun.Status {
+			case "pending", "running", "waiting":
+				nIDs = append(nIDs, nodeRun.WorkflowNodeID)
+			}
 		}
 
-		nIDs := make([]int32, len(workflowNodeRuns))
-		for idx, nodeRun := range workflowNodeRuns {
-			nIDs[idx] = nodeRun.WorkflowNodeID
+		if len(nIDs) == 0 {
+			return true, nil
 		}
 
 		err = s.reinitializeRunningNodeSet(ctx, task.RunID, nIDs)
@@ -124,22 +185,683 @@ func (s *ExecutorService) shouldFinalizeWorkflowRun(
 			s.logger.WithError(err).Warn("failed to reinitialize running node set")
 		}
 
-		r := len(workflowNodeRuns)
+		r := len(nIDs)
 		remaining = &r
 	}
 
 	return *remaining == 0, nil
 }
 
-func (s *ExecutorService) runWorkflowNodeTask(
+// buildRunContext gathers the run level data node configs can reference through templates.
+func (s *ExecutorService) buildRunContext(
 	ctx context.Context,
 	task *models.WorkflowNodeTask,
+	workflowGraph *models.WorkflowGraph,
+) (*expression.RunContext, error) {
+	workflowRun, err := s.workflowRunRepo.GetWorkflowRun(ctx, task.RunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow run: %w", err)
+	}
+
+	workflow, err := s.workflowRepo.GetWorkflow(ctx, task.WorkflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
+
+	var loop *expression.LoopContext
+
+	if body := internal.FindLoopBody(workflowGraph, task.NodeID); body != nil {
+		for _, nodeRun := range workflowRun.Nodes {
+			if nodeRun.WorkflowNodeID != body.LoopNodeID ||
+				nodeRun.IterationIndex != models.NoIteration {
+				continue
+			}
+
+			items, _ := internal.LoopItems(nodeRun)
+			if int(task.IterationIndex) < 0 || int(task.IterationIndex) >= len(items) {
+				return nil, fmt.Errorf("loop iteration %d is out of range", task.IterationIndex)
+			}
+
+			loop = &expression.LoopContext{
+				Index: task.IterationIndex,
+				Item:  items[task.IterationIndex],
+			}
+		}
+	}
+
+	var runError *expression.ErrorContext
+
+	for _, edge := range workflowGraph.Edges {
+		if edge.TargetNodeID != task.NodeID || edge.Label != internal.EdgeLabelOnError {
+			continue
+		}
+
+		for _, nodeRun := range workflowRun.Nodes {
+			if nodeRun.WorkflowNodeID != edge.SourceNodeID || nodeRun.Status != "failed" ||
+				(nodeRun.IterationIndex != models.NoIteration &&
+					nodeRun.IterationIndex != task.IterationIndex) {
+				continue
+			}
+
+			runError = &expression.ErrorContext{
+				NodeID:   nodeRun.WorkflowNodeID,
+				Message:  nodeRun.ErrorMessage.String,
+				Attempts: nodeRun.RetryCount,
+			}
+
+			for _, node := range workflowGraph.Nodes {
+				if node.ID == nodeRun.WorkflowNodeID {
+					runError.NodeType = node.NodeType
+				}
+			}
+		}
+	}
+
+	return &expression.RunContext{
+		RunID:          workflowRun.ID,
+		WorkflowID:     workflowRun.WorkflowID,
+		WorkflowName:   workflow.Name,
+		TriggerSource:  workflowRun.TriggerSource,
+		TriggerPayload: workflowRun.TriggerPayload,
+		TriggeredAt:    workflowRun.CreatedAt,
+		NodeRuns:       workflowRun.Nodes,
+		Inputs:         workflowRun.Inputs,
+		Loop:           loop,
+		Error:          runError,
+	}, nil
+}
+
+// advanceLoop starts the next iterations of the loop and, once all of them have finished,
+// completes the foreach node with their results and moves on to its children. The foreach node
+// fails when an iteration failed, its failure policy then decides how the run goes on.
+func (s *ExecutorService) advanceLoop(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	workflowGraph *models.WorkflowGraph,
+	body *internal.LoopBody,
 ) error {
-	workflowNode, err := s.workflowRepo.GetWorkflowNode(ctx, task.NodeID)
+	loopNodeRun, err := s.workflowRunRepo.GetWorkflowNodeRun(
+		ctx,
+		task.RunID,
+		body.LoopNodeID,
+		models.NoIteration,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to get workflow node: %w", err)
+		return fmt.Errorf("failed to get loop node run: %w", err)
+	}
+
+	kv := logrus.Fields{
+		"user_id":      task.UserID,
+		"workflow_id":  task.WorkflowID,
+		"run_id":       task.RunID,
+		"loop_node_id": body.LoopNodeID,
 	}
 
+	switch loopNodeRun.Status {
+	case "running":
+		var progress *internal.LoopProgress
+
+		// new iterations are created along with the tasks of their root nodes
+		err := s.workflowRunRepo.WithTransaction(
+			ctx,
+			func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+				var err error
+
+				progress, err = internal.AdvanceLoop(
+					ctx,
+					s.logger,
+					txRepo,
+					task.UserID,
+					task.WorkflowID,
+					task.RunID,
+					workflowGraph,
+					loopNodeRun,
+					body,
+				)
+
+				return err
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to advance loop: %w", err)
+		}
+
+		if !progress.Done {
+			return nil
+		}
+
+		status := "success"
+		if progress.Failed > 0 {
+			status = "failed"
+		}
+
+		metadata := maps.Clone(loopNodeRun.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]any)
+		}
+
+		metadata["results"] = progress.Results
+		metadata["failed_iterations"] = progress.Failed
+
+		completed, err := s.completeLoopNodeRun(ctx, workflowGraph, loopNodeRun, status, metadata)
+		if err != nil {
+			return err
+		}
+
+		if completed {
+			kv["status"] = status
+			kv["failed_iterations"] = progress.Failed
+			s.logger.WithFields(kv).Info("all loop iterations finished")
+
+			err := s.redisClient.PublishNodeStatusUpdate(
+				ctx,
+				task.RunID,
+				body.LoopNodeID,
+				status,
+				nil,
+			)
+			if err != nil {
+				s.logger.WithError(err).WithFields(kv).Warn("failed to publish node status update")
+			}
+		}
+
+		loopNodeRun.Status = status
+	case "success", "failed":
+		// completed by an earlier attempt, make sure its children were enqueued
+	default:
+		return nil
+	}
+
+	if loopNodeRun.Status == "failed" &&
+		internal.FailurePolicyOf(workflowGraph, body.LoopNodeID) == internal.FailurePolicyFailRun {
+		s.logger.WithFields(kv).Info("loop iterations failed - failing workflow run")
+
+		return s.finishWorkflowRun(ctx, task, "failed")
+	}
+
+	loopTask := &models.WorkflowNodeTask{
+		UserID:         task.UserID,
+		WorkflowID:     task.WorkflowID,
+		RunID:          task.RunID,
+		NodeID:         body.LoopNodeID,
+		NodeRunID:      loopNodeRun.ID,
+		IterationIndex: models.NoIteration,
+		Status:         loopNodeRun.Status,
+	}
+
+	return s.completeNodeTask(ctx, loopTask, workflowGraph)
+}
+
+// completeLoopNodeRun finishes the running foreach node run in status. A failed one has its
+// attempts used up, the loop is not run again and its children apply its failure policy.
+func (s *ExecutorService) completeLoopNodeRun(
+	ctx context.Context,
+	workflowGraph *models.WorkflowGraph,
+	loopNodeRun *models.WorkflowNodeRunCore,
+	status string,
+	metadata map[string]any,
+) (bool, error) {
+	var completed bool
+
+	err := s.workflowRunRepo.WithTransaction(
+		ctx,
+		func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+			var err error
+
+			completed, err = txRepo.CompleteLoopWorkflowNodeRun(
+				ctx,
+				loopNodeRun.ID,
+				status,
+				metadata,
+			)
+			if err != nil || !completed || status != "failed" {
+				return err
+			}
+
+			retryPolicy := s.getRetryPolicy(workflowGraph.Node(loopNodeRun.WorkflowNodeID))
+
+			return txRepo.SetWorkflowNodeRunRetryCount(ctx, loopNodeRun.ID, retryPolicy.MaxAttempts)
+		},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete loop node run: %w", err)
+	}
+
+	return completed, nil
+}
+
+// waitForDelay parks a delay node run and schedules the task that wakes it up.
+func (s *ExecutorService) waitForDelay(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	output handlers.ActionNodeOutput,
+) error {
+	resumeAt, ok := internal.DelayResumeAt(output)
+	if !ok {
+		return fmt.Errorf("delay node output has no resume time")
+	}
+
+	// the wake-up task is queued along with the waiting status so the node run always wakes up
+	err := s.workflowRunRepo.WithTransaction(
+		ctx,
+		func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+			err := txRepo.MarkWorkflowNodeAsWaiting(ctx, task.NodeRunID, output)
+			if err != nil {
+				return fmt.Errorf("failed to mark node run as waiting: %w", err)
+			}
+
+			return s.scheduleWakeUp(ctx, txRepo, task, resumeAt)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to park delay node run: %w", err)
+	}
+
+	details := map[string]any{
+		internal.DelayResumeAtKey: output[internal.DelayResumeAtKey],
+		"iteration_index":         task.IterationIndex,
+	}
+	err = s.redisClient.PublishNodeStatusUpdate(ctx, task.RunID, task.NodeID, "waiting", details)
+	if err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"run_id":  task.RunID,
+			"node_id": task.NodeID,
+		}).Warn("failed to publish node status update")
+	}
+
+	return nil
+}
+
+// scheduleWakeUp adds the task resuming a waiting delay node run at resumeAt to the outbox of
+// workflowRunRepo.
+func (s *ExecutorService) scheduleWakeUp(
+	ctx context.Context,
+	workflowRunRepo models.WorkflowRunRepository,
+	task *models.WorkflowNodeTask,
+	resumeAt time.Time,
+) error {
+	taskBytes, err := models.BuildWorkflowNodeTaskPayload(
+		task.UserID,
+		task.WorkflowID,
+		task.RunID,
+		task.NodeID,
+		task.NodeRunID,
+		task.IterationIndex,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to marshal wake-up task: %w", err)
+	}
+
+	if err := workflowRunRepo.AddOutboxTask(ctx, taskBytes, time.Until(resumeAt)); err != nil {
+		return fmt.Errorf("failed to schedule wake-up task: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"run_id":          task.RunID,
+		"node_id":         task.NodeID,
+		"iteration_index": task.IterationIndex,
+		"resume_at":       resumeAt,
+	}).Info("delay node waiting")
+
+	return nil
+}
+
+// resumeDelay completes a waiting delay node run once its resume time has passed. It reports
+// true while the node run keeps waiting: delays longer than the broker allows take several
+// wake-ups.
+func (s *ExecutorService) resumeDelay(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+) (bool, error) {
+	nodeRun, err := s.workflowRunRepo.GetWorkflowNodeRun(
+		ctx,
+		task.RunID,
+		task.NodeID,
+		task.IterationIndex,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to get workflow node run: %w", err)
+	}
+
+	if nodeRun.Status != "waiting" {
+		return false, nil
+	}
+
+	task.NodeRunID = nodeRun.ID
+
+	resumeAt, ok := internal.DelayResumeAt(nodeRun.Metadata)
+	if !ok {
+		return false, fmt.Errorf("waiting node run %d has no resume time", nodeRun.ID)
+	}
+
+	if time.Now().Before(resumeAt) {
+		return true, s.scheduleWakeUp(ctx, s.workflowRunRepo, task, resumeAt)
+	}
+
+	err = s.workflowRunRepo.UpdateWorkflowNodeRunStatus(
+		ctx,
+		nodeRun.ID,
+		"success",
+		nodeRun.Metadata,
+		nil,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark node run as success: %w", err)
+	}
+
+	err = s.redisClient.PublishNodeStatusUpdate(ctx, task.RunID, task.NodeID, "success", nil)
+	if err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"run_id":  task.RunID,
+			"node_id": task.NodeID,
+		}).Warn("failed to publish node status update")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"run_id":          task.RunID,
+		"node_id":         task.NodeID,
+		"iteration_index": task.IterationIndex,
+	}).Info("delay node resumed")
+
+	return false, nil
+}
+
+// startSubWorkflow starts the workflow a run_workflow node points at as a child of the current
+// run. When the node waits on the child, it is parked before the child starts so even a child
+// that finishes right away finds it waiting.
+func (s *ExecutorService) startSubWorkflow(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	workflowRun *models.WorkflowRunCore,
+	output handlers.ActionNodeOutput,
+) (handlers.ActionNodeOutput, error) {
+	if workflowRun.Depth >= internal.MaxSubWorkflowDepth {
+		return nil, fmt.Errorf(
+			"sub-workflow runs cannot be nested deeper than %d",
+			internal.MaxSubWorkflowDepth,
+		)
+	}
+
+	workflowID, _ := output["workflow_id"].(int32)
+	inputs, _ := output["inputs"].(map[string]any)
+	wait, _ := output["wait"].(bool)
+
+	workflow, err := s.workflowRepo.GetWorkflow(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow %d: %w", workflowID, err)
+	}
+
+	if workflow.UserID != task.UserID {
+		return nil, fmt.Errorf("workflow %d not found", workflowID)
+	}
+
+	// a redelivered task finds the child run its attempt already started
+	nodeRun, err := s.workflowRunRepo.GetWorkflowNodeRunByID(ctx, task.NodeRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sub-workflow node run: %w", err)
+	}
+
+	if childRunID, ok := nodeRun.Metadata["child_run_id"].(float64); ok {
+		s.logger.WithFields(logrus.Fields{
+			"run_id":       task.RunID,
+			"node_id":      task.NodeID,
+			"workflow_id":  workflowID,
+			"child_run_id": childRunID,
+		}).Info("sub-workflow run already started")
+
+		result := maps.Clone(output)
+		result["child_run_id"] = int32(childRunID)
+
+		return result, nil
+	}
+
+	if wait {
+		err := s.workflowRunRepo.MarkWorkflowNodeAsWaiting(ctx, task.NodeRunID, output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark node run as waiting: %w", err)
+		}
+	}
+
+	childRunID, err := s.orchestrator.OrchestrateWorkflow(
+		ctx,
+		task.UserID,
+		workflowID,
+		models.WorkflowRunOptions{
+			DryRun:          workflowRun.DryRun,
+			Inputs:          inputs,
+			ParentRunID:     null.Int32From(workflowRun.ID),
+			ParentNodeRunID: null.Int32From(task.NodeRunID),
+			Depth:           workflowRun.Depth + 1,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sub-workflow: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"run_id":       task.RunID,
+		"node_id":      task.NodeID,
+		"workflow_id":  workflowID,
+		"child_run_id": childRunID,
+		"wait":         wait,
+	}).Info("started sub-workflow run")
+
+	result := maps.Clone(output)
+	result["child_run_id"] = childRunID
+
+	return result, nil
+}
+
+// isFailedSubWorkflow reports whether the run_workflow node run was failed by a child run that
+// did not succeed, and its failure was not handled yet.
+func isFailedSubWorkflow(nodeRun *models.WorkflowNodeRunCore, retryPolicy models.RetryPolicy) bool {
+	_, hasChild := nodeRun.Metadata["child_run_id"]
+
+	return nodeRun.Status == "failed" && hasChild && nodeRun.RetryCount < retryPolicy.MaxAttempts
+}
+
+// failSubWorkflowNode fails the run_workflow node for good once its child run did not succeed.
+// Another attempt would start another child run and repeat the side effects of the first, so
+// the node's failure policy is applied right away.
+func (s *ExecutorService) failSubWorkflowNode(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	workflowGraph *models.WorkflowGraph,
+	nodeRun *models.WorkflowNodeRunCore,
+	retryPolicy models.RetryPolicy,
+) error {
+	task.Status = "failed"
+	task.RetryCount = retryPolicy.MaxAttempts
+
+	err := s.workflowRunRepo.SetWorkflowNodeRunRetryCount(ctx, nodeRun.ID, task.RetryCount)
+	if err != nil {
+		return fmt.Errorf("failed to exhaust sub-workflow node attempts: %w", err)
+	}
+
+	kv := logrus.Fields{
+		"user_id":         task.UserID,
+		"workflow_id":     task.WorkflowID,
+		"run_id":          task.RunID,
+		"node_id":         task.NodeID,
+		"node_run_id":     nodeRun.ID,
+		"iteration_index": task.IterationIndex,
+		"child_run_id":    nodeRun.Metadata["child_run_id"],
+		"child_status":    nodeRun.Metadata["status"],
+	}
+
+	taskErr := fmt.Errorf("task execution failed: %s", nodeRun.ErrorMessage.String)
+
+	return s.failNodeTask(ctx, task, workflowGraph, taskErr, kv)
+}
+
+// finishWorkflowRun completes the run, resumes the run_workflow node waiting on it if any, and
+// starts the workflow's queued runs. The run is completed in the same transaction that resumes
+// the parent node, so the parent never keeps waiting on a finished run.
+func (s *ExecutorService) finishWorkflowRun(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	status string,
+) error {
+	var resumed *internal.ResumedNodeRun
+
+	err := s.workflowRunRepo.WithTransaction(
+		ctx,
+		func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+			if err := txRepo.CompleteWorkflowRun(ctx, task.RunID, status); err != nil {
+				return err
+			}
+
+			var err error
+
+			resumed, err = internal.CompleteParentNodeRun(
+				ctx,
+				txRepo,
+				task.UserID,
+				task.RunID,
+				status,
+			)
+
+			return err
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete workflow run: %w", err)
+	}
+
+	internal.PublishResumedNodeRun(ctx, s.logger, s.redisClient, resumed)
+
+	// the run no longer takes up one of the workflow's concurrent runs
+	if err := s.orchestrator.StartQueuedWorkflowRuns(ctx, task.WorkflowID); err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"run_id":      task.RunID,
+			"workflow_id": task.WorkflowID,
+		}).Error("failed to start queued workflow runs")
+	}
+
+	return nil
+}
+
+// executeAction runs the node's action for the task's attempt. Actions with side effects run
+// once per attempt: an attempt that already completed, e.g. before its worker crashed, gets the
+// output it had then.
+func (s *ExecutorService) executeAction(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	nodeType string,
+	input handlers.ActionNodeInput,
+) (handlers.ActionNodeOutput, error) {
+	sideEffects := s.actionRegistry.HasSideEffects(nodeType)
+
+	if sideEffects {
+		output, err := s.getCompletedSideEffect(ctx, task, nodeType, input)
+		if err != nil || output != nil {
+			return output, err
+		}
+	}
+
+	if err := s.limiter.AllowAction(ctx, task.UserID); err != nil {
+		return nil, err
+	}
+
+	output, err := s.actionRegistry.Execute(ctx, task.UserID, nodeType, input)
+	if err != nil || !sideEffects {
+		return output, err
+	}
+
+	s.recordSideEffect(ctx, task, input.IdempotencyKey, output)
+
+	return output, nil
+}
+
+// getCompletedSideEffect returns the output of the attempt's side effect when it already
+// happened, or nil. The handler is asked when none was recorded.
+func (s *ExecutorService) getCompletedSideEffect(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	nodeType string,
+	input handlers.ActionNodeInput,
+) (handlers.ActionNodeOutput, error) {
+	output, err := s.workflowRunRepo.GetSideEffect(ctx, input.IdempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recorded side effect: %w", err)
+	}
+
+	if output == nil {
+		found, ok, err := s.actionRegistry.FindCompleted(ctx, task.UserID, nodeType, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up completed side effect: %w", err)
+		}
+
+		if !ok {
+			return nil, nil
+		}
+
+		output = found
+		s.recordSideEffect(ctx, task, input.IdempotencyKey, output)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"run_id":          task.RunID,
+		"node_id":         task.NodeID,
+		"idempotency_key": input.IdempotencyKey,
+	}).Info("attempt already completed its side effect, reusing its output")
+
+	return output, nil
+}
+
+func (s *ExecutorService) recordSideEffect(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	idempotencyKey string,
+	output handlers.ActionNodeOutput,
+) {
+	err := s.workflowRunRepo.RecordSideEffect(ctx, task.RunID, idempotencyKey, output)
+	if err != nil {
+		// the handler can still find the side effect by its idempotency key
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"run_id":          task.RunID,
+			"node_id":         task.NodeID,
+			"idempotency_key": idempotencyKey,
+		}).Warn("failed to record side effect")
+	}
+}
+
+// renderNodeConfig resolves the {{ ... }} expressions in a node config against scope. The
+// expression of a condition node is kept as written, its handler resolves the references in it
+// when evaluating it.
+func renderNodeConfig(
+	nodeType string,
+	config map[string]any,
+	scope map[string]any,
+) (map[string]any, error) {
+	if nodeType != internal.NodeTypeCondition {
+		return expression.RenderConfig(config, scope)
+	}
+
+	rest := maps.Clone(config)
+	delete(rest, internal.ConditionExpressionKey)
+
+	rendered, err := expression.RenderConfig(rest, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if expr, ok := config[internal.ConditionExpressionKey]; ok {
+		rendered[internal.ConditionExpressionKey] = expr
+	}
+
+	return rendered, nil
+}
+
+func (s *ExecutorService) runWorkflowNodeTask(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	workflowRun *models.WorkflowRunCore,
+	workflowGraph *models.WorkflowGraph,
+	workflowNode *models.WorkflowNode,
+	retryPolicy models.RetryPolicy,
+	parentNodeRuns []*models.LinkedWorkflowNodeRun,
+) error {
 	config := make(map[string]any)
 	if workflowNode.Config != nil {
 		config = *workflowNode.Config
@@ -154,14 +876,16 @@ func (s *ExecutorService) runWorkflowNodeTask(
 	}
 
 	kv := logrus.Fields{
-		"user_id":       task.UserID,
-		"workflow_id":   task.WorkflowID,
-		"run_id":        task.RunID,
-		"node_id":       task.NodeID,
-		"node_run_id":   task.NodeRunID,
-		"node_type":     workflowNode.NodeType,
-		"node_category": workflowNode.Category,
-		"node_config":   config,
+		"user_id":         task.UserID,
+		"workflow_id":     task.WorkflowID,
+		"run_id":          task.RunID,
+		"node_id":         task.NodeID,
+		"node_run_id":     task.NodeRunID,
+		"node_type":       workflowNode.NodeType,
+		"node_category":   workflowNode.Category,
+		"node_config":     config,
+		"dry_run":         workflowRun.DryRun,
+		"iteration_index": task.IterationIndex,
 	}
 
 	s.logger.WithFields(kv).Info("executing workflow node")
@@ -177,30 +901,124 @@ func (s *ExecutorService) runWorkflowNodeTask(
 		s.logger.WithError(err).WithFields(kv).Warn("failed to publish node status update")
 	}
 
-	doTask := func() error {
-		if err := s.actionRegistry.Execute(task.UserID, workflowNode.NodeType, handlers.ActionNodeInput{
-			Config: config,
-		}); err != nil {
-			return fmt.Errorf("failed to execute %s action: %w", workflowNode.NodeType, err)
+	parentOutputs := make(map[int32]handlers.ActionNodeOutput, len(parentNodeRuns))
+	for _, parentNodeRun := range parentNodeRuns {
+		parentOutputs[parentNodeRun.WorkflowNodeID] = parentNodeRun.Metadata
+
+		// failed parents have no output, their children get the failure instead
+		if parentNodeRun.Status == "failed" {
+			parentOutputs[parentNodeRun.WorkflowNodeID] = handlers.ActionNodeOutput{
+				"error":    parentNodeRun.ErrorMessage.String,
+				"attempts": parentNodeRun.RetryCount,
+			}
 		}
+	}
 
-		return nil
+	var loopBody *internal.LoopBody
+	if workflowNode.NodeType == internal.NodeTypeForEach {
+		loopBody = internal.GetLoopBodies(workflowGraph)[workflowNode.ID]
+	}
+
+	waitsOnChild := false
+
+	doTask := func() (handlers.ActionNodeOutput, error) {
+		runContext, err := s.buildRunContext(ctx, task, workflowGraph)
+		if err != nil {
+			return nil, err
+		}
+
+		scope := runContext.Scope()
+
+		renderedConfig, err := renderNodeConfig(workflowNode.NodeType, config, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s config: %w", workflowNode.NodeType, err)
+		}
+
+		input := handlers.ActionNodeInput{
+			Config:         renderedConfig,
+			ParentOutputs:  parentOutputs,
+			Scope:          scope,
+			IdempotencyKey: task.IdempotencyKey(),
+		}
+
+		var output handlers.ActionNodeOutput
+		if workflowRun.DryRun {
+			output, err = s.actionRegistry.Simulate(ctx, task.UserID, workflowNode.NodeType, input)
+		} else {
+			output, err = s.executeAction(ctx, task, workflowNode.NodeType, input)
+		}
+
+		if errors.Is(err, handlers.ErrActionTimedOut) {
+			// returned as is so the recorded error message starts with timed_out
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to execute %s action: %w", workflowNode.NodeType, err)
+		}
+
+		if workflowNode.NodeType == internal.NodeTypeForEach && loopBody == nil {
+			return nil, fmt.Errorf("foreach node has no %s edges", internal.EdgeLabelEach)
+		}
+
+		if workflowNode.NodeType == internal.NodeTypeRunWorkflow {
+			waitsOnChild, _ = output["wait"].(bool)
+			return s.startSubWorkflow(ctx, task, workflowRun, output)
+		}
+
+		return output, nil
 	}
 
 	var taskErr error
 
-	if err := doTask(); err != nil {
+	if output, err := doTask(); err != nil {
 		s.logger.WithFields(kv).WithError(err).Warn("workflow node execution failed")
 
 		errMsg := err.Error()
-		if err := s.workflowRunRepo.UpdateWorkflowNodeRunStatus(ctx, task.NodeRunID, "failed", &errMsg); err != nil {
+		if err := s.workflowRunRepo.UpdateWorkflowNodeRunStatus(ctx, task.NodeRunID, "failed", nil, &errMsg); err != nil {
 			return fmt.Errorf("failed to mark node run as failed: %w", err)
 		}
 
 		task.Status = "failed"
 		taskErr = fmt.Errorf("task execution failed: %w", err)
+	} else if loopBody != nil {
+		// the foreach node stays running until all of its iterations have finished
+		err := s.workflowRunRepo.SetWorkflowNodeRunMetadata(ctx, task.NodeRunID, output)
+		if err != nil {
+			return fmt.Errorf("failed to record loop items: %w", err)
+		}
+
+		task.Status = "running"
+
+		s.logger.WithFields(kv).Info("starting loop iterations")
+
+		return s.advanceLoop(ctx, task, workflowGraph, loopBody)
+	} else if workflowNode.NodeType == internal.NodeTypeDelay && !workflowRun.DryRun {
+		// the delay node waits for a delayed task instead of holding on to the worker
+		task.Status = "waiting"
+
+		return s.waitForDelay(ctx, task, output)
+	} else if waitsOnChild {
+		// the node was parked before the child run started, the child run resumes it
+		task.Status = "waiting"
+
+		details := map[string]any{
+			"child_run_id":    output["child_run_id"],
+			"iteration_index": task.IterationIndex,
+		}
+		if err := s.redisClient.PublishNodeStatusUpdate(
+			ctx,
+			task.RunID,
+			task.NodeID,
+			"waiting",
+			details,
+		); err != nil {
+			s.logger.WithError(err).WithFields(kv).Warn("failed to publish node status update")
+		}
+
+		s.logger.WithFields(kv).Info("waiting for sub-workflow run")
+
+		return nil
 	} else {
-		if err := s.workflowRunRepo.UpdateWorkflowNodeRunStatus(ctx, task.NodeRunID, "success", nil); err != nil {
+		if err := s.workflowRunRepo.UpdateWorkflowNodeRunStatus(ctx, task.NodeRunID, "success", output, nil); err != nil {
 			return fmt.Errorf("failed to mark node run as success: %w", err)
 		}
 
@@ -210,8 +1028,9 @@ func (s *ExecutorService) runWorkflowNodeTask(
 	}
 
 	statusDetails := map[string]interface{}{
-		"message": fmt.Sprintf("Node processing finished. %s", workflowNode.NodeType),
-		"config":  workflowNode.Config,
+		"message":         fmt.Sprintf("Node processing finished. %s", workflowNode.NodeType),
+		"config":          workflowNode.Config,
+		"iteration_index": task.IterationIndex,
 	}
 	if errPub := s.redisClient.PublishNodeStatusUpdate(ctx, task.RunID, task.NodeID, task.Status, statusDetails); errPub != nil {
 		s.logger.WithError(errPub).WithFields(logrus.Fields{
@@ -223,43 +1042,190 @@ func (s *ExecutorService) runWorkflowNodeTask(
 
 	kv["task_err"] = taskErr
 	kv["retry_count"] = task.RetryCount
+	kv["max_attempts"] = retryPolicy.MaxAttempts
 
-	// TODO: change this
-	if taskErr != nil && task.RetryCount == 3 {
-		s.logger.WithFields(kv).
-			Info("node task failed after max retries - should fail workflow run")
+	if taskErr == nil {
+		return nil
+	}
 
-		err = s.workflowRunRepo.CompleteWorkflowRun(ctx, task.RunID, "failed")
+	// another attempt would fail the same way, e.g. on a config that rendered to invalid values
+	var permanentErr *rabbitmq.PermanentError
+	if errors.As(taskErr, &permanentErr) && task.RetryCount < retryPolicy.MaxAttempts {
+		task.RetryCount = retryPolicy.MaxAttempts
+
+		err := s.workflowRunRepo.SetWorkflowNodeRunRetryCount(ctx, task.NodeRunID, task.RetryCount)
 		if err != nil {
-			return fmt.Errorf("failed to complete workflow run: %w", err)
+			return fmt.Errorf("failed to exhaust node attempts: %w", err)
+		}
+
+		kv["retry_count"] = task.RetryCount
+	}
+
+	if task.RetryCount < retryPolicy.MaxAttempts {
+		return &rabbitmq.RetryableError{
+			Err:   taskErr,
+			Delay: retryPolicy.Delay(task.RetryCount),
+		}
+	}
+
+	return s.failNodeTask(ctx, task, workflowGraph, taskErr, kv)
+}
+
+// failNodeTask handles a node that failed all of its attempts as its failure policy says: the run
+// carries on past the node, or finishes as failed.
+func (s *ExecutorService) failNodeTask(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	workflowGraph *models.WorkflowGraph,
+	taskErr error,
+	kv logrus.Fields,
+) error {
+	failurePolicy := internal.FailurePolicyOf(workflowGraph, task.NodeID)
+	kv["on_failure"] = failurePolicy
+
+	// nodes that continue or follow their on_error edges leave the run going, the children of
+	// the node are resolved like after a success
+	if failurePolicy != internal.FailurePolicyFailRun {
+		s.logger.WithFields(kv).
+			Info("node task failed after max retries - applying its failure policy")
+
+		if err := s.finishNodeTask(ctx, task, workflowGraph); err != nil {
+			return fmt.Errorf("failed to finish failed node task: %w", err)
+		}
+
+		return &rabbitmq.PermanentError{Err: taskErr}
+	}
+
+	// a loop that tolerates failures finishes the failed iteration and fails once all of its
+	// iterations finished
+	if task.IterationIndex != models.NoIteration &&
+		internal.LoopToleratesFailure(workflowGraph, task.NodeID) {
+		s.logger.WithFields(kv).
+			Info("node task failed after max retries - finishing its loop iteration")
+
+		if err := s.finishNodeTask(ctx, task, workflowGraph); err != nil {
+			return fmt.Errorf("failed to finish failed loop iteration: %w", err)
 		}
+
+		return &rabbitmq.PermanentError{Err: taskErr}
+	}
+
+	// children joining with any or all_settled still run after a permanent failure, the run then
+	// finishes as failed once they are done. Other loop iterations fail the run right away.
+	if task.IterationIndex == models.NoIteration &&
+		internal.ToleratesFailure(workflowGraph, task.NodeID) {
+		s.logger.WithFields(kv).
+			Info("node task failed after max retries - continuing with its joining children")
+
+		if err := s.completeNodeTask(ctx, task, workflowGraph); err != nil {
+			return fmt.Errorf("failed to complete failed node task: %w", err)
+		}
+
+		return &rabbitmq.PermanentError{Err: taskErr}
+	}
+
+	s.logger.WithFields(kv).
+		Info("node task failed after max retries - failing workflow run")
+
+	if err := s.finishWorkflowRun(ctx, task, "failed"); err != nil {
+		return err
 	}
 
-	return taskErr
+	return &rabbitmq.PermanentError{Err: taskErr}
 }
 
 func (s *ExecutorService) ExecuteWorkflowNode(ctx context.Context, msg []byte) error {
-	var task *models.WorkflowNodeTask
-	if err := json.Unmarshal(msg, &task); err != nil {
+	// tasks published before loops existed carry no iteration index
+	task := &models.WorkflowNodeTask{IterationIndex: models.NoIteration}
+	if err := json.Unmarshal(msg, task); err != nil {
 		return fmt.Errorf("failed to unmarshal task: %w", err)
 	}
 
-	parentNodeRuns, err := s.workflowRunRepo.GetParentWorkflowNodeRuns(ctx, task.RunID, task.NodeID)
+	workflowRun, err := s.workflowRunRepo.GetWorkflowRunCore(ctx, task.RunID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow run: %w", err)
+	}
+
+	// cancelled (or otherwise finished) runs must not execute or enqueue any more nodes
+	if workflowRun.Status != "running" {
+		s.logger.WithFields(logrus.Fields{
+			"user_id":     task.UserID,
+			"workflow_id": task.WorkflowID,
+			"run_id":      task.RunID,
+			"node_id":     task.NodeID,
+			"run_status":  workflowRun.Status,
+		}).Info("workflow run is not running, skipping node task")
+
+		return nil
+	}
+
+	parentNodeRuns, err := s.workflowRunRepo.GetParentWorkflowNodeRuns(
+		ctx,
+		task.RunID,
+		task.NodeID,
+		task.IterationIndex,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to get parent workflow node runs: %w", err)
 	}
 
-	// if any parent node run is failed, we defer the execution to when the parent succeeds and queues the child again
-	for _, parentNodeRun := range parentNodeRuns {
-		if parentNodeRun.Status != "success" {
-			s.logger.WithFields(logrus.Fields{
-				"user_id":        task.UserID,
-				"workflow_id":    task.WorkflowID,
-				"run_id":         task.RunID,
-				"node_id":        task.NodeID,
-				"parent_node_id": parentNodeRun.WorkflowNodeID,
-			}).Info("blocked by parent node run")
+	// the run executes the version it is pinned to, not the workflow as it is now
+	workflowGraph, err := s.workflowRepo.GetWorkflowRunGraph(ctx, task.RunID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow run graph: %w", err)
+	}
+
+	// if the parents do not satisfy the node's join yet, we defer the execution to when the
+	// remaining parents finish and queue the child again
+	joinMode := internal.JoinModeOf(workflowGraph, task.NodeID)
+	if internal.Join(workflowGraph, joinMode, parentNodeRuns) != internal.JoinRun {
+		s.logger.WithFields(logrus.Fields{
+			"user_id":         task.UserID,
+			"workflow_id":     task.WorkflowID,
+			"run_id":          task.RunID,
+			"node_id":         task.NodeID,
+			"iteration_index": task.IterationIndex,
+			"join":            joinMode,
+		}).Info("blocked by parent node runs")
 
+		return nil
+	}
+
+	workflowNode := workflowGraph.Node(task.NodeID)
+	if workflowNode == nil {
+		return fmt.Errorf("node %d is not part of the run's workflow version", task.NodeID)
+	}
+
+	retryPolicy := s.getRetryPolicy(workflowNode)
+
+	if workflowNode.NodeType == internal.NodeTypeRunWorkflow {
+		nodeRun, err := s.workflowRunRepo.GetWorkflowNodeRun(
+			ctx,
+			task.RunID,
+			task.NodeID,
+			task.IterationIndex,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get sub-workflow node run: %w", err)
+		}
+
+		// redelivered tasks must not start the child run again
+		if nodeRun.Status == "waiting" {
+			return nil
+		}
+
+		if isFailedSubWorkflow(nodeRun, retryPolicy) {
+			return s.failSubWorkflowNode(ctx, task, workflowGraph, nodeRun, retryPolicy)
+		}
+	}
+
+	if workflowNode.NodeType == internal.NodeTypeDelay {
+		waiting, err := s.resumeDelay(ctx, task)
+		if err != nil {
+			return fmt.Errorf("failed to resume delay node: %w", err)
+		}
+
+		if waiting {
 			return nil
 		}
 	}
@@ -268,17 +1234,31 @@ func (s *ExecutorService) ExecuteWorkflowNode(ctx context.Context, msg []byte) e
 	// 1. already completed the node task
 	// 2. failed on the publish child nodes step
 	// 3. OR exceeded the max retry count
-	shouldSkipExecution, err := s.shouldSkipNodeTaskExecution(ctx, task)
+	shouldSkipExecution, err := s.shouldSkipNodeTaskExecution(ctx, task, retryPolicy)
 	if err != nil {
 		// not ideal - we might end up processing the task > 1 time
 		return fmt.Errorf("failed to check if node task is already completed: %w", err)
 	}
 
 	if !shouldSkipExecution {
-		err := s.runWorkflowNodeTask(ctx, task)
+		err := s.runWorkflowNodeTask(
+			ctx,
+			task,
+			workflowRun,
+			workflowGraph,
+			workflowNode,
+			retryPolicy,
+			parentNodeRuns,
+		)
 		if err != nil {
 			return fmt.Errorf("failed to run workflow node task: %w", err)
 		}
+
+		// a foreach node that started its iterations is completed by the last of them, a delay
+		// node by its wake-up task
+		if task.Status == "running" || task.Status == "waiting" {
+			return nil
+		}
 	} else {
 		s.logger.WithFields(logrus.Fields{
 			"user_id":     task.UserID,
@@ -286,24 +1266,79 @@ func (s *ExecutorService) ExecuteWorkflowNode(ctx context.Context, msg []byte) e
 			"run_id":      task.RunID,
 			"node_id":     task.NodeID,
 			"retry_count": task.RetryCount,
-			"max_retries": 3,
+			"max_retries": retryPolicy.MaxAttempts,
 		}).Info("node task marked as should skip execution")
 	}
 
-	err = internal.EnqueueChildNodes(
+	return s.finishNodeTask(ctx, task, workflowGraph)
+}
+
+// finishNodeTask moves the run past a finished node: it completes the node task outside of loops
+// and moves the loop on inside them.
+func (s *ExecutorService) finishNodeTask(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	workflowGraph *models.WorkflowGraph,
+) error {
+	if task.IterationIndex == models.NoIteration {
+		return s.completeNodeTask(ctx, task, workflowGraph)
+	}
+
+	if err := s.enqueueChildNodes(ctx, task, workflowGraph, task.IterationIndex); err != nil {
+		return err
+	}
+
+	body := internal.FindLoopBody(workflowGraph, task.NodeID)
+	if body == nil {
+		return fmt.Errorf("node %d is not part of a loop", task.NodeID)
+	}
+
+	return s.advanceLoop(ctx, task, workflowGraph, body)
+}
+
+// enqueueChildNodes resolves the children of a finished node in one transaction, so the skipped
+// children and the tasks of the ready ones are recorded together.
+func (s *ExecutorService) enqueueChildNodes(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	workflowGraph *models.WorkflowGraph,
+	iterationIndex int32,
+) error {
+	err := s.workflowRunRepo.WithTransaction(
 		ctx,
-		s.logger,
-		s.workflowRunRepo,
-		s.rabbitMQClient,
-		task.UserID,
-		task.WorkflowID,
-		task.NodeID,
-		task.RunID,
+		func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+			return internal.EnqueueChildNodes(
+				ctx,
+				s.logger,
+				txRepo,
+				s.redisClient,
+				workflowGraph,
+				task.UserID,
+				task.WorkflowID,
+				task.NodeID,
+				task.RunID,
+				iterationIndex,
+			)
+		},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to enqueue child nodes: %w", err)
 	}
 
+	return nil
+}
+
+// completeNodeTask enqueues the children of a finished node and completes the workflow run once
+// no nodes remain.
+func (s *ExecutorService) completeNodeTask(
+	ctx context.Context,
+	task *models.WorkflowNodeTask,
+	workflowGraph *models.WorkflowGraph,
+) error {
+	if err := s.enqueueChildNodes(ctx, task, workflowGraph, models.NoIteration); err != nil {
+		return err
+	}
+
 	shouldFinalize, err := s.shouldFinalizeWorkflowRun(ctx, task)
 	if err != nil {
 		return fmt.Errorf("failed to check if workflow run should be finalized: %w", err)
@@ -318,15 +1353,14 @@ func (s *ExecutorService) ExecuteWorkflowNode(ctx context.Context, msg []byte) e
 		status := "success"
 
 		for _, nodeRun := range workflowNodeRuns {
-			if nodeRun.Status == "failed" {
+			if nodeRun.Status == "failed" && !internal.IsFailureHandled(workflowGraph, nodeRun) {
 				status = "failed"
 				break
 			}
 		}
 
-		err = s.workflowRunRepo.CompleteWorkflowRun(ctx, task.RunID, status)
-		if err != nil {
-			return fmt.Errorf("failed to complete workflow run: %w", err)
+		if err := s.finishWorkflowRun(ctx, task, status); err != nil {
+			return err
 		}
 
 		s.logger.WithFields(logrus.Fields{
@@ -340,4 +1374,106 @@ func (s *ExecutorService) ExecuteWorkflowNode(ctx context.Context, msg []byte) e
 	return nil
 }
 
+func (s *ExecutorService) ExecuteSingleNode(
+	ctx context.Context,
+	userID string,
+	workflowID int32,
+	nodeID int32,
+	parentOutputs map[int32]map[string]any,
+) (*models.NodeExecutionResult, error) {
+	workflowNode, err := s.workflowRepo.GetWorkflowNode(ctx, nodeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWorkflowNodeNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get workflow node: %w", err)
+	}
+
+	if workflowNode.WorkflowID != workflowID {
+		return nil, ErrWorkflowNodeNotFound
+	}
+
+	if workflowNode.Category != "action" {
+		return nil, ErrNodeNotExecutable
+	}
+
+	workflow, err := s.workflowRepo.GetWorkflow(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
+
+	config := make(map[string]any)
+	if workflowNode.Config != nil {
+		config = *workflowNode.Config
+	}
+
+	// the mock outputs stand in for successful parent node runs
+	nodeRuns := make([]*models.WorkflowNodeRunCore, 0, len(parentOutputs))
+	outputs := make(map[int32]handlers.ActionNodeOutput, len(parentOutputs))
+
+	for parentID, output := range parentOutputs {
+		nodeRuns = append(nodeRuns, &models.WorkflowNodeRunCore{
+			WorkflowNodeID: parentID,
+			IterationIndex: models.NoIteration,
+			Status:         "success",
+			Metadata:       output,
+		})
+		outputs[parentID] = output
+	}
+
+	runContext := &expression.RunContext{
+		WorkflowID:    workflowID,
+		WorkflowName:  workflow.Name,
+		TriggerSource: models.TriggerSourceManual,
+		TriggeredAt:   time.Now(),
+		NodeRuns:      nodeRuns,
+	}
+	scope := runContext.Scope()
+
+	result := &models.NodeExecutionResult{
+		NodeID:   workflowNode.ID,
+		NodeType: workflowNode.NodeType,
+	}
+
+	// a single node is an action like any other, it is limited the same way
+	if err := s.limiter.AllowAction(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	output, err := func() (handlers.ActionNodeOutput, error) {
+		renderedConfig, err := renderNodeConfig(workflowNode.NodeType, config, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s config: %w", workflowNode.NodeType, err)
+		}
+
+		return s.actionRegistry.Execute(ctx, userID, workflowNode.NodeType, handlers.ActionNodeInput{
+			Config:        renderedConfig,
+			ParentOutputs: outputs,
+			Scope:         scope,
+		})
+	}()
+
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		result.Status = "failed"
+		result.Error = null.StringFrom(err.Error())
+	} else {
+		result.Status = "success"
+		result.Output = output
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"workflow_id": workflowID,
+		"node_id":     nodeID,
+		"node_type":   workflowNode.NodeType,
+		"status":      result.Status,
+		"duration_ms": result.DurationMs,
+	}).Info("executed single workflow node")
+
+	return result, nil
+}
+
 var _ models.ExecutorService = (*ExecutorService)(nil)
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tinyautomator/tinyautomator-core/backend/internal"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// reconcileBatchSize is how many idle node runs one pass of the reconciler looks at most.
+const reconcileBatchSize = 500
+
+// nodeRunsReconciled counts the node run tasks the reconciler republished.
+var nodeRunsReconciled = expvar.NewInt("node_runs_reconciled")
+
+type NodeRunReconcilerService struct {
+	workflowRepo    models.WorkflowRepository
+	workflowRunRepo models.WorkflowRunRepository
+	idleThreshold   time.Duration
+	logger          logrus.FieldLogger
+}
+
+func NewNodeRunReconcilerService(cfg models.AppConfig) models.NodeRunReconcilerService {
+	return &NodeRunReconcilerService{
+		workflowRepo:    cfg.GetWorkflowRepository(),
+		workflowRunRepo: cfg.GetWorkflowRunRepository(),
+		idleThreshold:   cfg.GetEnvVars().NodeRunIdleThreshold,
+		logger:          cfg.GetLogger(),
+	}
+}
+
+func (s *NodeRunReconcilerService) ReconcilePendingNodeRuns(ctx context.Context) (int, error) {
+	nodeRuns, err := s.workflowRunRepo.GetIdlePendingWorkflowNodeRuns(
+		ctx,
+		time.Now().Add(-s.idleThreshold),
+		reconcileBatchSize,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get idle pending node runs: %w", err)
+	}
+
+	graphs := make(map[int32]*models.WorkflowGraph)
+	repaired := 0
+
+	for _, nodeRun := range nodeRuns {
+		kv := logrus.Fields{
+			"user_id":         nodeRun.UserID,
+			"workflow_id":     nodeRun.WorkflowID,
+			"run_id":          nodeRun.WorkflowRunID,
+			"node_id":         nodeRun.WorkflowNodeID,
+			"node_run_id":     nodeRun.ID,
+			"iteration_index": nodeRun.IterationIndex,
+		}
+
+		graph, ok := graphs[nodeRun.WorkflowRunID]
+		if !ok {
+			graph, err = s.workflowRepo.GetWorkflowRunGraph(ctx, nodeRun.WorkflowRunID)
+			if err != nil {
+				s.logger.WithError(err).WithFields(kv).Error("failed to get workflow run graph")
+				continue
+			}
+
+			graphs[nodeRun.WorkflowRunID] = graph
+		}
+
+		ready, err := s.isReady(ctx, graph, nodeRun)
+		if err != nil {
+			s.logger.WithError(err).WithFields(kv).Error("failed to check idle node run")
+			continue
+		}
+
+		// settled parents may still leave the node skipped or waiting on a retry
+		if !ready {
+			continue
+		}
+
+		if err := internal.EnqueueNode(
+			ctx,
+			s.logger,
+			s.workflowRunRepo,
+			nodeRun.UserID,
+			nodeRun.WorkflowID,
+			nodeRun.WorkflowRunID,
+			nodeRun.WorkflowNodeID,
+			nodeRun.IterationIndex,
+		); err != nil {
+			s.logger.WithError(err).WithFields(kv).Error("failed to republish idle node run task")
+			continue
+		}
+
+		nodeRunsReconciled.Add(1)
+		repaired++
+
+		s.logger.WithFields(kv).Warn("republished task of idle pending node run")
+	}
+
+	return repaired, nil
+}
+
+// isReady reports whether the node run's parents satisfy its join, in which case its task
+// should have been published already.
+func (s *NodeRunReconcilerService) isReady(
+	ctx context.Context,
+	graph *models.WorkflowGraph,
+	nodeRun *models.IdleNodeRun,
+) (bool, error) {
+	parents, err := s.workflowRunRepo.GetParentWorkflowNodeRuns(
+		ctx,
+		nodeRun.WorkflowRunID,
+		nodeRun.WorkflowNodeID,
+		nodeRun.IterationIndex,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to get parent workflow node runs: %w", err)
+	}
+
+	mode := internal.JoinModeOf(graph, nodeRun.WorkflowNodeID)
+
+	return internal.Join(graph, mode, parents) == internal.JoinRun, nil
+}
+
+var _ models.NodeRunReconcilerService = (*NodeRunReconcilerService)(nil)
@@ -2,22 +2,48 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
-	"github.com/tinyautomator/tinyautomator-core/backend/clients/rabbitmq"
 	"github.com/tinyautomator/tinyautomator-core/backend/clients/redis"
 	"github.com/tinyautomator/tinyautomator-core/backend/internal"
 	"github.com/tinyautomator/tinyautomator-core/backend/models"
 )
 
+// ErrWorkflowRunSkipped is returned when the workflow's overlap policy skipped the run, or a
+// triggered run was refused for its inputs or the usage limits. The run is still recorded, as
+// skipped and with the reason.
+var ErrWorkflowRunSkipped = errors.New("workflow run skipped")
+
+// InputValidationError reports every input a run was started with that does not match the
+// workflow's input schema, keyed by input name.
+type InputValidationError struct {
+	InputErrors map[string]string
+}
+
+func (e *InputValidationError) Error() string {
+	names := slices.Sorted(maps.Keys(e.InputErrors))
+
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s %s", name, e.InputErrors[name])
+	}
+
+	return "invalid inputs: " + strings.Join(msgs, "; ")
+}
+
 type OrchestratorService struct {
 	logger          logrus.FieldLogger
-	rabbitMQClient  rabbitmq.RabbitMQClient
 	redisClient     redis.RedisClient
 	workflowRepo    models.WorkflowRepository
 	workflowRunRepo models.WorkflowRunRepository
 	workflowSvc     models.WorkflowService
+	limiter         *UsageLimiter
 }
 
 func NewOrchestratorService(cfg models.AppConfig) models.OrchestratorService {
@@ -25,32 +51,41 @@ func NewOrchestratorService(cfg models.AppConfig) models.OrchestratorService {
 		workflowRepo:    cfg.GetWorkflowRepository(),
 		workflowRunRepo: cfg.GetWorkflowRunRepository(),
 		workflowSvc:     cfg.GetWorkflowService(),
-		rabbitMQClient:  cfg.GetRabbitMQClient(),
 		redisClient:     cfg.GetRedisClient(),
 		logger:          cfg.GetLogger(),
+		limiter:         NewUsageLimiter(cfg),
 	}
 }
 
-func (s *OrchestratorService) OrchestrateWorkflow(
-	ctx context.Context,
-	userID string,
-	workflowID int32,
-) (int32, error) {
-	wg, err := s.workflowRepo.GetWorkflowGraph(ctx, workflowID)
-	if err != nil {
-		return -1, fmt.Errorf("orchestrate workflow failed to get workflow graph: %w", err)
-	}
+// runPlan is what starting a run of a workflow graph takes.
+type runPlan struct {
+	// runNodes get a node run when the run is created
+	runNodes []models.ValidateNode
+	// nIDs are tracked by the running node set until their node runs finish
+	nIDs      []int32
+	rootNodes []*models.WorkflowNode
+}
 
+func (s *OrchestratorService) planWorkflowRun(wg *models.WorkflowGraph) (*runPlan, error) {
 	rootNodes := internal.GetRootNodes(wg)
 
+	// loop body nodes get their node runs per iteration once their foreach node runs, and are
+	// tracked through it instead of the running node set
+	bodyNodeIDs := []int32{}
+	for _, body := range internal.GetLoopBodies(wg) {
+		bodyNodeIDs = append(bodyNodeIDs, body.NodeIDs...)
+	}
+
 	n := make([]models.ValidateNode, len(wg.Nodes))
 	e := make([]models.ValidateEdge, len(wg.Edges))
+	runNodes := []models.ValidateNode{}
 	nIDs := []int32{}
 
 	for i, edge := range wg.Edges {
 		e[i] = models.ValidateEdge{
 			SourceNodeID: fmt.Sprintf("%d", edge.SourceNodeID),
 			TargetNodeID: fmt.Sprintf("%d", edge.TargetNodeID),
+			Label:        edge.Label,
 		}
 	}
 
@@ -60,6 +95,13 @@ func (s *OrchestratorService) OrchestrateWorkflow(
 			Category: node.Category,
 			NodeType: node.NodeType,
 		}
+
+		if slices.Contains(bodyNodeIDs, node.ID) {
+			continue
+		}
+
+		runNodes = append(runNodes, n[i])
+
 		isRoot := false
 
 		for _, root := range rootNodes {
@@ -74,70 +116,521 @@ func (s *OrchestratorService) OrchestrateWorkflow(
 		}
 	}
 
-	err = s.workflowSvc.ValidateWorkflowGraph(n, e)
+	if err := s.workflowSvc.ValidateWorkflowGraph(n, e); err != nil {
+		return nil, fmt.Errorf("orchestrate workflow failed to validate workflow graph: %w", err)
+	}
+
+	return &runPlan{
+		runNodes:  runNodes,
+		nIDs:      nIDs,
+		rootNodes: rootNodes,
+	}, nil
+}
+
+func (s *OrchestratorService) OrchestrateWorkflow(
+	ctx context.Context,
+	userID string,
+	workflowID int32,
+	opts models.WorkflowRunOptions,
+) (int32, error) {
+	switch {
+	case opts.Manual:
+		opts.TriggerSource = models.TriggerSourceManual
+	case opts.ParentRunID.Valid:
+		opts.TriggerSource = models.TriggerSourceWorkflow
+	case opts.TriggerSource == "":
+		return -1, fmt.Errorf("orchestrate workflow requires a trigger source")
+	}
+
+	// the run executes the latest version even when the workflow is edited while it is in flight
+	version, err := s.workflowRepo.GetLatestWorkflowVersion(ctx, workflowID)
+	if err != nil {
+		return -1, fmt.Errorf("orchestrate workflow failed to get workflow version: %w", err)
+	}
+
+	wg := version.Graph
+	opts.WorkflowVersionID = version.ID
+
+	plan, err := s.planWorkflowRun(wg)
 	if err != nil {
-		return -1, fmt.Errorf("orchestrate workflow failed to validate workflow graph: %w", err)
+		return -1, err
 	}
 
-	run, err := s.workflowRunRepo.CreateWorkflowRun(ctx, workflowID, n)
+	// a skipped run keeps the inputs it was started with
+	inputs, err := s.resolveRunInputs(ctx, workflowID, opts.Inputs)
 	if err != nil {
-		return -1, fmt.Errorf("orchestrate workflow failed to create workflow run: %w", err)
+		return -1, s.skipRefusedWorkflowRun(ctx, userID, workflowID, opts, err)
+	}
+
+	opts.Inputs = inputs
+
+	if err := s.limiter.AllowRunStart(ctx, userID); err != nil {
+		return -1, s.skipRefusedWorkflowRun(ctx, userID, workflowID, opts, err)
+	}
+
+	run, err := s.admitWorkflowRun(ctx, userID, workflowID, wg, plan, opts)
+	if err != nil {
+		return -1, s.skipRefusedWorkflowRun(ctx, userID, workflowID, opts, err)
 	}
 
 	s.logger.WithFields(logrus.Fields{
 		"workflow_id": workflowID,
-		"n_ids":       nIDs,
+		"n_ids":       plan.nIDs,
 		"run_id":      run.ID,
+		"dry_run":     opts.DryRun,
+		"status":      run.Status,
+		"trigger":     opts.TriggerSource,
 	}).Info("created workflow run")
 
-	err = s.redisClient.InitializeRunningNodeSet(ctx, run.ID, nIDs)
+	return run.ID, nil
+}
+
+// skipRefusedWorkflowRun records a run refused by the usage limiter or for its inputs as
+// skipped when a trigger started it, since nobody retries those. Other errors, and refused
+// manual runs, are returned as they are.
+func (s *OrchestratorService) skipRefusedWorkflowRun(
+	ctx context.Context,
+	userID string,
+	workflowID int32,
+	opts models.WorkflowRunOptions,
+	err error,
+) error {
+	var (
+		limitErr *UsageLimitError
+		inputErr *InputValidationError
+		reason   string
+	)
+
+	switch {
+	case opts.Manual:
+		return err
+	case errors.As(err, &limitErr):
+		reason = limitErr.Reason
+	case errors.As(err, &inputErr):
+		reason = fmt.Sprintf("invalid inputs: %s", inputErr.Error())
+	default:
+		return err
+	}
+
+	_, err = s.workflowRunRepo.CreateSkippedWorkflowRun(ctx, workflowID, opts, reason)
+	if err != nil {
+		return fmt.Errorf("orchestrate workflow failed to record skipped run: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"workflow_id": workflowID,
+		"reason":      reason,
+	}).Info("skipped workflow run")
+
+	return fmt.Errorf("%w: %s", ErrWorkflowRunSkipped, reason)
+}
+
+// resolveRunInputs checks the inputs a run is started with against the workflow's input schema
+// and fills in the defaults. Workflows that declare no inputs take any.
+func (s *OrchestratorService) resolveRunInputs(
+	ctx context.Context,
+	workflowID int32,
+	values map[string]any,
+) (map[string]any, error) {
+	schema, err := s.workflowRepo.GetWorkflowInputSchema(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("orchestrate workflow failed to get input schema: %w", err)
+	}
+
+	if len(schema.Inputs) == 0 {
+		return values, nil
+	}
+
+	inputs, inputErrs := schema.Resolve(values)
+	if len(inputErrs) > 0 {
+		return nil, &InputValidationError{InputErrors: inputErrs}
+	}
+
+	return inputs, nil
+}
+
+// admitWorkflowRun creates the run as the workflow's concurrency settings allow: running,
+// queued, or running after cancelling the oldest runs. Skipped runs are recorded and reported
+// through ErrWorkflowRunSkipped. Running and queued runs count against the monthly run quota,
+// which is refunded when their transaction fails.
+// Running runs are started in the transaction creating them, queued runs once an earlier run of
+// the workflow finishes.
+func (s *OrchestratorService) admitWorkflowRun(
+	ctx context.Context,
+	userID string,
+	workflowID int32,
+	wg *models.WorkflowGraph,
+	plan *runPlan,
+	opts models.WorkflowRunOptions,
+) (*models.WorkflowRunWithNodesDTO, error) {
+	concurrency, err := s.workflowRepo.GetWorkflowConcurrency(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("orchestrate workflow failed to get concurrency settings: %w", err)
+	}
+
+	var (
+		run        *models.WorkflowRunWithNodesDTO
+		skipReason string
+		cancelled  []int32
+		// Redis keeps the quota charged even when the transaction rolls back afterwards
+		charged bool
+	)
+
+	if opts.DryRun || concurrency.OverlapPolicy == models.OverlapPolicyAllow {
+		err := s.workflowRunRepo.WithTransaction(
+			ctx,
+			func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+				if err := s.limiter.AllowRun(ctx, userID, opts.DryRun); err != nil {
+					return err
+				}
+
+				charged = true
+
+				var err error
+
+				run, err = txRepo.CreateWorkflowRun(ctx, workflowID, plan.runNodes, opts, "running")
+				if err != nil {
+					return err
+				}
+
+				return s.startWorkflowRun(ctx, txRepo, userID, workflowID, wg, plan, run.ID)
+			},
+		)
+		if err != nil {
+			if charged {
+				s.refundRun(ctx, userID, opts.DryRun)
+			}
+
+			return nil, fmt.Errorf("orchestrate workflow failed to create workflow run: %w", err)
+		}
+
+		return run, nil
+	}
+
+	err = s.workflowRunRepo.WithTransaction(
+		ctx,
+		func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+			// concurrent triggers of the workflow wait here so they all see each other's runs
+			if err := txRepo.LockWorkflowRuns(ctx, workflowID); err != nil {
+				return err
+			}
+
+			active, err := txRepo.GetActiveWorkflowRuns(ctx, workflowID)
+			if err != nil {
+				return err
+			}
+
+			running := []*models.WorkflowRunCore{}
+			queued := 0
+
+			for _, activeRun := range active {
+				if activeRun.Status == "running" {
+					running = append(running, activeRun)
+				} else {
+					queued++
+				}
+			}
+
+			limit := int(concurrency.MaxConcurrentRuns)
+			status := "running"
+
+			switch concurrency.OverlapPolicy {
+			case models.OverlapPolicySkip:
+				if len(running) >= limit {
+					skipReason = fmt.Sprintf(
+						"concurrency limit reached: %d of %d runs in progress",
+						len(running),
+						limit,
+					)
+
+					_, err := txRepo.CreateSkippedWorkflowRun(ctx, workflowID, opts, skipReason)
+
+					return err
+				}
+			case models.OverlapPolicyQueue:
+				// runs already waiting keep their place in line
+				if len(running) >= limit || queued > 0 {
+					status = "queued"
+				}
+			case models.OverlapPolicyCancelPrevious:
+				for _, previous := range running[:max(len(running)-limit+1, 0)] {
+					ok, err := txRepo.CancelWorkflowRun(ctx, previous.ID)
+					if err != nil {
+						return err
+					}
+
+					if ok {
+						cancelled = append(cancelled, previous.ID)
+					}
+				}
+			}
+
+			// the quota is charged last so skipped runs do not use it up
+			if err := s.limiter.AllowRun(ctx, userID, opts.DryRun); err != nil {
+				return err
+			}
+
+			charged = true
+
+			run, err = txRepo.CreateWorkflowRun(ctx, workflowID, plan.runNodes, opts, status)
+			if err != nil || status == "queued" {
+				return err
+			}
+
+			return s.startWorkflowRun(ctx, txRepo, userID, workflowID, wg, plan, run.ID)
+		},
+	)
+	if err != nil {
+		if charged {
+			s.refundRun(ctx, userID, opts.DryRun)
+		}
+
+		return nil, fmt.Errorf("orchestrate workflow failed to create workflow run: %w", err)
+	}
+
+	if skipReason != "" {
+		s.logger.WithFields(logrus.Fields{
+			"workflow_id": workflowID,
+			"reason":      skipReason,
+		}).Info("skipped workflow run")
+
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowRunSkipped, skipReason)
+	}
+
+	for _, runID := range cancelled {
+		s.logger.WithFields(logrus.Fields{
+			"workflow_id": workflowID,
+			"run_id":      runID,
+		}).Info("cancelled previous workflow run")
+
+		err := s.redisClient.PublishWorkflowRunStatusUpdate(ctx, runID, "cancelled", nil)
+		if err != nil {
+			s.logger.WithError(err).WithField("run_id", runID).
+				Warn("failed to publish workflow run cancellation")
+		}
+
+		if err := internal.ResumeParentNodeRun(
+			ctx,
+			s.logger,
+			s.workflowRunRepo,
+			s.redisClient,
+			userID,
+			runID,
+			"cancelled",
+		); err != nil {
+			s.logger.WithError(err).WithField("run_id", runID).
+				Error("failed to resume parent node run")
+		}
+	}
+
+	return run, nil
+}
+
+// refundRun gives back the monthly quota charged by an admission whose transaction rolled back.
+func (s *OrchestratorService) refundRun(ctx context.Context, userID string, dryRun bool) {
+	if err := s.limiter.RefundRun(ctx, userID, dryRun); err != nil {
+		s.logger.WithError(err).WithField("user_id", userID).Warn("failed to refund run quota")
+	}
+}
+
+// StartQueuedWorkflowRuns starts the oldest queued runs of the workflow while it is below its
+// concurrency limit. It is called whenever a run of the workflow finishes.
+func (s *OrchestratorService) StartQueuedWorkflowRuns(
+	ctx context.Context,
+	workflowID int32,
+) error {
+	concurrency, err := s.workflowRepo.GetWorkflowConcurrency(ctx, workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to get concurrency settings: %w", err)
+	}
+
+	var (
+		userID string
+		graphs = make(map[int32]*models.WorkflowGraph)
+		plans  = make(map[int32]*runPlan)
+	)
+
+	// the workflow is only loaded once one of its queued runs actually starts, and each version
+	// the queued runs are pinned to is planned once
+	loadVersion := func(ctx context.Context, versionID int32) error {
+		if userID == "" {
+			workflow, err := s.workflowRepo.GetWorkflow(ctx, workflowID)
+			if err != nil {
+				return fmt.Errorf("failed to get workflow: %w", err)
+			}
+
+			userID = workflow.UserID
+		}
+
+		if _, ok := plans[versionID]; ok {
+			return nil
+		}
+
+		version, err := s.workflowRepo.GetWorkflowVersion(ctx, versionID)
+		if err != nil {
+			return fmt.Errorf("failed to get workflow version: %w", err)
+		}
+
+		plan, err := s.planWorkflowRun(version.Graph)
+		if err != nil {
+			return err
+		}
+
+		graphs[versionID] = version.Graph
+		plans[versionID] = plan
+
+		return nil
+	}
+
+	err = s.workflowRunRepo.WithTransaction(
+		ctx,
+		func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+			if err := txRepo.LockWorkflowRuns(ctx, workflowID); err != nil {
+				return err
+			}
+
+			active, err := txRepo.GetActiveWorkflowRuns(ctx, workflowID)
+			if err != nil {
+				return err
+			}
+
+			free := len(active)
+			if concurrency.OverlapPolicy != models.OverlapPolicyAllow {
+				free = int(concurrency.MaxConcurrentRuns)
+			}
+
+			for _, activeRun := range active {
+				if activeRun.Status == "running" {
+					free--
+				}
+			}
+
+			for _, activeRun := range active {
+				if free <= 0 {
+					break
+				}
+
+				if activeRun.Status != "queued" {
+					continue
+				}
+
+				ok, err := txRepo.StartQueuedWorkflowRun(ctx, activeRun.ID)
+				if err != nil {
+					return err
+				}
+
+				if !ok {
+					continue
+				}
+
+				free--
+
+				versionID := activeRun.WorkflowVersionID
+				if err := loadVersion(ctx, versionID); err != nil {
+					return err
+				}
+
+				s.logger.WithFields(logrus.Fields{
+					"workflow_id": workflowID,
+					"run_id":      activeRun.ID,
+				}).Info("starting queued workflow run")
+
+				err = s.startWorkflowRun(
+					ctx,
+					txRepo,
+					userID,
+					workflowID,
+					graphs[versionID],
+					plans[versionID],
+					activeRun.ID,
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to start queued workflow runs: %w", err)
+	}
+
+	return nil
+}
+
+// startWorkflowRun sets the deadline of a run that was just created or dequeued and enqueues its
+// root nodes, through the repository of the transaction that created or dequeued it.
+func (s *OrchestratorService) startWorkflowRun(
+	ctx context.Context,
+	workflowRunRepo models.WorkflowRunRepository,
+	userID string,
+	workflowID int32,
+	wg *models.WorkflowGraph,
+	plan *runPlan,
+	runID int32,
+) error {
+	timeout, err := s.workflowRepo.GetWorkflowTimeout(ctx, workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to get timeout settings: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout.RunTimeout())
+	if err := workflowRunRepo.SetWorkflowRunDeadline(ctx, runID, deadline); err != nil {
+		return fmt.Errorf("failed to set run deadline: %w", err)
+	}
+
+	err = s.redisClient.InitializeRunningNodeSet(ctx, runID, plan.nIDs)
 	if err != nil {
 		// it's okay if this fails, we'll just rely on the executor to retry
 		s.logger.WithError(err).Warn("failed to initialize running node set")
 	}
 
 	s.logger.WithFields(logrus.Fields{
-		"workflow_id": wg.ID,
-		"run_id":      run.ID,
-		"root_nodes":  rootNodes,
+		"workflow_id": workflowID,
+		"run_id":      runID,
+		"root_nodes":  plan.rootNodes,
 	}).Info("executing workflow")
 
-	for _, parent := range rootNodes {
+	for _, parent := range plan.rootNodes {
 		// trigger nodes are orchestrated on workflow creation so we just enqueue their child nodes here
 		if parent.Category == "trigger" {
 			if err := internal.EnqueueChildNodes(
 				ctx,
 				s.logger,
-				s.workflowRunRepo,
-				s.rabbitMQClient,
+				workflowRunRepo,
+				s.redisClient,
+				wg,
 				userID,
 				workflowID,
 				parent.ID,
-				run.ID,
+				runID,
+				models.NoIteration,
 			); err != nil {
-				return -1, fmt.Errorf("orchestrate workflow failed to enqueue child nodes: %w", err)
+				return fmt.Errorf("failed to enqueue child nodes: %w", err)
 			}
 
-			if err := s.redisClient.PublishNodeStatusUpdate(ctx, run.ID, parent.ID, "success", nil); err != nil {
+			if err := s.redisClient.PublishNodeStatusUpdate(ctx, runID, parent.ID, "success", nil); err != nil {
 				s.logger.WithError(err).Warn("failed to publish root node status update to redis")
 			}
 		} else {
 			if err := internal.EnqueueNode(
 				ctx,
 				s.logger,
-				s.workflowRunRepo,
-				s.rabbitMQClient,
+				workflowRunRepo,
 				userID,
 				workflowID,
-				run.ID,
+				runID,
 				parent.ID,
+				models.NoIteration,
 			); err != nil {
-				return -1, fmt.Errorf("orchestrate workflow failed to enqueue node: %w", err)
+				return fmt.Errorf("failed to enqueue node: %w", err)
 			}
 		}
 	}
 
-	return run.ID, nil
+	return nil
 }
 
 var _ models.OrchestratorService = (*OrchestratorService)(nil)
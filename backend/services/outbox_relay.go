@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tinyautomator/tinyautomator-core/backend/clients/rabbitmq"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// outboxBatchSize is how many outbox tasks one transaction of the relay claims at most.
+const outboxBatchSize = 100
+
+const (
+	// outboxMaxAttempts is how many times a task is published before it is given up on. With
+	// the backoff below, tasks outlive about an hour of the broker being unreachable.
+	outboxMaxAttempts = 20
+	// outboxMaxBackoff caps how long a task that failed to publish waits for its next attempt.
+	outboxMaxBackoff = 5 * time.Minute
+)
+
+type OutboxRelayService struct {
+	workflowRunRepo models.WorkflowRunRepository
+	rabbitMQClient  rabbitmq.RabbitMQClient
+	retention       time.Duration
+	logger          logrus.FieldLogger
+}
+
+func NewOutboxRelayService(cfg models.AppConfig) models.OutboxRelayService {
+	return &OutboxRelayService{
+		workflowRunRepo: cfg.GetWorkflowRunRepository(),
+		rabbitMQClient:  cfg.GetRabbitMQClient(),
+		retention:       cfg.GetEnvVars().OutboxRetention,
+		logger:          cfg.GetLogger(),
+	}
+}
+
+func (s *OutboxRelayService) RelayOutboxTasks(ctx context.Context) (int, error) {
+	relayed := 0
+
+	for {
+		sent, claimed, err := s.relayOutboxBatch(ctx)
+		relayed += sent
+
+		if err != nil {
+			return relayed, err
+		}
+
+		if claimed < outboxBatchSize {
+			return relayed, nil
+		}
+	}
+}
+
+// relayOutboxBatch publishes a batch of outbox tasks and marks them sent in the transaction that
+// claimed them. A task that fails to publish is retried with backoff and given up on after
+// outboxMaxAttempts, without holding up the tasks after it. It returns how many tasks it sent
+// and claimed.
+func (s *OutboxRelayService) relayOutboxBatch(ctx context.Context) (int, int, error) {
+	var sent, claimed int
+
+	err := s.workflowRunRepo.WithTransaction(
+		ctx,
+		func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+			tasks, err := txRepo.ClaimOutboxTasks(ctx, outboxBatchSize)
+			if err != nil {
+				return err
+			}
+
+			claimed = len(tasks)
+
+			for _, task := range tasks {
+				if err := s.publishOutboxTask(ctx, task); err != nil {
+					if err := s.failOutboxTask(ctx, txRepo, task, err); err != nil {
+						return err
+					}
+
+					continue
+				}
+
+				if err := txRepo.MarkOutboxTaskSent(ctx, task.ID); err != nil {
+					return err
+				}
+
+				sent++
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		// tasks published before the rollback are published again, the executor skips duplicates
+		return 0, 0, fmt.Errorf("failed to relay outbox tasks: %w", err)
+	}
+
+	return sent, claimed, nil
+}
+
+// failOutboxTask records a failed publish of the task, and gives up on the task once it used up
+// its attempts.
+func (s *OutboxRelayService) failOutboxTask(
+	ctx context.Context,
+	txRepo models.WorkflowRunRepository,
+	task *models.OutboxTask,
+	publishErr error,
+) error {
+	attempts := task.Attempts + 1
+	kv := logrus.Fields{
+		"outbox_task_id": task.ID,
+		"attempts":       attempts,
+	}
+
+	if attempts >= outboxMaxAttempts {
+		s.logger.WithError(publishErr).WithFields(kv).
+			Error("giving up on outbox task that failed to publish too many times")
+
+		return txRepo.MarkOutboxTaskDead(ctx, task.ID, publishErr.Error())
+	}
+
+	backoff := min(time.Second<<min(task.Attempts, 16), outboxMaxBackoff)
+
+	s.logger.WithError(publishErr).WithFields(kv).WithField("retry_in", backoff).
+		Warn("failed to publish outbox task")
+
+	return txRepo.MarkOutboxTaskFailed(ctx, task.ID, publishErr.Error(), time.Now().Add(backoff))
+}
+
+// publishOutboxTask publishes the task right away, or delayed by whatever is left of its delay.
+func (s *OutboxRelayService) publishOutboxTask(ctx context.Context, task *models.OutboxTask) error {
+	var err error
+
+	remaining := task.Delay - time.Since(task.CreatedAt)
+	if remaining > 0 {
+		err = s.rabbitMQClient.PublishDelayed(ctx, task.Payload, remaining)
+	} else {
+		err = s.rabbitMQClient.Publish(ctx, task.Payload)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to publish outbox task %d: %w", task.ID, err)
+	}
+
+	return nil
+}
+
+func (s *OutboxRelayService) PurgeSentOutboxTasks(ctx context.Context) (int64, error) {
+	n, err := s.workflowRunRepo.DeleteSentOutboxTasks(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent outbox tasks: %w", err)
+	}
+
+	return n, nil
+}
+
+var _ models.OutboxRelayService = (*OutboxRelayService)(nil)
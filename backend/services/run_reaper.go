@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tinyautomator/tinyautomator-core/backend/clients/redis"
+	"github.com/tinyautomator/tinyautomator-core/backend/internal"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+// reapBatchSize is how many stuck runs one pass of the reaper times out at most.
+const reapBatchSize = 100
+
+type RunReaperService struct {
+	workflowRunRepo models.WorkflowRunRepository
+	redisClient     redis.RedisClient
+	orchestrator    models.OrchestratorService
+	stallTimeout    time.Duration
+	logger          logrus.FieldLogger
+}
+
+func NewRunReaperService(cfg models.AppConfig) models.RunReaperService {
+	return &RunReaperService{
+		workflowRunRepo: cfg.GetWorkflowRunRepository(),
+		redisClient:     cfg.GetRedisClient(),
+		orchestrator:    cfg.GetOrchestratorService(),
+		stallTimeout:    cfg.GetEnvVars().RunStallTimeout,
+		logger:          cfg.GetLogger(),
+	}
+}
+
+func (s *RunReaperService) ReapStuckWorkflowRuns(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	runs, err := s.workflowRunRepo.GetStuckWorkflowRuns(
+		ctx,
+		now,
+		now.Add(-s.stallTimeout),
+		reapBatchSize,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stuck workflow runs: %w", err)
+	}
+
+	reaped := 0
+
+	for _, run := range runs {
+		ok, err := s.timeOutWorkflowRun(ctx, run, now)
+		if err != nil {
+			s.logger.WithError(err).WithField("run_id", run.ID).
+				Error("failed to time out stuck workflow run")
+
+			continue
+		}
+
+		if ok {
+			reaped++
+		}
+	}
+
+	return reaped, nil
+}
+
+// timeOutWorkflowRun marks the run as timed out and lets whatever waits on it carry on: the run
+// progress listeners, the run_workflow node that started it and the queued runs of its workflow.
+func (s *RunReaperService) timeOutWorkflowRun(
+	ctx context.Context,
+	run *models.StuckWorkflowRun,
+	now time.Time,
+) (bool, error) {
+	reason := fmt.Sprintf(
+		"run made no progress since %s",
+		run.LastProgressAt.UTC().Format(time.RFC3339),
+	)
+	if run.DeadlineAt.Valid && !run.DeadlineAt.Time.After(now) {
+		reason = fmt.Sprintf(
+			"run did not finish before its deadline at %s",
+			run.DeadlineAt.Time.UTC().Format(time.RFC3339),
+		)
+	}
+
+	status := "running"
+
+	// the node runs that get failed along with the run, fetched first to publish their update
+	runningNodeRuns, err := s.workflowRunRepo.GetWorkflowNodeRuns(ctx, run.ID, &status)
+	if err != nil {
+		return false, fmt.Errorf("failed to get running node runs: %w", err)
+	}
+
+	ok, err := s.workflowRunRepo.TimeOutWorkflowRun(ctx, run.ID, reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to time out workflow run: %w", err)
+	}
+
+	if !ok {
+		return false, nil
+	}
+
+	kv := logrus.Fields{
+		"user_id":     run.UserID,
+		"workflow_id": run.WorkflowID,
+		"run_id":      run.ID,
+		"reason":      reason,
+	}
+
+	s.logger.WithFields(kv).Warn("workflow run timed out")
+
+	for _, nodeRun := range runningNodeRuns {
+		err := s.redisClient.PublishNodeStatusUpdate(
+			ctx,
+			run.ID,
+			nodeRun.WorkflowNodeID,
+			"failed",
+			map[string]any{"error": reason},
+		)
+		if err != nil {
+			s.logger.WithError(err).WithFields(kv).Warn("failed to publish node status update")
+		}
+	}
+
+	err = s.redisClient.PublishWorkflowRunStatusUpdate(
+		ctx,
+		run.ID,
+		"timed_out",
+		map[string]any{"reason": reason},
+	)
+	if err != nil {
+		s.logger.WithError(err).WithFields(kv).Warn("failed to publish workflow run timeout")
+	}
+
+	if err := internal.ResumeParentNodeRun(
+		ctx,
+		s.logger,
+		s.workflowRunRepo,
+		s.redisClient,
+		run.UserID,
+		run.ID,
+		"timed_out",
+	); err != nil {
+		s.logger.WithError(err).WithFields(kv).Error("failed to resume parent node run")
+	}
+
+	if err := s.orchestrator.StartQueuedWorkflowRuns(ctx, run.WorkflowID); err != nil {
+		s.logger.WithError(err).WithFields(kv).Error("failed to start queued workflow runs")
+	}
+
+	return true, nil
+}
+
+var _ models.RunReaperService = (*RunReaperService)(nil)
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -70,8 +71,24 @@ func (s *SchedulerService) RunScheduledWorkflow(
 	go func() {
 		defer s.wg.Done()
 
-		if runID, err := s.orchestrator.OrchestrateWorkflow(ctx, ws.UserID, ws.WorkflowID); err != nil ||
-			runID == -1 {
+		if runID, err := s.orchestrator.OrchestrateWorkflow(
+			ctx,
+			ws.UserID,
+			ws.WorkflowID,
+			models.WorkflowRunOptions{
+				TriggerSource: models.TriggerSourceSchedule,
+				TriggerPayload: map[string]any{
+					"schedule_id":   ws.ID,
+					"schedule_type": ws.ScheduleType,
+					"scheduled_at":  ws.NextRunAt.Time.UTC().Format(time.RFC3339),
+				},
+			},
+		); errors.Is(err, ErrWorkflowRunSkipped) {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"schedule_id": ws.ID,
+				"workflow_id": ws.WorkflowID,
+			}).Info("workflow execution skipped")
+		} else if err != nil || runID == -1 {
 			s.logger.WithError(err).WithFields(logrus.Fields{
 				"schedule_id": ws.ID,
 				"workflow_id": ws.WorkflowID,
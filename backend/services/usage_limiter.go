@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tinyautomator/tinyautomator-core/backend/clients/redis"
+	"github.com/tinyautomator/tinyautomator-core/backend/models"
+)
+
+var ErrUsageLimited = errors.New("usage limit reached")
+
+// UsageLimitError tells a user which limit they hit and when to try again.
+type UsageLimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *UsageLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+func (e *UsageLimitError) Unwrap() error {
+	return ErrUsageLimited
+}
+
+// UsageLimiter enforces the per-user limits: a token bucket on starting runs, plus monthly
+// quotas on runs and on executed actions. Dry runs only count against the token bucket.
+type UsageLimiter struct {
+	logger         logrus.FieldLogger
+	redisClient    redis.RedisClient
+	runsPerSecond  float64
+	runBurst       int
+	monthlyRuns    int64
+	monthlyActions int64
+}
+
+func NewUsageLimiter(cfg models.AppConfig) *UsageLimiter {
+	envVars := cfg.GetEnvVars()
+
+	return &UsageLimiter{
+		logger:         cfg.GetLogger(),
+		redisClient:    cfg.GetRedisClient(),
+		runsPerSecond:  envVars.RunRateLimitPerMinute / 60,
+		runBurst:       envVars.RunRateLimitBurst,
+		monthlyRuns:    envVars.MonthlyRunQuota,
+		monthlyActions: envVars.MonthlyActionQuota,
+	}
+}
+
+// nextMonth returns when the monthly quotas of the current month reset, along with the month
+// the quota keys are scoped to.
+func nextMonth(now time.Time) (time.Time, string) {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC), now.Format("2006-01")
+}
+
+// AllowRunStart takes a token from the user's run bucket. It returns a UsageLimitError when
+// the user starts runs faster than the bucket refills.
+func (l *UsageLimiter) AllowRunStart(ctx context.Context, userID string) error {
+	if l.runsPerSecond <= 0 || l.runBurst <= 0 {
+		return nil
+	}
+
+	wait, err := l.redisClient.TakeRateLimitToken(
+		ctx,
+		fmt.Sprintf("runs:%s", userID),
+		l.runsPerSecond,
+		l.runBurst,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to check run rate limit: %w", err)
+	}
+
+	if wait > 0 {
+		return &UsageLimitError{Reason: "too many runs started", RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// AllowRun counts an admitted run against the user's monthly quota. It returns a
+// UsageLimitError when the quota is used up.
+func (l *UsageLimiter) AllowRun(ctx context.Context, userID string, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+
+	return l.consumeMonthlyQuota(ctx, "runs", userID, l.monthlyRuns)
+}
+
+// RefundRun gives back the monthly quota AllowRun counted for a run that was not admitted after
+// all.
+func (l *UsageLimiter) RefundRun(ctx context.Context, userID string, dryRun bool) error {
+	if dryRun || l.monthlyRuns <= 0 {
+		return nil
+	}
+
+	_, month := nextMonth(time.Now())
+
+	if err := l.redisClient.RefundQuota(ctx, fmt.Sprintf("runs:%s:%s", userID, month)); err != nil {
+		return fmt.Errorf("failed to refund monthly runs quota: %w", err)
+	}
+
+	return nil
+}
+
+// AllowAction counts an action execution against the user's monthly quota.
+func (l *UsageLimiter) AllowAction(ctx context.Context, userID string) error {
+	return l.consumeMonthlyQuota(ctx, "actions", userID, l.monthlyActions)
+}
+
+func (l *UsageLimiter) consumeMonthlyQuota(
+	ctx context.Context,
+	name string,
+	userID string,
+	limit int64,
+) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	resetAt, month := nextMonth(time.Now())
+
+	ok, err := l.redisClient.ConsumeQuota(
+		ctx,
+		fmt.Sprintf("%s:%s:%s", name, userID, month),
+		limit,
+		resetAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to check monthly %s quota: %w", name, err)
+	}
+
+	if !ok {
+		l.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"quota":   name,
+			"limit":   limit,
+		}).Info("monthly quota used up")
+
+		return &UsageLimitError{
+			Reason:     fmt.Sprintf("monthly %s quota of %d used up", name, limit),
+			RetryAfter: time.Until(resetAt),
+		}
+	}
+
+	return nil
+}
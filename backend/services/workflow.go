@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/tinyautomator/tinyautomator-core/backend/internal"
+	handlers "github.com/tinyautomator/tinyautomator-core/backend/internal/handlers/actions"
 	"github.com/tinyautomator/tinyautomator-core/backend/internal/handlers/triggers"
 
 	"github.com/tinyautomator/tinyautomator-core/backend/models"
@@ -19,13 +23,32 @@ import (
 
 var ErrUserDoesNotHaveAccessToWorkflow = errors.New("user does not have access to workflow")
 
+// NodeValidationError reports every node of a saved workflow that failed validation, keyed by
+// the node ID the editor sent so it can highlight them.
+type NodeValidationError struct {
+	NodeErrors map[string]string
+}
+
+func (e *NodeValidationError) Error() string {
+	ids := slices.Sorted(maps.Keys(e.NodeErrors))
+	msgs := make([]string, len(ids))
+
+	for i, id := range ids {
+		msgs[i] = fmt.Sprintf("node %s: %s", id, e.NodeErrors[id])
+	}
+
+	return "invalid nodes: " + strings.Join(msgs, "; ")
+}
+
 type WorkflowService struct {
 	logger               logrus.FieldLogger
 	workflowRepo         models.WorkflowRepository
 	workflowScheduleRepo models.WorkflowScheduleRepository
 	orchestrator         models.OrchestratorService
 	triggerRegistry      *triggers.TriggerRegistry
+	actionRegistry       *handlers.ActionRegistry
 	schedulerSvc         models.SchedulerService
+	oauthIntegrationRepo models.OauthIntegrationRepository
 }
 
 func NewWorkflowService(cfg models.AppConfig) models.WorkflowService {
@@ -41,7 +64,9 @@ func NewWorkflowService(cfg models.AppConfig) models.WorkflowService {
 		workflowScheduleRepo: cfg.GetWorkflowScheduleRepository(),
 		orchestrator:         cfg.GetOrchestratorService(),
 		triggerRegistry:      t,
+		actionRegistry:       newActionRegistry(cfg),
 		schedulerSvc:         schedulerSvc,
+		oauthIntegrationRepo: cfg.GetOauthIntegrationRepository(),
 	}
 }
 
@@ -81,6 +106,7 @@ func (s *WorkflowService) prepForValidate(
 		e[i] = models.ValidateEdge{
 			SourceNodeID: edge.SourceNodeID,
 			TargetNodeID: edge.TargetNodeID,
+			Label:        edge.Label,
 		}
 	}
 
@@ -117,6 +143,10 @@ func (s *WorkflowService) ValidateWorkflowGraph(
 			)
 		}
 
+		if err := validateEdgeLabel(idxToNode[fromIdx], edge); err != nil {
+			return err
+		}
+
 		g.Add(fromIdx, toIdx)
 	}
 
@@ -125,6 +155,10 @@ func (s *WorkflowService) ValidateWorkflowGraph(
 		return fmt.Errorf("validation error: cycle detected")
 	}
 
+	if err := validateLoopBodies(nodes, edges); err != nil {
+		return err
+	}
+
 	level := make(map[string]int)
 	for _, nodeIdx := range order {
 		if _, ok := level[idxToNode[nodeIdx].ID]; !ok {
@@ -166,6 +200,140 @@ func (s *WorkflowService) ValidateWorkflowGraph(
 	return nil
 }
 
+// validateEdgeLabel checks that branch labels are only used on edges leaving a condition node,
+// and that every edge leaving a condition node names the branch it belongs to. Edges leaving a
+// foreach node are either unlabelled or labelled each. Any node but a trigger may have on_error
+// edges.
+func validateEdgeLabel(source models.ValidateNode, edge models.ValidateEdge) error {
+	if edge.Label == internal.EdgeLabelOnError {
+		if source.Category == "trigger" {
+			return fmt.Errorf(
+				"validation error: edge %s -> %s leaving a trigger cannot be labelled %s",
+				edge.SourceNodeID,
+				edge.TargetNodeID,
+				internal.EdgeLabelOnError,
+			)
+		}
+
+		return nil
+	}
+
+	if source.NodeType == internal.NodeTypeForEach {
+		if edge.Label != internal.EdgeLabelEach && edge.Label != "" {
+			return fmt.Errorf(
+				"validation error: foreach edge %s -> %s must be labelled each or left unlabelled",
+				edge.SourceNodeID,
+				edge.TargetNodeID,
+			)
+		}
+
+		return nil
+	}
+
+	if source.NodeType == internal.NodeTypeCondition {
+		if edge.Label != internal.EdgeLabelTrue && edge.Label != internal.EdgeLabelFalse {
+			return fmt.Errorf(
+				"validation error: edge %s -> %s leaving a condition must be labelled true or false",
+				edge.SourceNodeID,
+				edge.TargetNodeID,
+			)
+		}
+
+		return nil
+	}
+
+	if edge.Label != "" {
+		return fmt.Errorf(
+			"validation error: edge %s -> %s has label %q but its source is not a condition",
+			edge.SourceNodeID,
+			edge.TargetNodeID,
+			edge.Label,
+		)
+	}
+
+	return nil
+}
+
+// validateLoopBodies checks that every foreach node has a loop body and that the body only runs
+// as part of its loop: body nodes are reached from the loop or other body nodes alone, and the
+// body holds no triggers or nested loops.
+func validateLoopBodies(nodes []models.ValidateNode, edges []models.ValidateEdge) error {
+	nodeByID := make(map[string]models.ValidateNode, len(nodes))
+	for _, node := range nodes {
+		nodeByID[node.ID] = node
+	}
+
+	roots := make(map[string][]string)
+	children := make(map[string][]string)
+	parents := make(map[string][]models.ValidateEdge)
+
+	for _, edge := range edges {
+		if edge.Label == internal.EdgeLabelEach {
+			roots[edge.SourceNodeID] = append(roots[edge.SourceNodeID], edge.TargetNodeID)
+		} else {
+			children[edge.SourceNodeID] = append(children[edge.SourceNodeID], edge.TargetNodeID)
+		}
+
+		parents[edge.TargetNodeID] = append(parents[edge.TargetNodeID], edge)
+	}
+
+	for _, loop := range nodes {
+		if loop.NodeType != internal.NodeTypeForEach {
+			continue
+		}
+
+		if len(roots[loop.ID]) == 0 {
+			return fmt.Errorf(
+				"validation error: foreach node %s needs at least one %s edge",
+				loop.ID,
+				internal.EdgeLabelEach,
+			)
+		}
+
+		body := make(map[string]struct{})
+		queue := slices.Clone(roots[loop.ID])
+
+		for len(queue) > 0 {
+			nodeID := queue[0]
+			queue = queue[1:]
+
+			if _, ok := body[nodeID]; ok {
+				continue
+			}
+
+			body[nodeID] = struct{}{}
+			queue = append(queue, children[nodeID]...)
+		}
+
+		for nodeID := range body {
+			node := nodeByID[nodeID]
+			if node.Category == "trigger" || node.NodeType == internal.NodeTypeForEach {
+				return fmt.Errorf(
+					"validation error: node %s in the loop body of %s must be an action",
+					nodeID,
+					loop.ID,
+				)
+			}
+
+			for _, edge := range parents[nodeID] {
+				_, inBody := body[edge.SourceNodeID]
+				fromLoop := edge.SourceNodeID == loop.ID && edge.Label == internal.EdgeLabelEach
+
+				if !inBody && !fromLoop {
+					return fmt.Errorf(
+						"validation error: loop body node %s of %s has parent %s outside the loop",
+						nodeID,
+						loop.ID,
+						edge.SourceNodeID,
+					)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *WorkflowService) validateNode(node *models.WorkflowNodeDTO) error {
 	if node.Category == "" {
 		return fmt.Errorf("validation error: node category is empty")
@@ -184,12 +352,69 @@ func (s *WorkflowService) validateNode(node *models.WorkflowNodeDTO) error {
 		return fmt.Errorf("validation error: node config is not valid JSON: %w", err)
 	}
 
-	if node.Category == "trigger" {
+	if _, err := internal.ParseJoinMode(*node.Config); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+
+	if _, err := internal.ParseFailurePolicy(*node.Config); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+
+	if _, err := models.ParseRetryPolicy(*node.Config); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+
+	switch node.Category {
+	case "trigger":
 		if err := s.triggerRegistry.Validate(node.NodeType, triggers.TriggerNodeInput{
 			Config: node.Config,
 		}); err != nil {
 			return fmt.Errorf("trigger validation error: %w", err)
 		}
+	case "action":
+		if err := s.actionRegistry.Validate(node.NodeType, *node.Config); err != nil {
+			return fmt.Errorf("action validation error: %w", err)
+		}
+	default:
+		return fmt.Errorf("validation error: unknown node category %q", node.Category)
+	}
+
+	return nil
+}
+
+// validateNodes validates every node, reporting all of the invalid ones at once through a
+// NodeValidationError. A node following its error branch must have an on_error edge to follow.
+func (s *WorkflowService) validateNodes(
+	nodes []*models.WorkflowNodeDTO,
+	edges []*models.WorkflowEdgeDTO,
+) error {
+	nodeErrors := make(map[string]string)
+	errorBranches := make(map[string]bool)
+
+	for _, edge := range edges {
+		if edge.Label == internal.EdgeLabelOnError {
+			errorBranches[edge.SourceNodeID] = true
+		}
+	}
+
+	for _, node := range nodes {
+		if err := s.validateNode(node); err != nil {
+			nodeErrors[node.ID] = err.Error()
+			continue
+		}
+
+		policy, _ := internal.ParseFailurePolicy(*node.Config)
+		if policy == internal.FailurePolicyErrorBranch && !errorBranches[node.ID] {
+			nodeErrors[node.ID] = fmt.Sprintf(
+				"validation error: failure policy %s needs an outgoing %s edge",
+				internal.FailurePolicyErrorBranch,
+				internal.EdgeLabelOnError,
+			)
+		}
+	}
+
+	if len(nodeErrors) > 0 {
+		return &NodeValidationError{NodeErrors: nodeErrors}
 	}
 
 	return nil
@@ -209,10 +434,8 @@ func (s *WorkflowService) CreateWorkflow(
 		return nil, fmt.Errorf("failed to validate workflow graph: %w", err)
 	}
 
-	for _, node := range nodes {
-		if err := s.validateNode(node); err != nil {
-			return nil, fmt.Errorf("failed to validate node: %w", err)
-		}
+	if err := s.validateNodes(nodes, edges); err != nil {
+		return nil, err
 	}
 
 	w, err := s.workflowRepo.CreateWorkflow(ctx, userID, name, description, status, nodes, edges)
@@ -251,10 +474,8 @@ func (s *WorkflowService) UpdateWorkflow(
 		return fmt.Errorf("failed to validate workflow graph: %w", err)
 	}
 
-	for _, node := range nodes {
-		if err := s.validateNode(node); err != nil {
-			return fmt.Errorf("failed to validate node: %w", err)
-		}
+	if err := s.validateNodes(nodes, edges); err != nil {
+		return err
 	}
 
 	existing, err := s.workflowRepo.RenderWorkflowGraph(ctx, workflowID)
@@ -339,25 +560,27 @@ func (s *WorkflowService) UpdateWorkflow(
 	}
 
 	type edgeKey struct {
-		src string
-		dst string
+		src   string
+		dst   string
+		label string
 	}
 
 	existingEdgeSet := make(map[edgeKey]struct{})
 	for _, e := range existing.Edges {
-		existingEdgeSet[edgeKey{e.SourceNodeID, e.TargetNodeID}] = struct{}{}
+		existingEdgeSet[edgeKey{e.SourceNodeID, e.TargetNodeID, e.Label}] = struct{}{}
 	}
 
 	inputEdgeSet := make(map[edgeKey]struct{})
 
 	for _, e := range edges {
-		k := edgeKey{e.SourceNodeID, e.TargetNodeID}
+		k := edgeKey{e.SourceNodeID, e.TargetNodeID, e.Label}
 		inputEdgeSet[k] = struct{}{}
 
 		if _, found := existingEdgeSet[k]; !found {
 			delta.EdgesToAdd = append(delta.EdgesToAdd, &models.WorkflowEdgeDTO{
 				SourceNodeID: e.SourceNodeID,
 				TargetNodeID: e.TargetNodeID,
+				Label:        e.Label,
 			})
 		}
 	}
@@ -367,6 +590,7 @@ func (s *WorkflowService) UpdateWorkflow(
 			delta.EdgesToDelete = append(delta.EdgesToDelete, &models.WorkflowEdgeDTO{
 				SourceNodeID: k.src,
 				TargetNodeID: k.dst,
+				Label:        k.label,
 			})
 		}
 	}
@@ -433,3 +657,158 @@ func (s *WorkflowService) ArchiveWorkflow(ctx context.Context, workflowID int32)
 
 	return nil
 }
+
+// MaxConcurrentRunsLimit caps the max concurrent runs a workflow can be configured with.
+const MaxConcurrentRunsLimit = 100
+
+var ErrInvalidWorkflowConcurrency = errors.New("invalid workflow concurrency settings")
+
+func (s *WorkflowService) GetWorkflowConcurrency(
+	ctx context.Context,
+	workflowID int32,
+) (*models.WorkflowConcurrency, error) {
+	concurrency, err := s.workflowRepo.GetWorkflowConcurrency(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow concurrency: %w", err)
+	}
+
+	return concurrency, nil
+}
+
+// UpdateWorkflowConcurrency saves the workflow's concurrency settings. They apply to runs
+// started from then on, runs already in progress are left alone.
+func (s *WorkflowService) UpdateWorkflowConcurrency(
+	ctx context.Context,
+	concurrency *models.WorkflowConcurrency,
+) (*models.WorkflowConcurrency, error) {
+	if concurrency.MaxConcurrentRuns < 1 || concurrency.MaxConcurrentRuns > MaxConcurrentRunsLimit {
+		return nil, fmt.Errorf(
+			"%w: max_concurrent_runs must be between 1 and %d",
+			ErrInvalidWorkflowConcurrency,
+			MaxConcurrentRunsLimit,
+		)
+	}
+
+	if !slices.Contains(models.OverlapPolicies, concurrency.OverlapPolicy) {
+		return nil, fmt.Errorf(
+			"%w: overlap_policy must be one of %v",
+			ErrInvalidWorkflowConcurrency,
+			models.OverlapPolicies,
+		)
+	}
+
+	updated, err := s.workflowRepo.UpdateWorkflowConcurrency(ctx, concurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update workflow concurrency: %w", err)
+	}
+
+	return updated, nil
+}
+
+// MaxRunTimeoutSeconds caps the run timeout a workflow can be configured with, 30 days.
+const MaxRunTimeoutSeconds = 30 * 24 * 60 * 60
+
+var ErrInvalidWorkflowTimeout = errors.New("invalid workflow timeout settings")
+
+func (s *WorkflowService) GetWorkflowTimeout(
+	ctx context.Context,
+	workflowID int32,
+) (*models.WorkflowTimeout, error) {
+	timeout, err := s.workflowRepo.GetWorkflowTimeout(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow timeout: %w", err)
+	}
+
+	return timeout, nil
+}
+
+// UpdateWorkflowTimeout saves the workflow's timeout settings. They apply to runs started from
+// then on, runs already in progress keep their deadline.
+func (s *WorkflowService) UpdateWorkflowTimeout(
+	ctx context.Context,
+	timeout *models.WorkflowTimeout,
+) (*models.WorkflowTimeout, error) {
+	if timeout.RunTimeoutSeconds < 1 || timeout.RunTimeoutSeconds > MaxRunTimeoutSeconds {
+		return nil, fmt.Errorf(
+			"%w: run_timeout_seconds must be between 1 and %d",
+			ErrInvalidWorkflowTimeout,
+			MaxRunTimeoutSeconds,
+		)
+	}
+
+	updated, err := s.workflowRepo.UpdateWorkflowTimeout(ctx, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update workflow timeout: %w", err)
+	}
+
+	return updated, nil
+}
+
+var ErrInvalidWorkflowInputSchema = errors.New("invalid workflow input schema")
+
+func (s *WorkflowService) GetWorkflowInputSchema(
+	ctx context.Context,
+	workflowID int32,
+) (*models.WorkflowInputSchema, error) {
+	schema, err := s.workflowRepo.GetWorkflowInputSchema(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow input schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+// UpdateWorkflowInputSchema saves the inputs the workflow declares. Runs started from then on
+// are checked against them, runs already started keep their inputs.
+func (s *WorkflowService) UpdateWorkflowInputSchema(
+	ctx context.Context,
+	schema *models.WorkflowInputSchema,
+) (*models.WorkflowInputSchema, error) {
+	if err := schema.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidWorkflowInputSchema, err)
+	}
+
+	updated, err := s.workflowRepo.UpdateWorkflowInputSchema(ctx, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update workflow input schema: %w", err)
+	}
+
+	return updated, nil
+}
+
+// GetNodeTypes returns the node types the engine can run, triggers first. A node type that acts
+// through an integration is only available once the user connected it with the scopes it needs.
+func (s *WorkflowService) GetNodeTypes(
+	ctx context.Context,
+	userID string,
+) ([]*models.NodeTypeSpec, error) {
+	integrations, err := s.oauthIntegrationRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth integrations: %w", err)
+	}
+
+	grantedScopes := make(map[string][]string, len(integrations))
+	for _, integration := range integrations {
+		grantedScopes[integration.Provider] = strings.Split(integration.Scopes, ",")
+	}
+
+	specs := append(s.triggerRegistry.Specs(), s.actionRegistry.Specs()...)
+
+	for _, spec := range specs {
+		if spec.OAuthScopes == nil {
+			spec.OAuthScopes = []string{}
+		}
+
+		if !spec.RequiresOAuth() {
+			spec.Available = true
+			continue
+		}
+
+		granted, ok := grantedScopes[spec.OAuthProvider]
+		spec.Available = ok && !slices.ContainsFunc(spec.OAuthScopes, func(scope string) bool {
+			return !slices.Contains(granted, scope)
+		})
+	}
+
+	return specs, nil
+}
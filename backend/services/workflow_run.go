@@ -2,18 +2,28 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/tinyautomator/tinyautomator-core/backend/clients/redis"
+	"github.com/tinyautomator/tinyautomator-core/backend/internal"
 	"github.com/tinyautomator/tinyautomator-core/backend/models"
 )
 
+var (
+	ErrWorkflowRunNotRunning = errors.New("workflow run is not running")
+	ErrWorkflowRunNotFailed  = errors.New("workflow run has not failed")
+)
+
 type WorkflowRunService struct {
+	workflowRepo    models.WorkflowRepository
 	workflowRunRepo models.WorkflowRunRepository
 	redisClient     redis.RedisClient
+	orchestrator    models.OrchestratorService
 	logger          logrus.FieldLogger
 
 	activeRunSubscribers map[string]map[chan []byte]bool
@@ -22,8 +32,10 @@ type WorkflowRunService struct {
 
 func NewWorkflowRunService(cfg models.AppConfig) *WorkflowRunService {
 	service := &WorkflowRunService{
+		workflowRepo:         cfg.GetWorkflowRepository(),
 		workflowRunRepo:      cfg.GetWorkflowRunRepository(),
 		redisClient:          cfg.GetRedisClient(),
+		orchestrator:         NewOrchestratorService(cfg),
 		logger:               cfg.GetLogger(),
 		activeRunSubscribers: make(map[string]map[chan []byte]bool),
 	}
@@ -43,6 +55,232 @@ func (s *WorkflowRunService) GetWorkflowRunStatus(
 	return run, nil
 }
 
+// CancelWorkflowRun stops a running workflow run. Pending node runs are cancelled so they are
+// never enqueued, and nodes that are already executing finish without advancing the run.
+func (s *WorkflowRunService) CancelWorkflowRun(ctx context.Context, runID int32) error {
+	cancelled, err := s.workflowRunRepo.CancelWorkflowRun(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel workflow run: %w", err)
+	}
+
+	if !cancelled {
+		return ErrWorkflowRunNotRunning
+	}
+
+	s.logger.WithField("run_id", runID).Info("workflow run cancelled")
+
+	if err := s.redisClient.PublishWorkflowRunStatusUpdate(ctx, runID, "cancelled", nil); err != nil {
+		s.logger.WithError(err).WithField("run_id", runID).
+			Warn("failed to publish workflow run cancellation")
+	}
+
+	if err := s.resumeParentNodeRun(ctx, runID, "cancelled"); err != nil {
+		s.logger.WithError(err).WithField("run_id", runID).
+			Error("failed to resume parent node run")
+	}
+
+	run, err := s.workflowRunRepo.GetWorkflowRunCore(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow run: %w", err)
+	}
+
+	if err := s.orchestrator.StartQueuedWorkflowRuns(ctx, run.WorkflowID); err != nil {
+		s.logger.WithError(err).WithField("run_id", runID).
+			Error("failed to start queued workflow runs")
+	}
+
+	return nil
+}
+
+// resumeParentNodeRun lets a run_workflow node waiting on the run carry on.
+func (s *WorkflowRunService) resumeParentNodeRun(
+	ctx context.Context,
+	runID int32,
+	status string,
+) error {
+	run, err := s.workflowRunRepo.GetWorkflowRunCore(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow run: %w", err)
+	}
+
+	if !run.ParentNodeRunID.Valid {
+		return nil
+	}
+
+	workflow, err := s.workflowRepo.GetWorkflow(ctx, run.WorkflowID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow: %w", err)
+	}
+
+	return internal.ResumeParentNodeRun(
+		ctx,
+		s.logger,
+		s.workflowRunRepo,
+		s.redisClient,
+		workflow.UserID,
+		runID,
+		status,
+	)
+}
+
+// ResumeWorkflowRun restarts a failed run from the nodes whose failure stopped it, along with
+// the nodes skipped downstream of them. Node runs that already succeeded keep their results, so
+// their side effects are not repeated, and failures handled by their failure policy stay failed.
+func (s *WorkflowRunService) ResumeWorkflowRun(
+	ctx context.Context,
+	userID string,
+	runID int32,
+) error {
+	run, err := s.workflowRunRepo.GetWorkflowRunCore(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow run: %w", err)
+	}
+
+	workflowGraph, err := s.workflowRepo.GetWorkflowRunGraph(ctx, run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow run graph: %w", err)
+	}
+
+	timeout, err := s.workflowRepo.GetWorkflowTimeout(ctx, run.WorkflowID)
+	if err != nil {
+		return fmt.Errorf("failed to get timeout settings: %w", err)
+	}
+
+	var pendingNodeRuns []*models.WorkflowNodeRunCore
+
+	// the node runs are reset along with queueing their tasks
+	err = s.workflowRunRepo.WithTransaction(
+		ctx,
+		func(ctx context.Context, txRepo models.WorkflowRunRepository) error {
+			nodeRuns, err := txRepo.GetWorkflowNodeRuns(ctx, runID, nil)
+			if err != nil {
+				return err
+			}
+
+			resumed, err := txRepo.ResumeWorkflowRun(
+				ctx,
+				runID,
+				internal.ResumableNodeRuns(workflowGraph, nodeRuns),
+			)
+			if err != nil {
+				return err
+			}
+
+			if !resumed {
+				return ErrWorkflowRunNotFailed
+			}
+
+			// the run gets its full timeout again, the reaper would time out the resumed run
+			// right away by its old deadline
+			deadline := time.Now().Add(timeout.RunTimeout())
+			if err := txRepo.SetWorkflowRunDeadline(ctx, runID, deadline); err != nil {
+				return err
+			}
+
+			pending := "pending"
+
+			pendingNodeRuns, err = txRepo.GetWorkflowNodeRuns(ctx, runID, &pending)
+			if err != nil {
+				return err
+			}
+
+			return s.enqueueReadyNodeRuns(
+				ctx,
+				txRepo,
+				workflowGraph,
+				userID,
+				run.WorkflowID,
+				runID,
+				pendingNodeRuns,
+			)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resume workflow run: %w", err)
+	}
+
+	s.initializeResumedRun(ctx, runID, pendingNodeRuns)
+
+	s.logger.WithFields(logrus.Fields{
+		"run_id":      runID,
+		"workflow_id": run.WorkflowID,
+		"n_pending":   len(pendingNodeRuns),
+	}).Info("workflow run resumed")
+
+	return nil
+}
+
+// initializeResumedRun tracks the pending node runs of a resumed run again and tells its
+// listeners that it is running.
+func (s *WorkflowRunService) initializeResumedRun(
+	ctx context.Context,
+	runID int32,
+	pendingNodeRuns []*models.WorkflowNodeRunCore,
+) {
+	// loop iterations are tracked by their foreach node, not the running node set
+	nIDs := []int32{}
+
+	for _, nodeRun := range pendingNodeRuns {
+		if nodeRun.IterationIndex == models.NoIteration {
+			nIDs = append(nIDs, nodeRun.WorkflowNodeID)
+		}
+	}
+
+	if err := s.redisClient.InitializeRunningNodeSet(ctx, runID, nIDs); err != nil {
+		// the executor falls back to the database when the set is missing
+		s.logger.WithError(err).Warn("failed to initialize running node set")
+	}
+
+	if err := s.redisClient.PublishWorkflowRunStatusUpdate(ctx, runID, "running", nil); err != nil {
+		s.logger.WithError(err).WithField("run_id", runID).
+			Warn("failed to publish workflow run resume")
+	}
+}
+
+// enqueueReadyNodeRuns enqueues every pending node run whose join is satisfied: the reset node
+// runs, plus any node that became ready but was never picked up once the run had failed.
+func (s *WorkflowRunService) enqueueReadyNodeRuns(
+	ctx context.Context,
+	workflowRunRepo models.WorkflowRunRepository,
+	workflowGraph *models.WorkflowGraph,
+	userID string,
+	workflowID int32,
+	runID int32,
+	pendingNodeRuns []*models.WorkflowNodeRunCore,
+) error {
+	for _, nodeRun := range pendingNodeRuns {
+		parents, err := workflowRunRepo.GetParentWorkflowNodeRuns(
+			ctx,
+			runID,
+			nodeRun.WorkflowNodeID,
+			nodeRun.IterationIndex,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get parent workflow node runs: %w", err)
+		}
+
+		joinMode := internal.JoinModeOf(workflowGraph, nodeRun.WorkflowNodeID)
+		if internal.Join(workflowGraph, joinMode, parents) != internal.JoinRun {
+			continue
+		}
+
+		if err := internal.EnqueueNode(
+			ctx,
+			s.logger,
+			workflowRunRepo,
+			userID,
+			workflowID,
+			runID,
+			nodeRun.WorkflowNodeID,
+			nodeRun.IterationIndex,
+		); err != nil {
+			return fmt.Errorf("failed to enqueue node %d: %w", nodeRun.WorkflowNodeID, err)
+		}
+	}
+
+	return nil
+}
+
 func (s *WorkflowRunService) StartWorkflowRunProgressListener(ctx context.Context) {
 	s.logger.Info("starting redis pubsub listener for workflow progress")
 